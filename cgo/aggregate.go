@@ -0,0 +1,249 @@
+//go:build cgo || (linux && amd64)
+
+package cgo
+
+import (
+	"reflect"
+	"slices"
+	"sync"
+	"unsafe"
+
+	"runtime.link/internal/cconv"
+	"runtime.link/std"
+)
+
+// aggregate is a precompiled C struct, that is passed or returned
+// by value. The fields of the Go struct are converted to and from
+// the C layout on each call.
+type aggregate struct {
+	size   uintptr
+	align  uintptr
+	fields []field
+	native native // backend representation.
+}
+
+// field within an aggregate.
+type field struct {
+	value
+	goffset uintptr // offset of the field in the Go struct.
+	coffset uintptr // offset of the field in the C struct.
+}
+
+// aggregates are cached by their Go type, as they are
+// immutable and the native representation is not freed.
+var aggregates sync.Map // map[reflect.Type]*aggregate
+
+// stdAggregates are the fields of standard C structs, such that when
+// a tag returns one of them, the fields can be mapped to multiple
+// Go results.
+var stdAggregates = map[string][]reflect.StructTag{
+	"div_t":     {`std:"quot int"`, `std:"rem int"`},
+	"ldiv_t":    {`std:"quot long"`, `std:"rem long"`},
+	"lldiv_t":   {`std:"quot long"`, `std:"rem long"`},
+	"imaxdiv_t": {`std:"quot long"`, `std:"rem long"`},
+}
+
+// spread returns a Go struct type with a field for each of the
+// Go results, tagged with the fields of the standard C struct.
+func spread(rtype reflect.Type, results int, name string) (reflect.Type, bool) {
+	tags, ok := stdAggregates[name]
+	if !ok || len(tags) != results {
+		return nil, false
+	}
+	var fields []reflect.StructField
+	for i, tag := range tags {
+		fields = append(fields, reflect.StructField{
+			Name: "F" + string(rune('0'+i)),
+			Type: rtype.Out(i),
+			Tag:  tag,
+		})
+	}
+	return reflect.StructOf(fields), true
+}
+
+// compileAggregate compiles the given Go struct type, as laid out in C by
+// [std.LayoutOf], each field is converted according to its std tag, or if
+// it isn't tagged, the C type is inferred from the Go type.
+func compileAggregate(rtype reflect.Type) (*aggregate, bool) {
+	if cached, ok := aggregates.Load(rtype); ok {
+		return cached.(*aggregate), true
+	}
+	size, align, layout, err := cconv.Layout(rtype)
+	if err != nil {
+		return nil, false
+	}
+	var ag = aggregate{size: size, align: align}
+	for i := 0; i < rtype.NumField(); i++ {
+		sfield := rtype.Field(i)
+		if sfield.Type.Size() == 0 {
+			if sfield.Tag.Get("std") == "packed" {
+				return nil, false // packed structs are not passed by value.
+			}
+			continue
+		}
+		v, ok := newValue(sfield.Type)
+		if !ok {
+			return nil, false
+		}
+		switch v.kind {
+		case kindString, kindFunc, kindSlice, kindMemory:
+			return nil, false
+		case kindHandle:
+			if !v.direct {
+				return nil, false
+			}
+			v.ctype = kindPointer
+		default:
+			v.ctype = v.kind
+		}
+		if tag, ok := sfield.Tag.Lookup("std"); ok && v.kind != kindAggregate {
+			_, ctype, err := std.Tag(tag).Parse()
+			if err != nil {
+				return nil, false
+			}
+			if ctype.Free != 0 {
+				v.ctype = kindPointer
+			} else if k, ok := ckinds[ctype.Name]; ok && k != kindVoid {
+				v.ctype = k
+			} else {
+				return nil, false
+			}
+		}
+		if v.ctype.isFloat() != v.kind.isFloat() || (v.kind.isPointer() && !v.ctype.isPointer()) {
+			return nil, false
+		}
+		at := slices.IndexFunc(layout, func(f cconv.Field) bool { return f.GoOffset == sfield.Offset })
+		if at < 0 || layout[at].Bits != 0 {
+			return nil, false // bitfields are not passed by value.
+		}
+		size := v.ctype.size()
+		if v.kind == kindAggregate {
+			size = v.aggregate.size
+		}
+		if size != layout[at].Size {
+			return nil, false
+		}
+		ag.fields = append(ag.fields, field{value: v, goffset: sfield.Offset, coffset: layout[at].Offset})
+	}
+	if len(ag.fields) == 0 {
+		return nil, false
+	}
+	ag.native = newNative(&ag)
+	cached, _ := aggregates.LoadOrStore(rtype, &ag)
+	return cached.(*aggregate), true
+}
+
+// size of the C representation of the kind.
+func (k kind) size() uintptr {
+	switch k {
+	case kindBool, kindInt8, kindUint8:
+		return 1
+	case kindInt16, kindUint16:
+		return 2
+	case kindInt32, kindUint32, kindFloat32:
+		return 4
+	case kindInt64, kindUint64, kindFloat64:
+		return 8
+	default:
+		return unsafe.Sizeof(uintptr(0))
+	}
+}
+
+// new returns zeroed memory for the C representation of the aggregate.
+func (ag *aggregate) new() unsafe.Pointer {
+	return unsafe.Pointer(unsafe.SliceData(make([]uint64, (ag.size+7)/8)))
+}
+
+// toC converts the Go struct at from into the C struct at into.
+func (ag *aggregate) toC(from, into unsafe.Pointer) {
+	for i := range ag.fields {
+		f := &ag.fields[i]
+		if f.kind == kindAggregate {
+			f.aggregate.toC(unsafe.Add(from, f.goffset), unsafe.Add(into, f.coffset))
+			continue
+		}
+		i, fl := load(f.kind, unsafe.Add(from, f.goffset+f.offset))
+		store(f.ctype, unsafe.Add(into, f.coffset), i, fl)
+	}
+}
+
+// toGo converts the C struct at from into the Go struct at into.
+func (ag *aggregate) toGo(from, into unsafe.Pointer) {
+	for i := range ag.fields {
+		f := &ag.fields[i]
+		if f.kind == kindAggregate {
+			f.aggregate.toGo(unsafe.Add(from, f.coffset), unsafe.Add(into, f.goffset))
+			continue
+		}
+		i, fl := load(f.ctype, unsafe.Add(from, f.coffset))
+		store(f.kind, unsafe.Add(into, f.goffset+f.offset), i, fl)
+	}
+}
+
+// each calls fn for each scalar field of the aggregate (including the
+// fields of nested aggregates), with its offset in the C struct.
+func (ag *aggregate) each(base uintptr, fn func(offset uintptr, ctype kind)) {
+	for i := range ag.fields {
+		f := &ag.fields[i]
+		if f.kind == kindAggregate {
+			f.aggregate.each(base+f.coffset, fn)
+			continue
+		}
+		fn(base+f.coffset, f.ctype)
+	}
+}
+
+// load the scalar of kind k at ptr, as either an integer or a float.
+func load(k kind, ptr unsafe.Pointer) (i int64, f float64) {
+	switch k {
+	case kindBool:
+		if *(*bool)(ptr) {
+			i = 1
+		}
+	case kindInt8:
+		i = int64(*(*int8)(ptr))
+	case kindInt16:
+		i = int64(*(*int16)(ptr))
+	case kindInt32:
+		i = int64(*(*int32)(ptr))
+	case kindInt64:
+		i = *(*int64)(ptr)
+	case kindUint8:
+		i = int64(*(*uint8)(ptr))
+	case kindUint16:
+		i = int64(*(*uint16)(ptr))
+	case kindUint32:
+		i = int64(*(*uint32)(ptr))
+	case kindUint64:
+		i = int64(*(*uint64)(ptr))
+	case kindFloat32:
+		f = float64(*(*float32)(ptr))
+	case kindFloat64:
+		f = *(*float64)(ptr)
+	case kindPointer, kindHandle:
+		i = int64(*(*uintptr)(ptr))
+	}
+	return
+}
+
+// store the scalar of kind k at ptr, from either an integer or a float.
+func store(k kind, ptr unsafe.Pointer, i int64, f float64) {
+	switch k {
+	case kindBool:
+		*(*bool)(ptr) = i != 0
+	case kindInt8, kindUint8:
+		*(*uint8)(ptr) = uint8(i)
+	case kindInt16, kindUint16:
+		*(*uint16)(ptr) = uint16(i)
+	case kindInt32, kindUint32:
+		*(*uint32)(ptr) = uint32(i)
+	case kindInt64, kindUint64:
+		*(*uint64)(ptr) = uint64(i)
+	case kindFloat32:
+		*(*float32)(ptr) = float32(f)
+	case kindFloat64:
+		*(*float64)(ptr) = f
+	case kindPointer, kindHandle:
+		*(*uintptr)(ptr) = uintptr(i)
+	}
+}
@@ -0,0 +1,420 @@
+//go:build cgo || (linux && amd64)
+
+package cgo
+
+import (
+	"cmp"
+	"reflect"
+	"strconv"
+	"unsafe"
+
+	"runtime.link/std"
+)
+
+// constants that can be referred to by name within
+// the assertions of a [std.Tag].
+var constants = map[string]int64{
+	"NULL":         0,
+	"true":         c_true,
+	"false":        c_false,
+	"EOF":          c_EOF,
+	"EXIT_SUCCESS": c_EXIT_SUCCESS,
+	"EXIT_FAILURE": c_EXIT_FAILURE,
+	"EDOM":         c_EDOM,
+	"ERANGE":       c_ERANGE,
+	"EILSEQ":       c_EILSEQ,
+	"BUFSIZ":       c_BUFSIZ,
+	"FILENAME_MAX": c_FILENAME_MAX,
+	"SEEK_SET":     c_SEEK_SET,
+	"SEEK_CUR":     c_SEEK_CUR,
+	"SEEK_END":     c_SEEK_END,
+	"CHAR_BIT":     c_CHAR_BIT,
+	"CHAR_MIN":     c_CHAR_MIN,
+	"CHAR_MAX":     c_CHAR_MAX,
+	"SCHAR_MIN":    c_SCHAR_MIN,
+	"SCHAR_MAX":    c_SCHAR_MAX,
+	"UCHAR_MAX":    c_UCHAR_MAX,
+	"SHRT_MIN":     c_SHRT_MIN,
+	"SHRT_MAX":     c_SHRT_MAX,
+	"USHRT_MAX":    c_USHRT_MAX,
+	"INT_MIN":      c_INT_MIN,
+	"INT_MAX":      c_INT_MAX,
+	"UINT_MAX":     c_UINT_MAX,
+	"LONG_MIN":     c_LONG_MIN,
+	"LONG_MAX":     c_LONG_MAX,
+}
+
+// check is a precompiled safety assertion for a single C
+// argument, or for the C result.
+type check struct {
+	test std.Assertions
+	arg  int   // index into plan.args, or -1 for the result.
+	ref  int   // index into plan.args of the argument being referred to, or -1 to use val.
+	val  int64 // constant to compare against, when ref is -1.
+
+	failure bool // the comparison is an error condition, rather than a requirement.
+}
+
+// compileChecks adds the assertions within ctype to the plan. Assertions on
+// arguments are requirements that must hold before the call is made, whereas
+// assertions on the result describe the error condition of the call.
+func (p *plan) compileChecks(ctype std.Type) error {
+	for i := range ctype.Args {
+		c, ok, err := p.compileCheck(i, ctype.Args[i].Test)
+		if err != nil {
+			return err
+		}
+		if ok {
+			p.requires = append(p.requires, c)
+		}
+	}
+	switch p.ret.kind {
+	case kindVoid, kindAggregate:
+		return nil
+	}
+	c, ok, err := p.compileCheck(-1, ctype.Func.Test)
+	if err != nil {
+		return err
+	}
+	if ok && !c.test.Capacity && (c.test.Equality.Check || c.test.MoreThan.Check || c.test.LessThan.Check) {
+		c.failure = true
+		p.failures = append(p.failures, c)
+	}
+	return nil
+}
+
+func (p *plan) compileCheck(i int, test std.Assertions) (check, bool, error) {
+	var c = check{test: test, arg: i, ref: -1}
+	var arg std.Argument
+	for _, a := range []std.Argument{test.Equality, test.MoreThan, test.LessThan, test.Overlaps, test.Lifetime, test.SameType, test.OfFormat} {
+		if a.Check {
+			arg = a
+			break
+		}
+	}
+	if !arg.Check {
+		return c, false, nil
+	}
+	subject := &p.ret
+	if i >= 0 {
+		subject = &p.args[i]
+	}
+	switch {
+	case arg.Index > 0:
+		if int(arg.Index) > len(p.args) {
+			return c, false, errorString("assertion refers to missing argument @" + strconv.Itoa(int(arg.Index)))
+		}
+		c.ref = int(arg.Index) - 1
+	case arg.Const != "":
+		val, ok := constants[arg.Const]
+		if !ok {
+			return c, false, errorString("unsupported constant " + arg.Const)
+		}
+		c.val = val
+	default:
+		c.val = arg.Value
+	}
+	if (test.Overlaps.Check || test.Lifetime.Check || test.SameType.Check || test.OfFormat.Check) && c.ref < 0 {
+		return c, false, errorString("memory assertions must refer to an argument")
+	}
+	if test.OfFormat.Check != (subject.kind == kindVariadic) {
+		return c, false, errorString("format assertions must be placed on variadic arguments")
+	}
+	if test.Indirect > 0 {
+		// the value must match the size of the value pointed to by @n.
+		if c.ref < 0 || p.args[c.ref].elem == 0 {
+			return c, false, nil
+		}
+		c.val, c.ref = int64(p.args[c.ref].elem), -1
+	}
+	if test.SameType.Check {
+		a, b := subject.rtype, p.args[c.ref].rtype
+		if a != nil && b != nil && (a.Kind() == reflect.Pointer || a.Kind() == reflect.Slice) &&
+			(b.Kind() == reflect.Pointer || b.Kind() == reflect.Slice) && a.Elem() != b.Elem() {
+			return c, false, errorString("type " + a.String() + " does not match " + b.String())
+		}
+		return c, false, nil // checked at compile time.
+	}
+	return c, true, nil
+}
+
+// holds reports whether the check holds for the given arguments and result,
+// for error conditions, whether the result is not an error. If it doesn't
+// hold, the reason is returned.
+func (c *check) holds(p *plan, args []unsafe.Pointer, ret unsafe.Pointer) (bool, string) {
+	v, ptr := &p.ret, ret
+	if c.arg >= 0 {
+		v = &p.args[c.arg]
+		ptr = args[v.from]
+	}
+	var ref *value
+	var refptr unsafe.Pointer
+	if c.ref >= 0 {
+		ref = &p.args[c.ref]
+		refptr = args[ref.from]
+	}
+	inverted := c.test.Inverted
+	switch {
+	case c.test.OfFormat.Check:
+		if ref.kind != kindString {
+			return true, ""
+		}
+		return printf(*(*string)(refptr), *(*[]any)(ptr))
+	case c.test.Overlaps.Check:
+		a, b := v.address(ptr), ref.address(refptr)
+		alen, ok := v.capacity(ptr)
+		if !ok || alen == 0 {
+			alen = 1 // at least the first byte.
+		}
+		blen, ok := ref.capacity(refptr)
+		if !ok || blen == 0 {
+			blen = 1
+		}
+		overlaps := a != nil && b != nil && uintptr(a) < uintptr(b)+blen && uintptr(b) < uintptr(a)+alen
+		if overlaps == inverted {
+			if overlaps {
+				return false, "overlaps with @" + strconv.Itoa(c.ref+1)
+			}
+			return false, "does not overlap with @" + strconv.Itoa(c.ref+1)
+		}
+		return true, ""
+	case c.test.Lifetime.Check:
+		a, b := v.address(ptr), ref.address(refptr)
+		size, ok := ref.capacity(refptr)
+		if a == nil || !ok {
+			return true, ""
+		}
+		within := b != nil && uintptr(a) >= uintptr(b) && uintptr(a) <= uintptr(b)+size
+		if within == inverted {
+			if within {
+				return false, "points within @" + strconv.Itoa(c.ref+1)
+			}
+			return false, "does not point within @" + strconv.Itoa(c.ref+1)
+		}
+		return true, ""
+	}
+	var (
+		x, y integer
+		ok   bool
+		what = "value"
+	)
+	if c.test.Capacity {
+		var size uintptr
+		if size, ok = v.capacity(ptr); !ok {
+			return true, ""
+		}
+		x, what = integer{int64(size), true}, "capacity"
+	} else if x, ok = v.integer(ptr); !ok {
+		return true, ""
+	}
+	y = integer{val: c.val}
+	if ref != nil {
+		if y, ok = ref.integer(refptr); !ok {
+			return true, ""
+		}
+	}
+	n := x.compare(y)
+	result := (c.test.Equality.Check && n == 0) || (c.test.MoreThan.Check && n > 0) || (c.test.LessThan.Check && n < 0)
+	if c.failure {
+		if result != inverted {
+			return false, "returned " + x.String()
+		}
+		return true, ""
+	}
+	if result == inverted {
+		return false, what + " " + x.String() + " must be " + c.operator() + " " + y.String()
+	}
+	return true, ""
+}
+
+// integer is either signed or unsigned, such that values
+// of either signedness can be compared.
+type integer struct {
+	val      int64
+	unsigned bool // val holds the bits of a uint64.
+}
+
+// compare returns -1, 0 or +1 depending on whether x is
+// less than, equal to, or greater than y.
+func (x integer) compare(y integer) int {
+	switch {
+	case !x.unsigned && !y.unsigned:
+		return cmp.Compare(x.val, y.val)
+	case !x.unsigned && x.val < 0:
+		return -1
+	case !y.unsigned && y.val < 0:
+		return 1
+	}
+	return cmp.Compare(uint64(x.val), uint64(y.val))
+}
+
+func (x integer) String() string {
+	if x.unsigned {
+		return strconv.FormatUint(uint64(x.val), 10)
+	}
+	return strconv.FormatInt(x.val, 10)
+}
+
+// operator returns the comparison made by the check.
+func (c *check) operator() string {
+	var op string
+	switch {
+	case c.test.MoreThan.Check:
+		op = ">"
+	case c.test.LessThan.Check:
+		op = "<"
+	}
+	if c.test.Equality.Check {
+		op += "="
+	}
+	if c.test.Inverted {
+		return "!" + op
+	}
+	return op
+}
+
+// address returns the memory address of the Go value at
+// ptr, if it is passed to C as a pointer.
+func (v *value) address(ptr unsafe.Pointer) unsafe.Pointer {
+	switch v.kind {
+	case kindPointer, kindSlice:
+		return *(*unsafe.Pointer)(ptr)
+	case kindString:
+		return unsafe.Pointer(unsafe.StringData(*(*string)(ptr)))
+	case kindHandle:
+		return *(*unsafe.Pointer)(unsafe.Add(ptr, v.offset))
+	case kindMemory:
+		return pointer((*memoryOf)(ptr).Pointer())
+	}
+	return nil
+}
+
+// capacity returns the size of the memory buffer in bytes that is
+// passed to C for the Go value at ptr, or false if it is unknown.
+func (v *value) capacity(ptr unsafe.Pointer) (uintptr, bool) {
+	switch v.kind {
+	case kindSlice:
+		return uintptr(len(*(*[]byte)(ptr))) * v.elem, true
+	case kindString:
+		s := *(*string)(ptr)
+		if len(s) > 0 && s[len(s)-1] == 0 {
+			return uintptr(len(s)), true
+		}
+		return uintptr(len(s)) + 1, true
+	case kindPointer:
+		if v.elem == 0 {
+			return 0, false
+		}
+		if *(*unsafe.Pointer)(ptr) == nil {
+			return 0, true
+		}
+		return v.elem, true
+	}
+	return 0, false
+}
+
+// integer returns the integer value of the Go value at ptr,
+// pointers are converted to their (unsigned) address.
+func (v *value) integer(ptr unsafe.Pointer) (integer, bool) {
+	switch v.kind {
+	case kindBool:
+		if *(*bool)(ptr) {
+			return integer{val: 1}, true
+		}
+		return integer{}, true
+	case kindInt8:
+		return integer{val: int64(*(*int8)(ptr))}, true
+	case kindInt16:
+		return integer{val: int64(*(*int16)(ptr))}, true
+	case kindInt32:
+		return integer{val: int64(*(*int32)(ptr))}, true
+	case kindInt64:
+		return integer{val: *(*int64)(ptr)}, true
+	case kindUint8:
+		return integer{val: int64(*(*uint8)(ptr))}, true
+	case kindUint16:
+		return integer{val: int64(*(*uint16)(ptr))}, true
+	case kindUint32:
+		return integer{val: int64(*(*uint32)(ptr))}, true
+	case kindUint64:
+		return integer{*(*int64)(ptr), true}, true
+	case kindPointer, kindSlice, kindHandle, kindMemory:
+		return integer{int64(uintptr(v.address(ptr))), true}, true
+	}
+	return integer{}, false
+}
+
+// printf reports whether args are suitable for the printf-style format.
+func printf(format string, args []any) (bool, string) {
+	next := func(class byte) (bool, string) {
+		if len(args) == 0 {
+			return false, "missing argument for format " + strconv.Quote(format)
+		}
+		arg := args[0]
+		args = args[1:]
+		if arg == nil {
+			return class == 'p' || class == 's', "nil argument for format " + strconv.Quote(format)
+		}
+		var kind = reflect.TypeOf(arg).Kind()
+		switch class {
+		case 'd':
+			switch kind {
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+				return true, ""
+			}
+		case 'f':
+			if kind == reflect.Float32 || kind == reflect.Float64 {
+				return true, ""
+			}
+		case 's':
+			if kind == reflect.String {
+				return true, ""
+			}
+		case 'p':
+			switch kind {
+			case reflect.Pointer, reflect.UnsafePointer, reflect.Uintptr, reflect.Slice, reflect.String:
+				return true, ""
+			}
+		}
+		return false, reflect.TypeOf(arg).String() + " argument does not match format " + strconv.Quote(format)
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		for i++; i < len(format); i++ {
+			switch c := format[i]; c {
+			case '-', '+', ' ', '#', '\'', '.', 'h', 'l', 'j', 'z', 't', 'L', 'q',
+				'0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				continue
+			case '*':
+				if ok, why := next('d'); !ok {
+					return ok, why
+				}
+				continue
+			case '%':
+			case 'd', 'i', 'o', 'u', 'x', 'X', 'c':
+				if ok, why := next('d'); !ok {
+					return ok, why
+				}
+			case 'e', 'E', 'f', 'F', 'g', 'G', 'a', 'A':
+				if ok, why := next('f'); !ok {
+					return ok, why
+				}
+			case 's':
+				if ok, why := next('s'); !ok {
+					return ok, why
+				}
+			case 'p':
+				if ok, why := next('p'); !ok {
+					return ok, why
+				}
+			default:
+				return false, "unsupported format verb %" + string(c)
+			}
+			break
+		}
+	}
+	return true, ""
+}
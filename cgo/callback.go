@@ -0,0 +1,200 @@
+//go:build cgo || (linux && amd64)
+
+package cgo
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"runtime.link/internal/cconv"
+	"runtime.link/std"
+)
+
+// callback is a precompiled conversion for a Go function
+// that can be called from C through a function pointer.
+type callback struct {
+	rtype reflect.Type
+	args  []value
+	ret   value
+}
+
+// ckinds maps standard C type names to the kind of value
+// they are represented by, so that callbacks read their
+// arguments with the correct width.
+var ckinds = map[string]kind{
+	"bool":      kindBool,
+	"char":      kindInt8,
+	"schar":     kindInt8,
+	"uchar":     kindUint8,
+	"short":     kindInt16,
+	"ushort":    kindUint16,
+	"int":       kindInt32,
+	"uint":      kindUint32,
+	"long":      kindInt64,
+	"ulong":     kindUint64,
+	"int8_t":    kindInt8,
+	"int16_t":   kindInt16,
+	"int32_t":   kindInt32,
+	"int64_t":   kindInt64,
+	"uint8_t":   kindUint8,
+	"uint16_t":  kindUint16,
+	"uint32_t":  kindUint32,
+	"uint64_t":  kindUint64,
+	"size_t":    kindUint64,
+	"ptrdiff":   kindInt64,
+	"ptrdiff_t": kindInt64,
+	"intptr_t":  kindInt64,
+	"uintptr_t": kindUint64,
+	"float":     kindFloat32,
+	"double":    kindFloat64,
+	"void":      kindVoid,
+}
+
+func (k kind) isFloat() bool   { return k == kindFloat32 || k == kindFloat64 }
+func (k kind) isPointer() bool { return k >= kindPointer }
+
+// compileCallback compiles the callback for the given Go function type and
+// the (optional) standard type. When the standard type has no parameter list,
+// the C types are inferred from the Go function type.
+func compileCallback(tag std.Tag, rtype reflect.Type, ctype std.Type) (*callback, error) {
+	incompatible := func(msg string) error {
+		return TagCompatiblityError{tag, errorString("callback " + msg), rtype}
+	}
+	if errCallbacks != nil {
+		return nil, incompatible(errCallbacks.Error())
+	}
+	if rtype.IsVariadic() || rtype.NumOut() > 1 {
+		return nil, incompatible("must have at most one result")
+	}
+	var inferred = ctype.Func == nil
+	if !inferred && len(ctype.Args) != rtype.NumIn() {
+		return nil, incompatible("parameter count does not match")
+	}
+	var cb = callback{rtype: rtype}
+	convert := func(rtype reflect.Type, ctype *std.Type) (value, error) {
+		v, ok := newValue(rtype)
+		if !ok || v.kind == kindFunc || v.kind == kindAggregate || v.kind == kindSlice {
+			return v, incompatible("unsupported type " + rtype.String())
+		}
+		v.ctype = v.kind
+		if ctype != nil {
+			if ctype.Free != 0 {
+				v.ctype = kindPointer
+			} else if k, ok := ckinds[ctype.Name]; ok {
+				v.ctype = k
+			}
+		}
+		if v.ctype.isFloat() != v.kind.isFloat() || (v.kind.isPointer() && !v.ctype.isPointer()) {
+			return v, incompatible("type " + rtype.String() + " does not match " + ctype.Name)
+		}
+		return v, nil
+	}
+	for i := 0; i < rtype.NumIn(); i++ {
+		var carg *std.Type
+		if !inferred {
+			carg = &ctype.Args[i]
+		}
+		arg, err := convert(rtype.In(i), carg)
+		if err != nil {
+			return nil, err
+		}
+		cb.args = append(cb.args, arg)
+	}
+	if rtype.NumOut() == 1 {
+		ret, err := convert(rtype.Out(0), ctype.Func)
+		if err != nil {
+			return nil, err
+		}
+		if ret.kind == kindString {
+			return nil, incompatible("cannot return a Go string to C")
+		}
+		cb.ret = ret
+	}
+	return &cb, nil
+}
+
+// set the Go value v to the C value represented by either the
+// integer i, the floating point f or the pointer p.
+func (v *value) set(rvalue reflect.Value, i int64, f float64, p unsafe.Pointer) {
+	switch v.kind {
+	case kindBool:
+		rvalue.SetBool(i != 0)
+	case kindInt8, kindInt16, kindInt32, kindInt64:
+		rvalue.SetInt(i)
+	case kindUint8, kindUint16, kindUint32, kindUint64:
+		rvalue.SetUint(uint64(i))
+	case kindFloat32, kindFloat64:
+		rvalue.SetFloat(f)
+	case kindPointer, kindHandle, kindMemory:
+		// any memory is borrowed from C for the duration of the callback.
+		cconv.Convert(rvalue, reflect.ValueOf(p))
+	case kindString:
+		rvalue.SetString(goString(p))
+	}
+}
+
+// get the C representation of the Go value v.
+func (v *value) get(rvalue reflect.Value) (i int64, f float64, p unsafe.Pointer) {
+	switch v.kind {
+	case kindBool:
+		if rvalue.Bool() {
+			i = 1
+		}
+	case kindInt8, kindInt16, kindInt32, kindInt64:
+		i = rvalue.Int()
+	case kindUint8, kindUint16, kindUint32, kindUint64:
+		i = int64(rvalue.Uint())
+	case kindFloat32, kindFloat64:
+		f = rvalue.Float()
+	case kindPointer, kindHandle, kindMemory:
+		cconv.Convert(reflect.ValueOf(&p).Elem(), rvalue)
+	}
+	return
+}
+
+// owned callbacks, keyed by the Go function value
+// they were created for, such that passing the same
+// Go function to C results in the same C function
+// pointer, until it is freed with [FreeCallback].
+var owned struct {
+	sync.Mutex
+	byFunc map[unsafe.Pointer]unsafe.Pointer
+}
+
+// make returns a C function pointer for the Go function value.
+func (cb *callback) make(fn unsafe.Pointer, own bool) unsafe.Pointer {
+	if !own {
+		return newCallback(cb, reflect.NewAt(cb.rtype, unsafe.Pointer(&fn)).Elem())
+	}
+	owned.Lock()
+	defer owned.Unlock()
+	if ptr, ok := owned.byFunc[fn]; ok {
+		return ptr
+	}
+	if owned.byFunc == nil {
+		owned.byFunc = make(map[unsafe.Pointer]unsafe.Pointer)
+	}
+	ptr := newCallback(cb, reflect.NewAt(cb.rtype, unsafe.Pointer(&fn)).Elem())
+	owned.byFunc[fn] = ptr
+	return ptr
+}
+
+// FreeCallback frees the C function pointer that was created
+// when the Go function fn was passed to C as an owned '$func'
+// callback. The same function value that was passed to C must
+// be provided, C must not call the function pointer after it
+// has been freed.
+func FreeCallback(fn any) {
+	if rtype := reflect.TypeOf(fn); rtype == nil || rtype.Kind() != reflect.Func {
+		panic("cgo.FreeCallback: fn must be a func")
+	}
+	key := (*eface)(unsafe.Pointer(&fn)).value
+	owned.Lock()
+	ptr, ok := owned.byFunc[key]
+	delete(owned.byFunc, key)
+	owned.Unlock()
+	if ok {
+		freeCallback(ptr)
+	}
+}
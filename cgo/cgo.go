@@ -3,20 +3,38 @@ package cgo
 
 import (
 	"reflect"
+	"strconv"
+	"strings"
 	"unsafe"
 
 	"runtime.link/std"
 )
 
 const (
-	ErrDisabled errorString = "cgo is disabled" // returned when CGO_ENABLED=0 and the function requires CGO to call.
+	ErrDisabled errorString = "cgo is disabled"            // returned when CGO_ENABLED=0 and the platform has no pure Go calling convention.
+	ErrUnlinked errorString = "function has been unlinked" // returned (or panicked) by functions after they have been unlinked with [Unlink].
 )
 
 // MissingSymbolError is returned when the linker
 // couldn't find the symbol for the given name.
 type MissingSymbolError string
 
-func (e MissingSymbolError) Error() string { return string("symbol " + string(e) + " not found") }
+func (e MissingSymbolError) Error() string {
+	var b strings.Builder
+	b.WriteString("symbol ")
+	for i, symbol := range strings.Split(string(e), ",") {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		name, version, ok := strings.Cut(symbol, "@")
+		b.WriteString(name)
+		if ok {
+			b.WriteString(" (version " + strings.TrimPrefix(version, "@") + ")")
+		}
+	}
+	b.WriteString(" not found")
+	return b.String()
+}
 
 type TagCompatiblityError struct {
 	tag   std.Tag
@@ -28,6 +46,26 @@ func (e TagCompatiblityError) Error() string {
 	return "incompatible tag '" + string(e.tag) + "' for function " + e.ftype.String() + ": " + e.err.Error()
 }
 
+// AssertionError is returned by (or if the function has no error
+// result, panicked from) a function implemented by [Linker.MakeFunc],
+// when a call violates one of the assertions in its [std.Tag], or
+// when the result of the call matches its error condition.
+type AssertionError struct {
+	tag    std.Tag
+	symbol string
+	arg    int // 1-based index of the C argument, or 0 for the result.
+	err    error
+}
+
+func (e AssertionError) Error() string {
+	if e.arg == 0 {
+		return e.symbol + " " + e.err.Error()
+	}
+	return e.symbol + ": argument @" + strconv.Itoa(e.arg) + " violates '" + string(e.tag) + "': " + e.err.Error()
+}
+
+func (e AssertionError) Unwrap() error { return e.err }
+
 type errorString string
 
 func (e errorString) Error() string { return string(e) }
@@ -39,9 +77,12 @@ type Linker func(sym string) unsafe.Pointer
 // MakeFunc takes a pointer to Go function 'fn' and a runtime.link
 // standard tag and implements fn, such that it calls the platform-native
 // ABI function. An error is returned if the linker couldn't find the symbol,
-// or if the Go function signature is incompatible with the tag. MakeFunc
-// cannot assert the correctness of the tag, so it is very important that
-// the tag correctly describes the function signature and memory behaviour.
+// or if the Go function signature is incompatible with the tag. If the tag
+// marks the symbol as optional and it cannot be found, fn is left as is and
+// no error is returned. The safety
+// assertions of the tag are checked on each call, see [AssertionError], yet
+// MakeFunc cannot assert the correctness of the tag, so it is very important
+// that the tag correctly describes the function signature and memory behaviour.
 // Incorrect tags can lead to undefined behaviour, memory corruption and
 // unpredictable crashes. Treat the tag as you would unsafe code.
 func (ln Linker) MakeFunc(fn any, tag std.Tag) error {
@@ -1,4 +1,4 @@
-//go:build !cgo
+//go:build !cgo && !(linux && amd64)
 
 package cgo
 
@@ -9,3 +9,9 @@ import (
 func (ln Linker) makeFunc(fn any, tag std.Tag) error {
 	return ErrDisabled
 }
+
+// FreeCallback is a no-op, as callbacks cannot be created when cgo is disabled.
+func FreeCallback(fn any) {}
+
+// Unlink is a no-op, as functions cannot be implemented when cgo is disabled.
+func Unlink(fn any) {}
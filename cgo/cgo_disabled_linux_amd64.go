@@ -0,0 +1,73 @@
+//go:build !cgo
+
+package cgo
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"runtime.link/cgo/internal/abi"
+)
+
+// frame implements vm without cgo, by calling symbols directly
+// with the System V AMD64 calling convention.
+type frame struct {
+	abi.Frame
+}
+
+var frames = sync.Pool{
+	New: func() any { return new(frame) },
+}
+
+func getVM() vm { return frames.Get().(*frame) }
+
+func putVM(vm vm) { frames.Put(vm) }
+
+func (f *frame) PushBool(v bool) {
+	if v {
+		f.PushInt(1)
+	} else {
+		f.PushInt(0)
+	}
+}
+
+func (f *frame) PushInt8(v int8)   { f.PushInt(uintptr(v)) }
+func (f *frame) PushInt16(v int16) { f.PushInt(uintptr(v)) }
+func (f *frame) PushInt32(v int32) { f.PushInt(uintptr(v)) }
+func (f *frame) PushInt64(v int64) { f.PushInt(uintptr(v)) }
+
+// Variadic is a no-op, as variadic arguments are passed like any
+// other argument, the upper bound of vector registers used is
+// always set in AL.
+func (f *frame) Variadic() {}
+
+func (f *frame) CallBool(fn unsafe.Pointer) bool   { f.Call(fn); return int32(f.Int()) != 0 }
+func (f *frame) CallInt8(fn unsafe.Pointer) int8   { f.Call(fn); return int8(f.Int()) }
+func (f *frame) CallInt16(fn unsafe.Pointer) int16 { f.Call(fn); return int16(f.Int()) }
+func (f *frame) CallInt32(fn unsafe.Pointer) int32 { f.Call(fn); return int32(f.Int()) }
+func (f *frame) CallInt64(fn unsafe.Pointer) int64 { f.Call(fn); return int64(f.Int()) }
+
+func (f *frame) CallFloat32(fn unsafe.Pointer) float32 { f.Call(fn); return f.Float32() }
+func (f *frame) CallFloat64(fn unsafe.Pointer) float64 { f.Call(fn); return f.Float64() }
+
+func (f *frame) CallPointer(fn unsafe.Pointer) unsafe.Pointer { f.Call(fn); return f.Pointer() }
+
+// errCallbacks is returned for callbacks, as C
+// cannot call back into Go without cgo.
+var errCallbacks error = errorString("requires cgo")
+
+func newCallback(*callback, reflect.Value) unsafe.Pointer { panic(errCallbacks) }
+
+func freeCallback(unsafe.Pointer) { panic(errCallbacks) }
+
+// native representation of an aggregate.
+type native = *abi.Aggr
+
+func newNative(ag *aggregate) native {
+	aggr := abi.NewAggr(ag.size)
+	ag.each(0, func(offset uintptr, ctype kind) {
+		aggr.Field(offset, ctype.size(), ctype.isFloat())
+	})
+	return aggr
+}
@@ -2,172 +2,127 @@
 
 package cgo
 
-// #include <internal/dyncall/dyncall.h>
-import "C"
 import (
-	"fmt"
 	"reflect"
-	"strings"
+	"runtime"
+	"sync"
 	"unsafe"
 
 	"runtime.link/cgo/internal/dyncall"
-	"runtime.link/std"
 )
 
-func (ln Linker) makeFunc(fn any, tag std.Tag) error {
-	var (
-		rtype = reflect.TypeOf(fn).Elem()
-		value = reflect.ValueOf(fn).Elem()
-	)
-	symbols, ctype, err := tag.Parse()
-	if err != nil {
-		return err
-	}
-	if ctype.Func == nil {
-		return TagCompatiblityError{tag, errorString("symbol is not a function"), rtype}
-	}
-	var symbol unsafe.Pointer
-	for _, sym := range symbols {
-		symbol = ln(sym)
-		if symbol != nil {
-			break
-		}
-	}
-	if symbol == nil {
-		return MissingSymbolError(strings.Join(symbols, ","))
+// vms are reused between calls, so that each call doesn't need
+// to allocate a new dyncall VM.
+var vms = sync.Pool{
+	New: func() any {
+		vm := dyncall.NewVM(4096)
+		runtime.SetFinalizer(vm, (*dyncall.VM).Free)
+		return vm
+	},
+}
+
+func getVM() vm { return vms.Get().(*dyncall.VM) }
+
+func putVM(vm vm) { vms.Put(vm) }
+
+// errCallbacks is nil, as callbacks are supported with cgo.
+var errCallbacks error
+
+// signatures maps each kind to its dyncall signature character.
+var signatures = [...]rune{
+	kindVoid:    dyncall.Void,
+	kindBool:    dyncall.Bool,
+	kindInt8:    dyncall.Char,
+	kindInt16:   dyncall.Short,
+	kindInt32:   dyncall.Int,
+	kindInt64:   dyncall.LongLong,
+	kindUint8:   dyncall.UnsignedChar,
+	kindUint16:  dyncall.UnsignedShort,
+	kindUint32:  dyncall.Uint,
+	kindUint64:  dyncall.UnsignedLongLong,
+	kindFloat32: dyncall.Float,
+	kindFloat64: dyncall.Double,
+	kindPointer: dyncall.Pointer,
+	kindString:  dyncall.Pointer,
+	kindHandle:  dyncall.Pointer,
+	kindMemory:  dyncall.Pointer,
+	kindFunc:    dyncall.Pointer,
+}
+
+func newCallback(cb *callback, fn reflect.Value) unsafe.Pointer {
+	var signature dyncall.Signature
+	for _, arg := range cb.args {
+		signature.Args = append(signature.Args, signatures[arg.ctype])
 	}
-	value.Set(reflect.MakeFunc(rtype, func(args []reflect.Value) []reflect.Value {
-		var vm = dyncall.NewVM(4096)
-		defer vm.Free()
-		push := func(value reflect.Value) {
-			switch value.Kind() {
-			case reflect.Bool:
-				vm.PushBool(value.Bool())
-			case reflect.Int8:
-				vm.PushInt8(int8(value.Int()))
-			case reflect.Int16:
-				vm.PushInt16(int16(value.Int()))
-			case reflect.Int32:
-				vm.PushInt32(int32(value.Int()))
-			case reflect.Int64:
-				vm.PushInt64(value.Int())
-			case reflect.Uint8:
-				u8 := uint8(value.Uint())
-				vm.PushInt8(*(*int8)(unsafe.Pointer(&u8)))
-			case reflect.Uint16:
-				u16 := uint16(value.Uint())
-				vm.PushInt16(*(*int16)(unsafe.Pointer(&u16)))
-			case reflect.Uint32:
-				u32 := uint32(value.Uint())
-				vm.PushInt32(*(*int32)(unsafe.Pointer(&u32)))
-			case reflect.Uint64:
-				u64 := uint64(value.Uint())
-				vm.PushInt64(*(*int64)(unsafe.Pointer(&u64)))
-			case reflect.Float32:
-				vm.PushFloat32(float32(value.Float()))
-			case reflect.Float64:
-				vm.PushFloat64(value.Float())
-			case reflect.Pointer, reflect.UnsafePointer:
-				vm.PushPointer(value.UnsafePointer())
-			case reflect.String:
-				s := std.StringOf(value.String())
-				vm.PushPointer(unsafe.Pointer(s.UnsafePointer()))
-			case reflect.Struct:
-				if value.Type().Implements(reflect.TypeOf([0]std.IsPointer{}).Elem()) {
-					vm.PushPointer(unsafe.Pointer(value.Interface().(std.IsPointer).Pointer()))
-				} else {
-					panic("unsupported struct " + value.Type().String())
-				}
-			/*case reflect.Func:
-			signature := newSignature(value.Type())
-			ptr := dyncall.NewCallback(signature, newCallback(signature, value))
-			vm.PushPointer(unsafe.Pointer(ptr))*/
+	signature.Returns = signatures[cb.ret.ctype]
+	return unsafe.Pointer(dyncall.NewCallback(signature, func(_ *dyncall.Callback, args *dyncall.Args, result unsafe.Pointer) rune {
+		var values = make([]reflect.Value, len(cb.args))
+		for i := range cb.args {
+			arg := &cb.args[i]
+			values[i] = reflect.New(cb.rtype.In(i)).Elem()
+			switch arg.ctype {
+			case kindBool:
+				arg.set(values[i], int64(args.Bool()), 0, nil)
+			case kindInt8:
+				arg.set(values[i], int64(args.Char()), 0, nil)
+			case kindInt16:
+				arg.set(values[i], int64(args.Short()), 0, nil)
+			case kindInt32:
+				arg.set(values[i], int64(args.Int()), 0, nil)
+			case kindInt64:
+				arg.set(values[i], int64(args.LongLong()), 0, nil)
+			case kindUint8:
+				arg.set(values[i], int64(args.UnsignedChar()), 0, nil)
+			case kindUint16:
+				arg.set(values[i], int64(args.UnsignedShort()), 0, nil)
+			case kindUint32:
+				arg.set(values[i], int64(args.UnsignedInt()), 0, nil)
+			case kindUint64:
+				arg.set(values[i], int64(args.UnsignedLongLong()), 0, nil)
+			case kindFloat32:
+				arg.set(values[i], 0, float64(args.Float()), nil)
+			case kindFloat64:
+				arg.set(values[i], 0, float64(args.Double()), nil)
 			default:
-				panic("unsupported type " + value.Type().String())
+				ptr := unsafe.Pointer(args.Pointer())
+				arg.set(values[i], int64(uintptr(ptr)), 0, ptr)
 			}
 		}
-		for _, carg := range ctype.Args {
-			fmt.Println(carg.Maps)
-			push(args[carg.Maps-1])
+		results := fn.Call(values)
+		if cb.ret.ctype == kindVoid {
+			return signature.Returns
 		}
-		var results = make([]reflect.Value, rtype.NumOut())
-		for i := 0; i < rtype.NumOut(); i++ {
-			results[i] = reflect.New(rtype.Out(i)).Elem()
-		}
-		var returnsError bool
-		_ = returnsError
-		switch rtype.NumOut() {
+		i, f, p := cb.ret.get(results[0])
+		switch cb.ret.ctype {
+		case kindFloat32:
+			*(*float32)(result) = float32(f)
+		case kindFloat64:
+			*(*float64)(result) = f
+		case kindPointer, kindHandle, kindMemory:
+			*(*unsafe.Pointer)(result) = p
 		default:
-			if rtype.NumOut() > 1 {
-				length := rtype.NumOut()
-				if rtype.Out(length-1) == reflect.TypeOf([0]error{}).Elem() {
-					returnsError = true
-					length--
-				}
-				for i := 1; i < length; i++ {
-					push(results[i].Addr())
-				}
-			}
-			fallthrough
-		case 1:
-			result := rtype.Out(0)
-			switch result.Kind() {
-			case reflect.Bool:
-				results[0].SetBool(vm.CallBool(symbol))
-			case reflect.Int8:
-				results[0].SetInt(int64(vm.CallInt8(symbol)))
-			case reflect.Int16:
-				results[0].SetInt(int64(vm.CallInt16(symbol)))
-			case reflect.Int32:
-				results[0].SetInt(int64(vm.CallInt32(symbol)))
-			case reflect.Int64:
-				results[0].SetInt(int64(vm.CallInt64(symbol)))
-			case reflect.Uint8:
-				u8 := vm.CallInt8(symbol)
-				results[0].SetUint(uint64(*(*uint8)(unsafe.Pointer(&u8))))
-			case reflect.Uint16:
-				u16 := vm.CallInt16(symbol)
-				results[0].SetUint(uint64(*(*uint16)(unsafe.Pointer(&u16))))
-			case reflect.Uint32:
-				u32 := vm.CallInt32(symbol)
-				results[0].SetUint(uint64(*(*uint32)(unsafe.Pointer(&u32))))
-			case reflect.Uint64:
-				u64 := vm.CallInt64(symbol)
-				results[0].SetUint(uint64(*(*uint64)(unsafe.Pointer(&u64))))
-			case reflect.Float32:
-				results[0].SetFloat(float64(vm.CallFloat32(symbol)))
-			case reflect.Float64:
-				results[0].SetFloat(float64(vm.CallFloat64(symbol)))
-			case reflect.String:
-				results[0].SetString(C.GoString((*C.char)(vm.CallPointer(symbol))))
-			case reflect.UnsafePointer:
-				results[0].SetPointer(vm.CallPointer(symbol))
-			case reflect.Pointer:
-				results[0] = reflect.NewAt(rtype.Out(0).Elem(), unsafe.Pointer(vm.CallPointer(symbol)))
-			case reflect.Struct:
-				if rtype.Implements(reflect.TypeOf([0]std.IsPointer{}).Elem()) {
-					*(*unsafe.Pointer)(results[0].Addr().UnsafePointer()) = vm.CallPointer(symbol)
-				} else {
-					panic("unsupported struct " + rtype.Out(0).String())
-				}
-			default:
-				panic("unsupported type " + rtype.Out(0).String())
-			}
-		case 0:
-			vm.Call(symbol)
+			*(*int64)(result) = i
 		}
-		/*if returnsError {
-			if results[0].IsZero() {
-				if !getErr.IsValid() {
-					panic("an error occured")
-				}
-				switch fn := getErr.Interface().(type) {
-				case func() string:
-					results[len(results)-1] = reflect.ValueOf(errors.New(fn()))
-				}
-			}
-		}*/
-		return results
+		return signature.Returns
 	}))
-	return nil
+}
+
+func freeCallback(ptr unsafe.Pointer) { (*dyncall.Callback)(ptr).Free() }
+
+// native representation of an aggregate.
+type native = *dyncall.Aggr
+
+func newNative(ag *aggregate) native {
+	var fields int
+	ag.each(0, func(uintptr, kind) { fields++ })
+	aggr := dyncall.NewAggr(fields, ag.size)
+	ag.each(0, func(offset uintptr, ctype kind) {
+		sig := signatures[ctype]
+		if ctype == kindBool {
+			sig = dyncall.UnsignedChar // DCbool is an int
+		}
+		aggr.Field(sig, offset, 1, nil)
+	})
+	aggr.Close()
+	return aggr
 }
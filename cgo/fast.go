@@ -0,0 +1,192 @@
+//go:build cgo || (linux && amd64)
+
+package cgo
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// shape of a Go value, as far as the Go calling convention is concerned.
+// Go functions that only differ by the named types of their arguments
+// and results share the same shape, such that a single generic
+// implementation can be reused for all of them.
+type shape uint8
+
+const (
+	shapeNone shape = iota
+	shapeInt
+	shapeFloat32
+	shapeFloat64
+	shapePointer
+	shapeString
+)
+
+func shapeOf(v value) shape {
+	switch v.kind {
+	case kindBool, kindInt8, kindInt16, kindInt32, kindInt64,
+		kindUint8, kindUint16, kindUint32, kindUint64:
+		return shapeInt
+	case kindFloat32:
+		return shapeFloat32
+	case kindFloat64:
+		return shapeFloat64
+	case kindPointer:
+		return shapePointer
+	case kindString:
+		return shapeString
+	case kindHandle:
+		if v.direct {
+			return shapeInt
+		}
+	}
+	return shapeNone
+}
+
+// none is used as the result type of specialised functions without results.
+type none struct{}
+
+// eface is the runtime representation of an empty interface.
+type eface struct {
+	rtype unsafe.Pointer
+	value unsafe.Pointer
+}
+
+// specialise returns an allocation-free implementation of the plan
+// for Go function types with up to two arguments and at most one
+// result, or nil if the function type is not supported. As these
+// functions have no error result, they panic when an assertion fails.
+func (p *plan) specialise(rtype reflect.Type) any {
+	if rtype.IsVariadic() || rtype.NumIn() > 2 || rtype.NumOut() > 1 || p.errors {
+		return nil
+	}
+	var in [2]shape
+	for i := 0; i < rtype.NumIn(); i++ {
+		arg, ok := newValue(rtype.In(i))
+		if !ok {
+			return nil
+		}
+		if in[i] = shapeOf(arg); in[i] == shapeNone {
+			return nil
+		}
+	}
+	if rtype.NumOut() == 0 {
+		return specialiseArgs[none](p, rtype.NumIn(), in)
+	}
+	switch shapeOf(p.ret) {
+	case shapeInt:
+		return specialiseArgs[int64](p, rtype.NumIn(), in)
+	case shapeFloat32:
+		return specialiseArgs[float32](p, rtype.NumIn(), in)
+	case shapeFloat64:
+		return specialiseArgs[float64](p, rtype.NumIn(), in)
+	case shapePointer:
+		return specialiseArgs[unsafe.Pointer](p, rtype.NumIn(), in)
+	case shapeString:
+		return specialiseArgs[string](p, rtype.NumIn(), in)
+	default:
+		return nil
+	}
+}
+
+func specialiseArgs[R any](p *plan, n int, in [2]shape) any {
+	switch n {
+	case 0:
+		return fn0[R](p)
+	case 1:
+		switch in[0] {
+		case shapeInt:
+			return fn1[int64, R](p)
+		case shapeFloat32:
+			return fn1[float32, R](p)
+		case shapeFloat64:
+			return fn1[float64, R](p)
+		case shapePointer:
+			return fn1[unsafe.Pointer, R](p)
+		case shapeString:
+			return fn1[string, R](p)
+		}
+	case 2:
+		switch in[0] {
+		case shapeInt:
+			return specialise2[int64, R](p, in[1])
+		case shapeFloat32:
+			return specialise2[float32, R](p, in[1])
+		case shapeFloat64:
+			return specialise2[float64, R](p, in[1])
+		case shapePointer:
+			return specialise2[unsafe.Pointer, R](p, in[1])
+		case shapeString:
+			return specialise2[string, R](p, in[1])
+		}
+	}
+	return nil
+}
+
+func specialise2[A, R any](p *plan, in shape) any {
+	switch in {
+	case shapeInt:
+		return fn2[A, int64, R](p)
+	case shapeFloat32:
+		return fn2[A, float32, R](p)
+	case shapeFloat64:
+		return fn2[A, float64, R](p)
+	case shapePointer:
+		return fn2[A, unsafe.Pointer, R](p)
+	case shapeString:
+		return fn2[A, string, R](p)
+	}
+	return nil
+}
+
+func fn0[R any](p *plan) any {
+	if _, ok := any(*new(R)).(none); ok {
+		return func() {
+			if err := p.call(nil, nil, nil); err != nil {
+				panic(err)
+			}
+		}
+	}
+	return func() (r R) {
+		if err := p.call(nil, unsafe.Pointer(&r), nil); err != nil {
+			panic(err)
+		}
+		return
+	}
+}
+
+func fn1[A, R any](p *plan) any {
+	if _, ok := any(*new(R)).(none); ok {
+		return func(a A) {
+			args := [...]unsafe.Pointer{unsafe.Pointer(&a)}
+			if err := p.call(args[:], nil, nil); err != nil {
+				panic(err)
+			}
+		}
+	}
+	return func(a A) (r R) {
+		args := [...]unsafe.Pointer{unsafe.Pointer(&a)}
+		if err := p.call(args[:], unsafe.Pointer(&r), nil); err != nil {
+			panic(err)
+		}
+		return
+	}
+}
+
+func fn2[A, B, R any](p *plan) any {
+	if _, ok := any(*new(R)).(none); ok {
+		return func(a A, b B) {
+			args := [...]unsafe.Pointer{unsafe.Pointer(&a), unsafe.Pointer(&b)}
+			if err := p.call(args[:], nil, nil); err != nil {
+				panic(err)
+			}
+		}
+	}
+	return func(a A, b B) (r R) {
+		args := [...]unsafe.Pointer{unsafe.Pointer(&a), unsafe.Pointer(&b)}
+		if err := p.call(args[:], unsafe.Pointer(&r), nil); err != nil {
+			panic(err)
+		}
+		return
+	}
+}
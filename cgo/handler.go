@@ -0,0 +1,112 @@
+//go:build cgo || (linux && amd64)
+
+package cgo
+
+import (
+	"strconv"
+	"unsafe"
+
+	"runtime.link/std"
+)
+
+// handlerResults are the results of well-known failure handlers that
+// return a description of the error, any other handler is assumed to
+// return an int.
+var handlerResults = map[string]kind{
+	"strerror":     kindString,
+	"dlerror":      kindString,
+	"gai_strerror": kindString,
+	"SDL_GetError": kindString,
+}
+
+// handler is a precompiled call to the failure handler of a [std.Tag],
+// which is called to explain why a call failed.
+type handler struct {
+	name   string
+	symbol unsafe.Pointer
+	args   []operand
+	ret    kind
+}
+
+// operand is either a C argument of the failed call, errno or a constant.
+type operand struct {
+	ref   int // index into plan.args, or -1 to use val.
+	val   int64
+	errno bool // use the errno set by the failed call.
+}
+
+// compileHandler compiles the failure handler of the plan.
+func (ln Linker) compileHandler(p *plan, call std.Call) error {
+	symbol := ln(call.Name)
+	if symbol == nil {
+		return MissingSymbolError(call.Name)
+	}
+	var h = handler{name: call.Name, symbol: symbol, ret: kindInt32}
+	if ret, ok := handlerResults[call.Name]; ok {
+		h.ret = ret
+	}
+	for _, arg := range call.Args {
+		var op = operand{ref: -1, val: arg.Value}
+		switch {
+		case arg.Index > 0:
+			if int(arg.Index) > len(p.args) {
+				return errorString("failure handler refers to missing argument @" + strconv.Itoa(int(arg.Index)))
+			}
+			switch p.args[arg.Index-1].kind {
+			case kindFunc, kindVariadic:
+				return errorString("failure handler cannot be passed argument @" + strconv.Itoa(int(arg.Index)))
+			}
+			op.ref = int(arg.Index) - 1
+		case arg.Const == "errno":
+			op.errno = true
+			p.errno = true
+		case arg.Const != "":
+			val, ok := constants[arg.Const]
+			if !ok {
+				return errorString("unsupported constant " + arg.Const)
+			}
+			op.val = val
+		}
+		h.args = append(h.args, op)
+	}
+	p.handler = &h
+	return nil
+}
+
+// explain calls the failure handler with the arguments of the failed
+// call (and the errno it set) and returns its description of the error.
+// pushed holds the values returned by [value.push] for the failed call.
+func (h *handler) explain(p *plan, args, pushed []unsafe.Pointer, errno int32) string {
+	var buf [4]unsafe.Pointer
+	alive := buf[:0]
+	vm := getVM()
+	vm.Reset()
+	for _, op := range h.args {
+		if op.errno {
+			vm.PushInt32(errno)
+			continue
+		}
+		if op.ref < 0 {
+			vm.PushInt64(op.val)
+			continue
+		}
+		arg := &p.args[op.ref]
+		if arg.sold {
+			// the memory has already been released to C, so it
+			// is passed as it was to the failed call.
+			vm.PushPointer(pushed[op.ref])
+			continue
+		}
+		alive = append(alive, arg.push(vm, args[arg.from]))
+	}
+	var explanation string
+	switch h.ret {
+	case kindString:
+		explanation = goString(vm.CallPointer(h.symbol))
+	default:
+		explanation = h.name + " = " + strconv.Itoa(int(vm.CallInt32(h.symbol)))
+	}
+	putVM(vm)
+	keepAlive(alive)
+	return explanation
+}
@@ -1,3 +1,6 @@
-package abi
-
+// Package abi provides a pure Go implementation of the platform's C calling
+// convention, such that C functions can be called without cgo.
+//
 // https://go.googlesource.com/go/+/refs/heads/master/src/cmd/compile/abi-internal.md
+// https://gitlab.com/x86-psABIs/x86-64-ABI
+package abi
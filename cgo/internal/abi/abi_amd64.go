@@ -1,17 +1,136 @@
 package abi
 
-// AMD64 registers
-// general purpose: 15
-// floating point: 8
+import (
+	"math"
+	"unsafe"
+)
 
-func PushFunc(u uintptr) {
-	pushFunc(u)
+// System V AMD64 registers available for arguments.
+const (
+	maxInts   = 6  // RDI, RSI, RDX, RCX, R8, R9
+	maxFloats = 8  // XMM0-XMM7
+	maxStack  = 16 // arguments passed on the stack.
+)
+
+// Frame for a System V AMD64 call. A zero Frame is ready to
+// have arguments pushed onto it, once the call is made, the
+// results can be read from the Frame.
+type Frame struct {
+	ints   [maxInts]uintptr
+	floats [maxFloats]uint64
+	stack  [maxStack]uintptr
+
+	nint   uintptr
+	nfloat uintptr
+	nstack uintptr
+
+	rax, rdx   uintptr // integer results.
+	xmm0, xmm1 uint64  // floating point results.
+
+	fn uintptr
+
+	errnofn uintptr // returns the address of errno, when errno is captured.
+	errno   int64   // captured by the last call.
+}
+
+// errnoLocation is the C ABI address of a function that returns the
+// address of the thread's errno, or zero if errno cannot be captured.
+var errnoLocation uintptr
+
+// Reset the frame, so that it can be reused for another call.
+func (f *Frame) Reset() {
+	f.nint = 0
+	f.nfloat = 0
+	f.nstack = 0
+	f.errnofn = 0
+}
+
+// CaptureErrno captures the errno value set by the next call, so
+// that it can be read with [Frame.Errno]. errno is cleared before
+// the call is made.
+func (f *Frame) CaptureErrno() { f.errnofn = errnoLocation }
+
+// Errno returns the errno value that was captured by the last
+// call, see [Frame.CaptureErrno].
+func (f *Frame) Errno() int32 { return int32(f.errno) }
+
+func (f *Frame) spill(value uintptr) {
+	if f.nstack == maxStack {
+		panic("abi: too many arguments")
+	}
+	f.stack[f.nstack] = value
+	f.nstack++
 }
 
-func pushFunc(fn uintptr)
+// PushInt pushes an integer class argument (any integer, bool
+// or pointer) onto the frame. Values narrower than 64 bits
+// should be sign or zero extended by the caller.
+func (f *Frame) PushInt(value uintptr) {
+	if f.nint == maxInts {
+		f.spill(value)
+		return
+	}
+	f.ints[f.nint] = value
+	f.nint++
+}
+
+// PushPointer pushes a pointer argument onto the frame. The
+// caller is responsible for keeping the pointer alive until
+// the call returns.
+func (f *Frame) PushPointer(value unsafe.Pointer) {
+	f.PushInt(uintptr(value))
+}
+
+// PushFloat32 pushes a C float argument onto the frame.
+func (f *Frame) PushFloat32(value float32) {
+	if f.nfloat == maxFloats {
+		f.spill(uintptr(math.Float32bits(value)))
+		return
+	}
+	f.floats[f.nfloat] = uint64(math.Float32bits(value))
+	f.nfloat++
+}
+
+// PushFloat64 pushes a C double argument onto the frame.
+func (f *Frame) PushFloat64(value float64) {
+	if f.nfloat == maxFloats {
+		f.spill(uintptr(math.Float64bits(value)))
+		return
+	}
+	f.floats[f.nfloat] = math.Float64bits(value)
+	f.nfloat++
+}
 
-func Fcallf(f float64) float64 {
-	return fcallf(f)
+// Call the C function at the given address with the arguments
+// that have been pushed onto the frame. The call runs on the
+// system stack of the current thread and the Go g register is
+// restored afterwards.
+func (f *Frame) Call(fn unsafe.Pointer) {
+	f.fn = uintptr(fn)
+	cgocall(callABI0, unsafe.Pointer(f))
 }
 
-func fcallf(f1 float64) float64
+// Int returns the integer class result of the last call (RAX).
+func (f *Frame) Int() uintptr { return f.rax }
+
+// Pointer returns the pointer result of the last call (RAX).
+func (f *Frame) Pointer() unsafe.Pointer { return *(*unsafe.Pointer)(unsafe.Pointer(&f.rax)) }
+
+// Float32 returns the float result of the last call (XMM0).
+func (f *Frame) Float32() float32 { return math.Float32frombits(uint32(f.xmm0)) }
+
+// Float64 returns the double result of the last call (XMM0).
+func (f *Frame) Float64() float64 { return math.Float64frombits(f.xmm0) }
+
+// callABI0 is the address of the call trampoline, which
+// loads the registers from the frame (passed in DI).
+var callABI0 uintptr
+
+// cgocall switches to the system stack (which is large enough for C code
+// to run on) and calls fn with arg, using the platform's C calling convention.
+// The goroutine is marked as being in a system call for the duration of the
+// call, so that the scheduler can continue running other goroutines.
+//
+//go:linkname cgocall runtime.cgocall
+//go:noescape
+func cgocall(fn uintptr, arg unsafe.Pointer) int32
@@ -1,50 +1,80 @@
 #include "go_asm.h"
 #include "textflag.h"
 
-#ifdef GOARCH_arm
-#define LR R14
-#endif
-
-#ifdef GOARCH_amd64
-#define	get_tls(r)	MOVQ TLS, r
-#define	g(r)	0(r)(TLS*1)
-#endif
-
-#ifdef GOARCH_386
-#define	get_tls(r)	MOVL TLS, r
-#define	g(r)	0(r)(TLS*1)
-#endif
-
-/*
-    MOVQ	SP, DX
-	ANDQ	$~15, SP	// alignment
-	MOVQ	DX, 8(SP)
-    MOVQ	DI, 48(SP)	// save g
+// call is invoked by runtime.cgocall on the system stack, using the
+// C calling convention, with a pointer to the Frame in DI. It loads
+// the argument registers from the frame, copies any spilled arguments
+// onto a 16-byte aligned stack, calls the function and then stores the
+// result registers back into the frame. If errno is being captured,
+// it is cleared before the call and stored into the frame afterwards.
+TEXT call<>(SB),NOSPLIT|NOFRAME,$0
+	PUSHQ	BP
+	MOVQ	SP, BP
+	PUSHQ	R12
+	PUSHQ	R13
+	MOVQ	DI, R12
+
+	// errno location (R13 is preserved across the call).
+	XORQ	R13, R13
+	MOVQ	Frame_errnofn(R12), R11
+	TESTQ	R11, R11
+	JZ	args
 	CALL	R11
-	get_tls(CX)
-	MOVQ	48(SP), DI
-	MOVQ	8(SP), DX
-	MOVQ	DX, SP
-*/
-
-// expand the stack so that it is C-compatible stack
-TEXT ·pushFunc(SB),NOPTR,$1000000-8
-    MOVQ fn+0(FP), R11
-    RET
-
-// Call C function that returns a float64 with 1 float64 argument 
-TEXT ·fcallf(SB),NOSPLIT|NOPTR,$48-16
-    MOVQ f1+0(FP), X0
-
-    MOVQ	SP, DX
-	ANDQ	$~15, SP	// alignment
-	MOVQ	DX, 8(SP)
-    MOVQ	DI, 48(SP)	// save g
+	MOVL	$0, (AX)
+	MOVQ	AX, R13
+
+args:
+	// stack arguments.
+	MOVQ	Frame_nstack(R12), CX
+	MOVQ	CX, AX
+	SHLQ	$3, AX
+	SUBQ	AX, SP
+	ANDQ	$~15, SP
+	LEAQ	Frame_stack(R12), SI
+	MOVQ	SP, DI
+	CLD
+	REP; MOVSQ
+
+	// floating point arguments.
+	MOVQ	(Frame_floats+0)(R12), X0
+	MOVQ	(Frame_floats+8)(R12), X1
+	MOVQ	(Frame_floats+16)(R12), X2
+	MOVQ	(Frame_floats+24)(R12), X3
+	MOVQ	(Frame_floats+32)(R12), X4
+	MOVQ	(Frame_floats+40)(R12), X5
+	MOVQ	(Frame_floats+48)(R12), X6
+	MOVQ	(Frame_floats+56)(R12), X7
+
+	// integer arguments.
+	MOVQ	(Frame_ints+0)(R12), DI
+	MOVQ	(Frame_ints+8)(R12), SI
+	MOVQ	(Frame_ints+16)(R12), DX
+	MOVQ	(Frame_ints+24)(R12), CX
+	MOVQ	(Frame_ints+32)(R12), R8
+	MOVQ	(Frame_ints+40)(R12), R9
+
+	// AL is the upper bound on the number of vector
+	// registers used, required for variadic functions.
+	MOVQ	Frame_nfloat(R12), AX
+	MOVQ	Frame_fn(R12), R11
 	CALL	R11
-	get_tls(CX)
-	MOVQ	48(SP), DI
-	MOVQ	8(SP), DX
-	MOVQ	DX, SP
 
-    MOVQ X0, ret+8(FP)
-    RET
+	MOVQ	AX, Frame_rax(R12)
+	MOVQ	DX, Frame_rdx(R12)
+	MOVQ	X0, Frame_xmm0(R12)
+	MOVQ	X1, Frame_xmm1(R12)
+
+	TESTQ	R13, R13
+	JZ	done
+	MOVLQSX	(R13), AX
+	MOVQ	AX, Frame_errno(R12)
+
+done:
+	LEAQ	-16(BP), SP
+	POPQ	R13
+	POPQ	R12
+	POPQ	BP
+	RET
+
+DATA	·callABI0(SB)/8, $call<>(SB)
+GLOBL	·callABI0(SB), NOPTR|RODATA, $8
@@ -0,0 +1,59 @@
+package abi_test
+
+import (
+	"math"
+	"testing"
+	"unsafe"
+
+	"runtime.link/cgo/internal/abi"
+	"runtime.link/dll"
+)
+
+func TestRegisters(t *testing.T) {
+	libc, err := dll.Open("libc.so.6")
+	if err != nil {
+		t.Skip(err)
+	}
+	snprintf := libc.Symbol("snprintf")
+	if snprintf == nil {
+		t.Skip("snprintf not available")
+	}
+	var (
+		buf    [64]byte
+		format = []byte("%d %d %d %d %d %d %.1f %.1f\x00")
+	)
+	var frame abi.Frame
+	frame.PushPointer(unsafe.Pointer(&buf[0]))
+	frame.PushInt(uintptr(len(buf)))
+	frame.PushPointer(unsafe.Pointer(&format[0]))
+	for i := 1; i <= 6; i++ {
+		frame.PushInt(uintptr(i))
+	}
+	frame.PushFloat64(1.5)
+	frame.PushFloat64(2.5)
+	frame.Call(snprintf)
+	if n := int32(frame.Int()); string(buf[:n]) != "1 2 3 4 5 6 1.5 2.5" {
+		t.Fatalf("unexpected result %q", buf[:n])
+	}
+}
+
+func BenchmarkSqrt(b *testing.B) {
+	libm, err := dll.Open("libm.so.6")
+	if err != nil {
+		b.Skip(err)
+	}
+	sym := libm.Symbol("sqrt")
+
+	var sqrt func(float64) float64 = math.Sqrt
+
+	sqrt = func(f float64) float64 {
+		var frame abi.Frame
+		frame.PushFloat64(f)
+		frame.Call(sym)
+		return frame.Float64()
+	}
+
+	for i := 0; i < b.N; i++ {
+		sqrt(2.0)
+	}
+}
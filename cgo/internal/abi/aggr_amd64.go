@@ -0,0 +1,118 @@
+package abi
+
+import "unsafe"
+
+// class of an eightbyte within an aggregate.
+type class uint8
+
+const (
+	classNone class = iota
+	classSSE
+	classInteger
+)
+
+// Aggr is the System V classification of a C struct that
+// is passed or returned by value.
+type Aggr struct {
+	size    uintptr
+	memory  bool     // passed on the stack, returned through a hidden pointer.
+	classes [2]class // of each eightbyte, unless memory.
+}
+
+// NewAggr returns a new aggregate of the given size, fields
+// need to be added to it before it can be used.
+func NewAggr(size uintptr) *Aggr {
+	return &Aggr{size: size, memory: size > 16}
+}
+
+// Field adds a scalar field of the given size at the given offset.
+func (ag *Aggr) Field(offset, size uintptr, float bool) {
+	if ag.memory || size == 0 {
+		return
+	}
+	if offset%size != 0 {
+		ag.memory = true // unaligned
+		return
+	}
+	switch i := offset / 8; {
+	case !float:
+		ag.classes[i] = classInteger
+	case ag.classes[i] == classNone:
+		ag.classes[i] = classSSE
+	}
+}
+
+func (ag *Aggr) words() uintptr { return (ag.size + 7) / 8 }
+
+// word reads the ith eightbyte of the aggregate at ptr.
+func (ag *Aggr) word(ptr unsafe.Pointer, i uintptr) (word uintptr) {
+	n := min(8, ag.size-i*8)
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(&word)), n), unsafe.Slice((*byte)(unsafe.Add(ptr, i*8)), n))
+	return word
+}
+
+// setWord writes the ith eightbyte of the aggregate at ptr.
+func (ag *Aggr) setWord(ptr unsafe.Pointer, i uintptr, word uintptr) {
+	n := min(8, ag.size-i*8)
+	copy(unsafe.Slice((*byte)(unsafe.Add(ptr, i*8)), n), unsafe.Slice((*byte)(unsafe.Pointer(&word)), n))
+}
+
+// BeginAggr must be called before any arguments are pushed onto
+// the frame, when the call returns the given aggregate.
+func (f *Frame) BeginAggr(ag *Aggr) {
+	if ag.memory {
+		f.nint = 1 // RDI is reserved for the hidden pointer.
+	}
+}
+
+// PushAggr pushes a copy of the aggregate at ptr onto the frame.
+func (f *Frame) PushAggr(ag *Aggr, ptr unsafe.Pointer) {
+	var ints, floats uintptr
+	for i := uintptr(0); !ag.memory && i < ag.words(); i++ {
+		if ag.classes[i] == classInteger {
+			ints++
+		} else {
+			floats++
+		}
+	}
+	if ag.memory || f.nint+ints > maxInts || f.nfloat+floats > maxFloats {
+		for i := uintptr(0); i < ag.words(); i++ {
+			f.spill(ag.word(ptr, i))
+		}
+		return
+	}
+	for i := uintptr(0); i < ag.words(); i++ {
+		if ag.classes[i] == classInteger {
+			f.ints[f.nint] = ag.word(ptr, i)
+			f.nint++
+		} else {
+			f.floats[f.nfloat] = uint64(ag.word(ptr, i))
+			f.nfloat++
+		}
+	}
+}
+
+// CallAggr calls the C function at the given address (see [Frame.Call])
+// and writes the aggregate it returns to ret.
+func (f *Frame) CallAggr(fn unsafe.Pointer, ag *Aggr, ret unsafe.Pointer) {
+	if ag.memory {
+		f.ints[0] = uintptr(ret)
+		f.Call(fn)
+		return
+	}
+	f.Call(fn)
+	var (
+		ints   = [2]uintptr{f.rax, f.rdx}
+		floats = [2]uint64{f.xmm0, f.xmm1}
+		ni, nf int
+	)
+	for i := uintptr(0); i < ag.words(); i++ {
+		if ag.classes[i] == classInteger {
+			ag.setWord(ret, i, ints[ni])
+			ni++
+		} else {
+			ag.setWord(ret, i, uintptr(floats[nf]))
+			nf++
+		}
+	}
+}
@@ -0,0 +1,75 @@
+//go:build !cgo
+
+package abi
+
+// When cgo is disabled, the Go runtime creates its own threads
+// and manages its own thread local storage, such that libc is
+// unable to run on them. This file fills in the hooks that the
+// runtime/cgo package would otherwise provide, so that threads
+// are created with pthread_create and the runtime uses the
+// platform's thread local storage. The hooks themselves are
+// implemented in assembly, as they are called with the C calling
+// convention, before the Go runtime has been initialised.
+
+import _ "unsafe" // for go:linkname
+
+//go:cgo_import_dynamic libc_malloc malloc "libc.so.6"
+//go:cgo_import_dynamic libc_free free "libc.so.6"
+//go:cgo_import_dynamic libc_abort abort "libc.so.6"
+//go:cgo_import_dynamic libc_setenv setenv "libc.so.6"
+//go:cgo_import_dynamic libc_unsetenv unsetenv "libc.so.6"
+//go:cgo_import_dynamic libc_sigfillset sigfillset "libc.so.6"
+//go:cgo_import_dynamic libc___errno_location __errno_location "libc.so.6"
+//go:cgo_import_dynamic _ _ "libc.so.6"
+
+//go:cgo_import_dynamic libpthread_pthread_attr_init pthread_attr_init "libpthread.so.0"
+//go:cgo_import_dynamic libpthread_pthread_attr_destroy pthread_attr_destroy "libpthread.so.0"
+//go:cgo_import_dynamic libpthread_pthread_attr_getstacksize pthread_attr_getstacksize "libpthread.so.0"
+//go:cgo_import_dynamic libpthread_pthread_attr_setdetachstate pthread_attr_setdetachstate "libpthread.so.0"
+//go:cgo_import_dynamic libpthread_pthread_create pthread_create "libpthread.so.0"
+//go:cgo_import_dynamic libpthread_pthread_sigmask pthread_sigmask "libpthread.so.0"
+//go:cgo_import_dynamic _ _ "libpthread.so.0"
+
+//go:linkname _iscgo runtime.iscgo
+var _iscgo = true
+
+//go:linkname _set_crosscall2 runtime.set_crosscall2
+var _set_crosscall2 = setCrosscall2
+
+// setCrosscall2 has nothing to do, as C is never able
+// to call back into Go when cgo is disabled.
+func setCrosscall2() {}
+
+//go:linkname _cgo_setenv runtime._cgo_setenv
+var _cgo_setenv = &x_cgo_setenv
+
+//go:linkname _cgo_unsetenv runtime._cgo_unsetenv
+var _cgo_unsetenv = &x_cgo_unsetenv
+
+//go:linkname _cgo_init _cgo_init
+var _cgo_init = &x_cgo_init
+
+//go:linkname _cgo_thread_start _cgo_thread_start
+var _cgo_thread_start = &x_cgo_thread_start
+
+//go:linkname _cgo_notify_runtime_init_done _cgo_notify_runtime_init_done
+var _cgo_notify_runtime_init_done = &x_cgo_notify_runtime_init_done
+
+//go:linkname _cgo_pthread_key_created _cgo_pthread_key_created
+var _cgo_pthread_key_created = &x_cgo_pthread_key_created
+
+var x_cgo_pthread_key_created uintptr
+
+// Implemented in runtime_linux_amd64.s
+var (
+	//go:linkname x_cgo_init x_cgo_init
+	x_cgo_init byte
+	//go:linkname x_cgo_thread_start x_cgo_thread_start
+	x_cgo_thread_start byte
+	//go:linkname x_cgo_notify_runtime_init_done x_cgo_notify_runtime_init_done
+	x_cgo_notify_runtime_init_done byte
+	//go:linkname x_cgo_setenv x_cgo_setenv
+	x_cgo_setenv byte
+	//go:linkname x_cgo_unsetenv x_cgo_unsetenv
+	x_cgo_unsetenv byte
+)
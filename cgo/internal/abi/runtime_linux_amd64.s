@@ -0,0 +1,191 @@
+//go:build !cgo
+
+#include "textflag.h"
+
+// These functions replace the ones in runtime/cgo (gcc_libinit.c,
+// gcc_linux_amd64.c and gcc_amd64.S) and are called by the runtime
+// with the C calling convention.
+
+#define SIG_SETMASK 2
+#define PTHREAD_CREATE_DETACHED 1
+
+// void x_cgo_init(G *g, void (*setg)(void*))
+//
+// Records setg_gcc and sets g->stacklo, based on the
+// default pthread stack size.
+TEXT x_cgo_init(SB),NOSPLIT|NOFRAME,$0
+	PUSHQ	BP
+	MOVQ	SP, BP
+	PUSHQ	BX
+	PUSHQ	R12
+	SUBQ	$80, SP
+	ANDQ	$~15, SP
+	MOVQ	DI, BX
+	MOVQ	SI, setg_gcc<>(SB)
+
+	// pthread_attr_t at 16(SP), size_t at 0(SP)
+	LEAQ	16(SP), DI
+	CALL	libpthread_pthread_attr_init(SB)
+	LEAQ	16(SP), DI
+	LEAQ	0(SP), SI
+	CALL	libpthread_pthread_attr_getstacksize(SB)
+	LEAQ	16(SP), DI
+	CALL	libpthread_pthread_attr_destroy(SB)
+
+	// g->stacklo = BP - size + 4096
+	MOVQ	BP, AX
+	SUBQ	0(SP), AX
+	ADDQ	$4096, AX
+	MOVQ	AX, 0(BX)
+
+	LEAQ	-16(BP), SP
+	POPQ	R12
+	POPQ	BX
+	POPQ	BP
+	RET
+
+// void x_cgo_thread_start(ThreadStart *ts)
+//
+// Creates a new detached pthread that will run ts->fn with ts->g,
+// all signals are blocked whilst the thread is being created.
+TEXT x_cgo_thread_start(SB),NOSPLIT|NOFRAME,$0
+	PUSHQ	BP
+	MOVQ	SP, BP
+	PUSHQ	BX
+	PUSHQ	R12
+	SUBQ	$352, SP
+	ANDQ	$~15, SP
+	MOVQ	DI, R12
+
+	// make our own copy of ts, that can persist after we return.
+	MOVQ	$24, DI
+	CALL	libc_malloc(SB)
+	TESTQ	AX, AX
+	JZ	fail
+	MOVQ	0(R12), CX
+	MOVQ	CX, 0(AX)
+	MOVQ	8(R12), CX
+	MOVQ	CX, 8(AX)
+	MOVQ	16(R12), CX
+	MOVQ	CX, 16(AX)
+	MOVQ	AX, BX
+
+	// pthread_attr_t at 0(SP), sigset_t ign at 64(SP), sigset_t oset
+	// at 192(SP), pthread_t at 320(SP) and size_t at 328(SP).
+	LEAQ	64(SP), DI
+	CALL	libc_sigfillset(SB)
+	MOVQ	$SIG_SETMASK, DI
+	LEAQ	64(SP), SI
+	LEAQ	192(SP), DX
+	CALL	libpthread_pthread_sigmask(SB)
+
+	LEAQ	0(SP), DI
+	CALL	libpthread_pthread_attr_init(SB)
+	LEAQ	0(SP), DI
+	MOVQ	$PTHREAD_CREATE_DETACHED, SI
+	CALL	libpthread_pthread_attr_setdetachstate(SB)
+	LEAQ	0(SP), DI
+	LEAQ	328(SP), SI
+	CALL	libpthread_pthread_attr_getstacksize(SB)
+
+	// Leave stacklo=0 and set stackhi=size; mstart will do the rest.
+	MOVQ	0(BX), AX
+	MOVQ	328(SP), CX
+	MOVQ	CX, 8(AX)
+
+	LEAQ	320(SP), DI
+	LEAQ	0(SP), SI
+	MOVQ	$threadentry<>(SB), DX
+	MOVQ	BX, CX
+	CALL	libpthread_pthread_create(SB)
+	MOVQ	AX, R12
+
+	MOVQ	$SIG_SETMASK, DI
+	LEAQ	192(SP), SI
+	XORQ	DX, DX
+	CALL	libpthread_pthread_sigmask(SB)
+	LEAQ	0(SP), DI
+	CALL	libpthread_pthread_attr_destroy(SB)
+
+	TESTL	R12, R12
+	JNZ	fail
+
+	LEAQ	-16(BP), SP
+	POPQ	R12
+	POPQ	BX
+	POPQ	BP
+	RET
+fail:
+	CALL	libc_abort(SB)
+	RET
+
+// void *threadentry(void *ts)
+//
+// The start routine of threads created by x_cgo_thread_start,
+// see crosscall1 in runtime/cgo/gcc_amd64.S
+TEXT threadentry<>(SB),NOSPLIT|NOFRAME,$0
+	PUSHQ	BX
+	PUSHQ	BP
+	PUSHQ	R12
+	PUSHQ	R13
+	PUSHQ	R14
+	PUSHQ	R15
+	SUBQ	$8, SP
+
+	MOVQ	0(DI), R12  // ts->g
+	MOVQ	16(DI), R13 // ts->fn
+	CALL	libc_free(SB)
+
+	MOVQ	R12, DI
+	MOVQ	setg_gcc<>(SB), AX
+	CALL	AX
+	CALL	R13
+
+	ADDQ	$8, SP
+	POPQ	R15
+	POPQ	R14
+	POPQ	R13
+	POPQ	R12
+	POPQ	BP
+	POPQ	BX
+	XORQ	AX, AX
+	RET
+
+// void x_cgo_notify_runtime_init_done(void*)
+//
+// Nothing to do, as C never calls back into Go.
+TEXT x_cgo_notify_runtime_init_done(SB),NOSPLIT|NOFRAME,$0
+	RET
+
+// void x_cgo_setenv(char **arg)
+TEXT x_cgo_setenv(SB),NOSPLIT|NOFRAME,$0
+	PUSHQ	BP
+	MOVQ	SP, BP
+	ANDQ	$~15, SP
+	MOVQ	8(DI), SI
+	MOVQ	0(DI), DI
+	MOVQ	$1, DX
+	CALL	libc_setenv(SB)
+	MOVQ	BP, SP
+	POPQ	BP
+	RET
+
+// void x_cgo_unsetenv(char **arg)
+TEXT x_cgo_unsetenv(SB),NOSPLIT|NOFRAME,$0
+	PUSHQ	BP
+	MOVQ	SP, BP
+	ANDQ	$~15, SP
+	MOVQ	0(DI), DI
+	CALL	libc_unsetenv(SB)
+	MOVQ	BP, SP
+	POPQ	BP
+	RET
+
+GLOBL	setg_gcc<>(SB), NOPTR, $8
+
+// errno_location returns the address of errno for the current thread.
+TEXT errno_location<>(SB),NOSPLIT|NOFRAME,$0
+	JMP	libc___errno_location(SB)
+
+DATA	·errnoLocation(SB)/8, $errno_location<>(SB)
+GLOBL	·errnoLocation(SB), NOPTR|RODATA, $8
@@ -6,6 +6,7 @@ package dyncall
 */
 import "C"
 import (
+	"sync"
 	"unsafe"
 )
 
@@ -34,11 +35,19 @@ type Signature struct {
 	Returns rune
 }
 
-var functions []CallbackHandler
+// handlers for each live callback, keyed by their userdata.
+var handlers struct {
+	sync.Mutex
+	last uintptr
+	byID map[uintptr]CallbackHandler
+}
 
 //export bridge_callback
 func bridge_callback(cb *C.DCCallback, args *C.DCArgs, result unsafe.Pointer, userdata uintptr) C.DCsigchar {
-	return C.DCsigchar(functions[userdata-1]((*Callback)(cb), (*Args)(args), result))
+	handlers.Lock()
+	handler := handlers.byID[userdata]
+	handlers.Unlock()
+	return C.DCsigchar(handler((*Callback)(cb), (*Args)(args), result))
 }
 
 type Args C.DCArgs
@@ -2,6 +2,7 @@ package dyncall
 
 /*
 #include <assert.h>
+#include <errno.h>
 #include <dyncall.h>
 #include <dyncall_callback.h>
 #include <stdint.h>
@@ -16,10 +17,14 @@ DCCallback *goNewCallback(const DCsigchar * signature, uintptr_t userdata) {
 typedef struct {
 	DCsigchar vtype;
 	DCValue value;
+	const DCaggr *aggr;
 } GoArg;
 
-void goArgs(DCCallVM *vm, GoArg *arg, int argc) {
-	dcReset(vm);
+void goAggrField(DCaggr *ag, DCsigchar type, DCint offset, DCsize array_len, const DCaggr *sub) {
+	dcAggrField(ag, type, offset, array_len, sub);
+}
+
+void goPush(DCCallVM *vm, GoArg *arg, int argc) {
 	DCValue value;
 	for (int i = 0; i < argc; i++) {
 		value = arg[i].value;
@@ -70,17 +75,60 @@ void goArgs(DCCallVM *vm, GoArg *arg, int argc) {
 			dcArgPointer(vm, value.p);
 			break;
 		case DC_SIGCHAR_AGGREGATE:
-			assert(0); // FIXME
+			dcArgAggr(vm, arg[i].aggr, value.p);
+			break;
+		case DC_SIGCHAR_CC_ELLIPSIS_VARARGS:
+			dcMode(vm, DC_CALL_C_ELLIPSIS_VARARGS);
 			break;
 		}
 	}
 }
 
-double goCallDouble(DCCallVM *vm, DCpointer funcptr, GoArg *arg, int argc) {
+void goArgs(DCCallVM *vm, GoArg *arg, int argc) {
+	dcMode(vm, DC_CALL_C_DEFAULT);
+	dcReset(vm);
+	goPush(vm, arg, argc);
+}
+
+void goCallAggr(DCCallVM *vm, DCpointer funcptr, GoArg *arg, int argc, const DCaggr *ag, DCpointer ret, int *err) {
+	dcMode(vm, DC_CALL_C_DEFAULT);
+	dcReset(vm);
+	dcBeginCallAggr(vm, ag);
+	goPush(vm, arg, argc);
+	if (err != NULL) errno = 0;
+	dcCallAggr(vm, funcptr, ag, ret);
+	if (err != NULL) *err = errno;
+}
+
+void goCallVoid(DCCallVM *vm, DCpointer funcptr, GoArg *arg, int argc, int *err) {
 	goArgs(vm, arg, argc);
-	return dcCallDouble(vm, funcptr);
+	if (err != NULL) errno = 0;
+	dcCallVoid(vm, funcptr);
+	if (err != NULL) *err = errno;
 }
 
+// errno is captured within the same call, as
+// Go may otherwise clobber it.
+#define GO_CALL(name, type, call) \
+type name(DCCallVM *vm, DCpointer funcptr, GoArg *arg, int argc, int *err) { \
+	goArgs(vm, arg, argc); \
+	if (err == NULL) return call(vm, funcptr); \
+	errno = 0; \
+	type result = call(vm, funcptr); \
+	*err = errno; \
+	return result; \
+}
+
+GO_CALL(goCallBool, DCbool, dcCallBool)
+GO_CALL(goCallChar, DCchar, dcCallChar)
+GO_CALL(goCallShort, DCshort, dcCallShort)
+GO_CALL(goCallInt, DCint, dcCallInt)
+GO_CALL(goCallLong, DClong, dcCallLong)
+GO_CALL(goCallLongLong, DClonglong, dcCallLongLong)
+GO_CALL(goCallFloat, DCfloat, dcCallFloat)
+GO_CALL(goCallDouble, DCdouble, dcCallDouble)
+GO_CALL(goCallPointer, DCpointer, dcCallPointer)
+
 */
 import "C"
 import (
@@ -92,20 +140,59 @@ type Callback C.DCCallback
 type CallbackHandler func(*Callback, *Args, unsafe.Pointer) rune
 
 func NewCallback(sig Signature, handler CallbackHandler) *Callback {
-	functions = append(functions, handler)
+	handlers.Lock()
+	if handlers.byID == nil {
+		handlers.byID = make(map[uintptr]CallbackHandler)
+	}
+	handlers.last++
+	id := handlers.last
+	handlers.byID[id] = handler
+	handlers.Unlock()
 
 	s := C.CString(string(sig.Args) + ")" + string(sig.Returns))
 	defer C.free(unsafe.Pointer(s))
-	return (*Callback)(C.goNewCallback((*C.DCsigchar)(s), C.uintptr_t(len(functions))))
+	return (*Callback)(C.goNewCallback((*C.DCsigchar)(s), C.uintptr_t(id)))
 }
 
 func (callback *Callback) Free() {
+	id := uintptr(C.dcbGetUserData((*C.DCCallback)(callback)))
+	handlers.Lock()
+	delete(handlers.byID, id)
+	handlers.Unlock()
 	C.dcbFreeCallback((*C.DCCallback)(callback))
 }
 
+// Aggr describes the layout of a C struct, so
+// that it can be passed and returned by value.
+type Aggr C.DCaggr
+
+// NewAggr returns a new aggregate of the given size, with
+// space for the given number of fields.
+func NewAggr(fields int, size uintptr) *Aggr {
+	return (*Aggr)(C.dcNewAggr(C.DCsize(fields), C.DCsize(size)))
+}
+
+// Field adds a field of the given signature type at the given offset,
+// sub must be provided for nested aggregates.
+func (ag *Aggr) Field(sig rune, offset uintptr, length int, sub *Aggr) {
+	C.goAggrField((*C.DCaggr)(ag), C.DCsigchar(sig), C.DCint(offset), C.DCsize(length), (*C.DCaggr)(sub))
+}
+
+// Close must be called after all fields have been added.
+func (ag *Aggr) Close() {
+	C.dcCloseAggr((*C.DCaggr)(ag))
+}
+
+func (ag *Aggr) Free() {
+	C.dcFreeAggr((*C.DCaggr)(ag))
+}
+
 type VM struct {
 	ptr *C.DCCallVM
 	buf []C.GoArg
+
+	capture bool  // capture errno on the next call.
+	errno   C.int // captured by the last call.
 }
 
 func NewVM(size int) *VM {
@@ -117,6 +204,23 @@ func NewVM(size int) *VM {
 
 func (vm *VM) Reset() {
 	vm.buf = vm.buf[:0]
+	vm.capture = false
+}
+
+// CaptureErrno captures the errno value set by the next call,
+// so that it can be read with [VM.Errno]. errno is cleared
+// before the call is made.
+func (vm *VM) CaptureErrno() { vm.capture = true }
+
+// Errno returns the errno value that was captured by the last
+// call, see [VM.CaptureErrno].
+func (vm *VM) Errno() int32 { return int32(vm.errno) }
+
+func (vm *VM) errp() *C.int {
+	if !vm.capture {
+		return nil
+	}
+	return &vm.errno
 }
 
 func (vm *VM) Free() {
@@ -199,6 +303,18 @@ func (vm *VM) PushFloat64(value float64) {
 	})
 }
 
+// PushAggr pushes the aggregate pointed to by value, which
+// must remain valid until the call.
+func (vm *VM) PushAggr(ag *Aggr, value unsafe.Pointer) {
+	var val C.DCValue
+	*(*C.DCpointer)(unsafe.Pointer(&val)) = C.DCpointer(value)
+	vm.buf = append(vm.buf, C.GoArg{
+		vtype: C.DC_SIGCHAR_AGGREGATE,
+		value: val,
+		aggr:  (*C.DCaggr)(ag),
+	})
+}
+
 func (vm *VM) PushPointer(value unsafe.Pointer) {
 	var val C.DCValue
 	*(*C.DCpointer)(unsafe.Pointer(&val)) = C.DCpointer(value)
@@ -208,51 +324,60 @@ func (vm *VM) PushPointer(value unsafe.Pointer) {
 	})
 }
 
+// Variadic marks the remaining arguments of the call
+// as the variadic arguments of a C function.
+func (vm *VM) Variadic() {
+	vm.buf = append(vm.buf, C.GoArg{
+		vtype: C.DC_SIGCHAR_CC_ELLIPSIS_VARARGS,
+	})
+}
+
 func (vm *VM) Call(address unsafe.Pointer) {
-	C.goArgs((*C.DCCallVM)(vm.ptr), unsafe.SliceData(vm.buf), C.int(len(vm.buf)))
-	C.dcCallVoid((*C.DCCallVM)(vm.ptr), (C.DCpointer)(unsafe.Pointer(address)))
+	C.goCallVoid((*C.DCCallVM)(vm.ptr), (C.DCpointer)(address), unsafe.SliceData(vm.buf), C.int(len(vm.buf)), vm.errp())
 }
 
 func (vm *VM) CallBool(address unsafe.Pointer) bool {
-	C.goArgs((*C.DCCallVM)(vm.ptr), unsafe.SliceData(vm.buf), C.int(len(vm.buf)))
-	return C.dcCallBool((*C.DCCallVM)(vm.ptr), (C.DCpointer)(unsafe.Pointer(address))) != 0
+	return C.goCallBool((*C.DCCallVM)(vm.ptr), (C.DCpointer)(address), unsafe.SliceData(vm.buf), C.int(len(vm.buf)), vm.errp()) != 0
 }
 
 func (vm *VM) CallInt8(address unsafe.Pointer) int8 {
-	C.goArgs((*C.DCCallVM)(vm.ptr), unsafe.SliceData(vm.buf), C.int(len(vm.buf)))
-	return int8(C.dcCallChar((*C.DCCallVM)(vm.ptr), (C.DCpointer)(unsafe.Pointer(address))))
+	return int8(C.goCallChar((*C.DCCallVM)(vm.ptr), (C.DCpointer)(address), unsafe.SliceData(vm.buf), C.int(len(vm.buf)), vm.errp()))
 }
 
 func (vm *VM) CallInt16(address unsafe.Pointer) int16 {
-	C.goArgs((*C.DCCallVM)(vm.ptr), unsafe.SliceData(vm.buf), C.int(len(vm.buf)))
-	return int16(C.dcCallShort((*C.DCCallVM)(vm.ptr), (C.DCpointer)(unsafe.Pointer(address))))
+	return int16(C.goCallShort((*C.DCCallVM)(vm.ptr), (C.DCpointer)(address), unsafe.SliceData(vm.buf), C.int(len(vm.buf)), vm.errp()))
 }
 
 func (vm *VM) CallInt32(address unsafe.Pointer) int32 {
-	C.goArgs((*C.DCCallVM)(vm.ptr), unsafe.SliceData(vm.buf), C.int(len(vm.buf)))
-	return int32(C.dcCallInt((*C.DCCallVM)(vm.ptr), (C.DCpointer)(unsafe.Pointer(address))))
+	return int32(C.goCallInt((*C.DCCallVM)(vm.ptr), (C.DCpointer)(address), unsafe.SliceData(vm.buf), C.int(len(vm.buf)), vm.errp()))
 }
 
 func (vm *VM) CallInt(address unsafe.Pointer) int {
-	C.goArgs((*C.DCCallVM)(vm.ptr), unsafe.SliceData(vm.buf), C.int(len(vm.buf)))
-	return int(C.dcCallLong((*C.DCCallVM)(vm.ptr), (C.DCpointer)(unsafe.Pointer(address))))
+	return int(C.goCallLong((*C.DCCallVM)(vm.ptr), (C.DCpointer)(address), unsafe.SliceData(vm.buf), C.int(len(vm.buf)), vm.errp()))
 }
 
 func (vm *VM) CallInt64(address unsafe.Pointer) int64 {
-	C.goArgs((*C.DCCallVM)(vm.ptr), unsafe.SliceData(vm.buf), C.int(len(vm.buf)))
-	return int64(C.dcCallLongLong((*C.DCCallVM)(vm.ptr), (C.DCpointer)(unsafe.Pointer(address))))
+	return int64(C.goCallLongLong((*C.DCCallVM)(vm.ptr), (C.DCpointer)(address), unsafe.SliceData(vm.buf), C.int(len(vm.buf)), vm.errp()))
 }
 
 func (vm *VM) CallFloat32(address unsafe.Pointer) float32 {
-	C.goArgs((*C.DCCallVM)(vm.ptr), unsafe.SliceData(vm.buf), C.int(len(vm.buf)))
-	return float32(C.dcCallFloat((*C.DCCallVM)(vm.ptr), (C.DCpointer)(unsafe.Pointer(address))))
+	return float32(C.goCallFloat((*C.DCCallVM)(vm.ptr), (C.DCpointer)(address), unsafe.SliceData(vm.buf), C.int(len(vm.buf)), vm.errp()))
 }
 
 func (vm *VM) CallFloat64(address unsafe.Pointer) float64 {
-	return float64(C.goCallDouble((*C.DCCallVM)(vm.ptr), (C.DCpointer)(unsafe.Pointer(address)), unsafe.SliceData(vm.buf), C.int(len(vm.buf))))
+	return float64(C.goCallDouble((*C.DCCallVM)(vm.ptr), (C.DCpointer)(address), unsafe.SliceData(vm.buf), C.int(len(vm.buf)), vm.errp()))
 }
 
 func (vm *VM) CallPointer(address unsafe.Pointer) unsafe.Pointer {
-	C.goArgs((*C.DCCallVM)(vm.ptr), unsafe.SliceData(vm.buf), C.int(len(vm.buf)))
-	return unsafe.Pointer(C.dcCallPointer((*C.DCCallVM)(vm.ptr), (C.DCpointer)(unsafe.Pointer(address))))
+	return unsafe.Pointer(C.goCallPointer((*C.DCCallVM)(vm.ptr), (C.DCpointer)(address), unsafe.SliceData(vm.buf), C.int(len(vm.buf)), vm.errp()))
+}
+
+// BeginAggr is a no-op, as the VM only begins the call
+// within [VM.CallAggr].
+func (vm *VM) BeginAggr(ag *Aggr) {}
+
+// CallAggr calls the function at address, writing the
+// aggregate it returns to result.
+func (vm *VM) CallAggr(address unsafe.Pointer, ag *Aggr, result unsafe.Pointer) {
+	C.goCallAggr((*C.DCCallVM)(vm.ptr), (C.DCpointer)(address), unsafe.SliceData(vm.buf), C.int(len(vm.buf)), (*C.DCaggr)(ag), (C.DCpointer)(result), vm.errp())
 }
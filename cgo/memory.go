@@ -0,0 +1,48 @@
+//go:build cgo || (linux && amd64)
+
+package cgo
+
+import (
+	"reflect"
+	"unsafe"
+
+	"runtime.link/std"
+)
+
+// memoryOf has the same layout as any [std.Memory], [std.Pointer]
+// or [std.Struct] (and any type defined by one), so it can be used
+// to read and write them.
+type memoryOf = std.Memory[struct{}]
+
+// compileDestructor compiles the call to the named destructor of owned
+// results, which is passed the pointer to free.
+func (ln Linker) compileDestructor(tag std.Tag, name string) (*plan, error) {
+	symbol := ln(name)
+	if symbol == nil {
+		return nil, MissingSymbolError(name)
+	}
+	return compile(tag, reflect.TypeOf(func(unsafe.Pointer) {}), std.Type{
+		Name: "func",
+		Func: &std.Type{Name: "void"},
+		Args: []std.Type{{Free: '$', Name: "void", Maps: 1}},
+	}, name, symbol)
+}
+
+// memory returns the [std.Memory] for the C pointer returned by a call,
+// which is freed by the destructor of the value (if any).
+func (v *value) memory(ptr unsafe.Pointer) memoryOf {
+	var free func()
+	if v.drop != nil {
+		free = func() {
+			// once unlinked, the destructor can no longer be called,
+			// so the memory is leaked.
+			v.drop.call([]unsafe.Pointer{unsafe.Pointer(&ptr)}, nil, nil)
+		}
+	}
+	return std.MemoryOf[struct{}](uintptr(ptr), free)
+}
+
+// pointer converts the address of C memory back into a pointer.
+func pointer(addr uintptr) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&addr))
+}
@@ -0,0 +1,640 @@
+//go:build cgo || (linux && amd64)
+
+package cgo
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"runtime.link/internal/cconv"
+	"runtime.link/std"
+)
+
+// vm is the calling convention backend, either the dyncall
+// VM (when cgo is enabled) or a pure Go [abi.Frame].
+type vm interface {
+	Reset()
+
+	PushBool(bool)
+	PushInt8(int8)
+	PushInt16(int16)
+	PushInt32(int32)
+	PushInt64(int64)
+	PushFloat32(float32)
+	PushFloat64(float64)
+	PushPointer(unsafe.Pointer)
+	PushAggr(native, unsafe.Pointer)
+
+	// Variadic must be called before pushing the
+	// variadic arguments of the call.
+	Variadic()
+
+	// CaptureErrno clears errno before the next call, so
+	// that Errno returns the value set by the call.
+	CaptureErrno()
+	Errno() int32
+
+	Call(unsafe.Pointer)
+	CallBool(unsafe.Pointer) bool
+	CallInt8(unsafe.Pointer) int8
+	CallInt16(unsafe.Pointer) int16
+	CallInt32(unsafe.Pointer) int32
+	CallInt64(unsafe.Pointer) int64
+	CallFloat32(unsafe.Pointer) float32
+	CallFloat64(unsafe.Pointer) float64
+	CallPointer(unsafe.Pointer) unsafe.Pointer
+
+	// BeginAggr must be called before any arguments are pushed
+	// when the call returns an aggregate with CallAggr.
+	BeginAggr(native)
+	CallAggr(unsafe.Pointer, native, unsafe.Pointer)
+}
+
+// kind of conversion to apply when passing a Go value
+// to C, or when returning a C value to Go.
+type kind uint8
+
+const (
+	kindVoid kind = iota
+	kindBool
+	kindInt8
+	kindInt16
+	kindInt32
+	kindInt64
+	kindUint8
+	kindUint16
+	kindUint32
+	kindUint64
+	kindFloat32
+	kindFloat64
+	kindPointer
+	kindString
+	kindHandle    // struct that implements std.IsPointer
+	kindMemory    // std.Memory, std.Pointer or std.Struct, freed by the destructor of the tag
+	kindFunc      // Go function passed to C as a callback
+	kindAggregate // struct passed by value
+	kindSlice     // pointer to the first element
+	kindVariadic  // Go variadic arguments, passed as C varargs
+)
+
+// value is a precompiled conversion for a single Go value.
+type value struct {
+	kind  kind
+	from  int          // index of the Go argument (or result).
+	rtype reflect.Type // Go type of the value.
+	elem  uintptr      // for kindPointer and kindSlice, size of the element (if known).
+
+	offset uintptr // for kindHandle, offset of the pointer.
+	direct bool    // for kindHandle, the pointer can be read from the offset, else call the method.
+
+	ctype    kind      // for callbacks, the C representation of the value.
+	callback *callback // for kindFunc
+	owned    bool      // for kindFunc, the callback is owned by C and must be freed explicitly.
+
+	aggregate *aggregate // for kindAggregate
+
+	drop *plan // for kindMemory results, the destructor that frees the memory.
+	sold bool  // for kindMemory arguments, ownership is sold to C.
+}
+
+// plan is a precompiled call to a C function, such that each call
+// doesn't need to reflect on the Go function or the [std.Type].
+type plan struct {
+	symbol unsafe.Pointer
+
+	args []value // C arguments, in order.
+	ret  value   // C return value.
+	outs []value // Go results passed to C as pointers.
+
+	borrows bool         // one or more arguments are borrowed callbacks.
+	spread  reflect.Type // Go results that are mapped to the fields of an aggregate result.
+	discard bool         // the C result is only used to check for errors.
+
+	tag      std.Tag
+	name     string   // of the symbol.
+	requires []check  // assertions on the arguments, checked before each call.
+	failures []check  // assertions on the result, that signal an error.
+	errors   bool     // the Go function has an error result.
+	handler  *handler // to explain failures, if any.
+	errno    bool     // errno is captured on each call.
+
+	unlinked atomic.Bool // the symbol may no longer be called.
+}
+
+// compile the plan for the given Go function type and standard type.
+func compile(tag std.Tag, rtype reflect.Type, ctype std.Type, name string, symbol unsafe.Pointer) (*plan, error) {
+	var p = plan{symbol: symbol, tag: tag, name: name}
+	incompatible := func(msg string) error {
+		return TagCompatiblityError{tag, errorString(msg), rtype}
+	}
+	for i, carg := range ctype.Args {
+		if carg.Maps < 1 || carg.Maps > rtype.NumIn() {
+			return nil, incompatible("argument " + carg.Name + " maps to missing Go argument")
+		}
+		if carg.More != (rtype.IsVariadic() && carg.Maps == rtype.NumIn()) || (carg.More && i != len(ctype.Args)-1) {
+			return nil, incompatible("variadic argument " + carg.Name + " must map to the Go variadic argument")
+		}
+		arg, ok := newValue(rtype.In(carg.Maps - 1))
+		if carg.More {
+			arg = value{kind: kindVariadic, rtype: rtype.In(carg.Maps - 1)}
+			ok = arg.rtype.Elem().Kind() == reflect.Interface // ...any
+		}
+		if !ok {
+			return nil, incompatible("unsupported argument type " + rtype.In(carg.Maps-1).String())
+		}
+		arg.from = carg.Maps - 1
+		if arg.kind == kindFunc {
+			callback, err := compileCallback(tag, rtype.In(arg.from), carg)
+			if err != nil {
+				return nil, err
+			}
+			arg.callback = callback
+			arg.owned = carg.Free == '$'
+			p.borrows = p.borrows || !arg.owned
+		}
+		arg.sold = arg.kind == kindMemory && carg.Free == '$'
+		p.args = append(p.args, arg)
+	}
+	length := rtype.NumOut()
+	if length > 0 && rtype.Out(length-1) == reflect.TypeOf([0]error{}).Elem() {
+		p.errors = true
+		length--
+	}
+	if spread, ok := spread(rtype, length, ctype.Func.Name); ok && length > 1 {
+		p.spread = spread
+		p.ret, _ = newValue(spread)
+		length = 0
+	}
+	if length > 0 {
+		ret, ok := newValue(rtype.Out(0))
+		if !ok {
+			return nil, incompatible("unsupported result type " + rtype.Out(0).String())
+		}
+		p.ret = ret
+	}
+	for i := 1; i < length; i++ {
+		p.outs = append(p.outs, value{kind: kindPointer, from: i})
+	}
+	if length == 0 && p.spread == nil && p.errors {
+		// the result is not returned to Go, yet it is
+		// needed to check the error condition.
+		if ctype.Func.Free != 0 {
+			p.ret.kind = kindPointer
+		} else if k, ok := ckinds[ctype.Func.Name]; ok {
+			p.ret.kind = k
+		}
+		p.discard = p.ret.kind != kindVoid
+	}
+	if err := p.compileChecks(ctype); err != nil {
+		return nil, incompatible(err.Error())
+	}
+	return &p, nil
+}
+
+func newValue(rtype reflect.Type) (value, bool) {
+	var v = value{rtype: rtype}
+	switch rtype.Kind() {
+	case reflect.Bool:
+		v.kind = kindBool
+	case reflect.Int8:
+		v.kind = kindInt8
+	case reflect.Int16:
+		v.kind = kindInt16
+	case reflect.Int32:
+		v.kind = kindInt32
+	case reflect.Int, reflect.Int64:
+		v.kind = kindInt64
+	case reflect.Uint8:
+		v.kind = kindUint8
+	case reflect.Uint16:
+		v.kind = kindUint16
+	case reflect.Uint32:
+		v.kind = kindUint32
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		v.kind = kindUint64
+	case reflect.Float32:
+		v.kind = kindFloat32
+	case reflect.Float64:
+		v.kind = kindFloat64
+	case reflect.Pointer:
+		v.kind = kindPointer
+		v.elem = rtype.Elem().Size()
+	case reflect.UnsafePointer:
+		v.kind = kindPointer
+	case reflect.Slice:
+		v.kind = kindSlice
+		v.elem = rtype.Elem().Size()
+	case reflect.String:
+		v.kind = kindString
+	case reflect.Func:
+		v.kind = kindFunc
+	case reflect.Struct:
+		if cconv.IsMemory(rtype) {
+			v.kind = kindMemory
+			break
+		}
+		if !rtype.Implements(reflect.TypeOf([0]std.IsPointer{}).Elem()) {
+			aggregate, ok := compileAggregate(rtype)
+			if !ok {
+				return v, false
+			}
+			v.kind = kindAggregate
+			v.aggregate = aggregate
+			break
+		}
+		v.kind = kindHandle
+		v.offset, v.direct = cconv.HandleOffset(rtype)
+	default:
+		return v, false
+	}
+	return v, true
+}
+
+// push the Go value at ptr onto the vm. Any memory that needs to stay
+// alive for the duration of the call is returned, for callbacks, this
+// is the C function pointer and for memory sold to C, the pointer that
+// was released.
+func (v *value) push(vm vm, ptr unsafe.Pointer) (alive unsafe.Pointer) {
+	switch v.kind {
+	case kindBool:
+		vm.PushBool(*(*bool)(ptr))
+	case kindInt8, kindUint8:
+		vm.PushInt8(*(*int8)(ptr))
+	case kindInt16, kindUint16:
+		vm.PushInt16(*(*int16)(ptr))
+	case kindInt32, kindUint32:
+		vm.PushInt32(*(*int32)(ptr))
+	case kindInt64, kindUint64:
+		vm.PushInt64(*(*int64)(ptr))
+	case kindFloat32:
+		vm.PushFloat32(*(*float32)(ptr))
+	case kindFloat64:
+		vm.PushFloat64(*(*float64)(ptr))
+	case kindPointer, kindSlice:
+		vm.PushPointer(*(*unsafe.Pointer)(ptr))
+	case kindString:
+		s := std.StringOf(*(*string)(ptr))
+		vm.PushPointer(s.UnsafePointer())
+		return s.UnsafePointer()
+	case kindHandle:
+		vm.PushPointer(*(*unsafe.Pointer)(unsafe.Add(ptr, v.offset)))
+	case kindMemory:
+		if v.sold {
+			sold := pointer((*memoryOf)(ptr).Release())
+			vm.PushPointer(sold)
+			return sold
+		}
+		vm.PushPointer(pointer((*memoryOf)(ptr).Pointer()))
+	case kindFunc:
+		var fn unsafe.Pointer
+		if *(*unsafe.Pointer)(ptr) != nil {
+			fn = v.callback.make(*(*unsafe.Pointer)(ptr), v.owned)
+		}
+		vm.PushPointer(fn)
+		return fn
+	case kindAggregate:
+		buf := v.aggregate.new()
+		v.aggregate.toC(ptr, buf)
+		vm.PushAggr(v.aggregate.native, buf)
+		return buf
+	}
+	return nil
+}
+
+// call the symbol, writing the result to the Go value at ptr.
+func (v *value) call(vm vm, symbol, ptr unsafe.Pointer) {
+	switch v.kind {
+	case kindVoid:
+		vm.Call(symbol)
+	case kindBool:
+		*(*bool)(ptr) = vm.CallBool(symbol)
+	case kindInt8, kindUint8:
+		*(*int8)(ptr) = vm.CallInt8(symbol)
+	case kindInt16, kindUint16:
+		*(*int16)(ptr) = vm.CallInt16(symbol)
+	case kindInt32, kindUint32:
+		*(*int32)(ptr) = vm.CallInt32(symbol)
+	case kindInt64, kindUint64:
+		*(*int64)(ptr) = vm.CallInt64(symbol)
+	case kindFloat32:
+		*(*float32)(ptr) = vm.CallFloat32(symbol)
+	case kindFloat64:
+		*(*float64)(ptr) = vm.CallFloat64(symbol)
+	case kindPointer:
+		*(*unsafe.Pointer)(ptr) = vm.CallPointer(symbol)
+	case kindHandle:
+		*(*unsafe.Pointer)(unsafe.Add(ptr, v.offset)) = vm.CallPointer(symbol)
+	case kindMemory:
+		*(*memoryOf)(ptr) = v.memory(vm.CallPointer(symbol))
+	case kindString:
+		*(*string)(ptr) = goString(vm.CallPointer(symbol))
+	case kindAggregate:
+		buf := v.aggregate.new()
+		vm.CallAggr(symbol, v.aggregate.native, buf)
+		v.aggregate.toGo(buf, ptr)
+	}
+}
+
+// call the plan with the Go arguments pointed to by args,
+// the C result is written to ret and any additional Go
+// results are passed to C by pointer from outs. An
+// [AssertionError] is returned if the arguments violate
+// the assertions of the tag (in which case, the call is
+// not made) or, if the Go function has an error result,
+// when the result matches the error condition of the tag,
+// explained by the failure handler of the tag (if any).
+func (p *plan) call(args []unsafe.Pointer, ret unsafe.Pointer, outs []unsafe.Pointer) error {
+	if p.unlinked.Load() {
+		return ErrUnlinked
+	}
+	for i := range p.requires {
+		if ok, why := p.requires[i].holds(p, args, ret); !ok {
+			return AssertionError{p.tag, p.name, p.requires[i].arg + 1, errorString(why)}
+		}
+	}
+	if p.handler != nil && p.errors {
+		// the failure handler may depend on thread-local state.
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+	}
+	var discard uint64
+	if p.discard {
+		ret = unsafe.Pointer(&discard)
+	}
+	var buf [8]unsafe.Pointer
+	alive := buf[:0]
+	vm := getVM()
+	vm.Reset()
+	if p.ret.kind == kindAggregate {
+		vm.BeginAggr(p.ret.aggregate.native)
+	}
+	if p.errno {
+		vm.CaptureErrno()
+	}
+	for i := range p.args {
+		arg := &p.args[i]
+		if arg.kind == kindVariadic {
+			var err error
+			if alive, err = pushVariadic(vm, *(*[]any)(args[arg.from]), alive); err != nil {
+				putVM(vm)
+				p.release(alive)
+				return err
+			}
+			continue
+		}
+		alive = append(alive, arg.push(vm, args[arg.from]))
+	}
+	for i := range outs {
+		vm.PushPointer(outs[i])
+	}
+	p.ret.call(vm, p.symbol, ret)
+	var errno int32
+	if p.errno {
+		errno = vm.Errno()
+	}
+	putVM(vm)
+	p.release(alive)
+	keepAlive(alive)
+	if p.errors {
+		for i := range p.failures {
+			if ok, why := p.failures[i].holds(p, args, ret); !ok {
+				if p.handler != nil {
+					return AssertionError{p.tag, p.name, 0, errorString(why + ": " + p.handler.explain(p, args, alive, errno))}
+				}
+				return p.failed(why, errno)
+			}
+		}
+		if len(p.failures) == 0 && p.errno && p.handler == nil && errno != 0 {
+			return p.failed("failed", errno)
+		}
+	}
+	return nil
+}
+
+// release frees the callbacks borrowed by the arguments that have been
+// pushed, alive holds the value pushed for each of them.
+func (p *plan) release(alive []unsafe.Pointer) {
+	if !p.borrows {
+		return
+	}
+	for i := range p.args {
+		if arg := &p.args[i]; i < len(alive) && arg.kind == kindFunc && !arg.owned && alive[i] != nil {
+			freeCallback(alive[i])
+		}
+	}
+}
+
+// failed returns the error for a failed call, that
+// unwraps to errno if it was set by the call.
+func (p *plan) failed(why string, errno int32) error {
+	if errno == 0 {
+		return AssertionError{p.tag, p.name, 0, errorString(why)}
+	}
+	return AssertionError{p.tag, p.name, 0, errnoError{why, std.Error(errno)}}
+}
+
+// errnoError describes a call that failed and set errno.
+type errnoError struct {
+	why   string
+	errno std.Error
+}
+
+func (e errnoError) Error() string { return e.why + ": " + e.errno.Error() }
+func (e errnoError) Unwrap() error { return e.errno }
+
+// pushVariadic pushes the variadic Go arguments onto the vm with the
+// default argument promotions of C. Any memory that needs to stay alive
+// for the duration of the call is appended to alive.
+func pushVariadic(vm vm, args []any, alive []unsafe.Pointer) ([]unsafe.Pointer, error) {
+	vm.Variadic()
+	for _, arg := range args {
+		if arg == nil {
+			vm.PushPointer(nil)
+			continue
+		}
+		rvalue := reflect.ValueOf(arg)
+		switch rvalue.Kind() {
+		case reflect.Bool:
+			if rvalue.Bool() {
+				vm.PushInt64(1)
+			} else {
+				vm.PushInt64(0)
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			vm.PushInt64(rvalue.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			vm.PushInt64(int64(rvalue.Uint()))
+		case reflect.Float32, reflect.Float64:
+			vm.PushFloat64(rvalue.Float())
+		case reflect.String:
+			s := std.StringOf(rvalue.String())
+			vm.PushPointer(s.UnsafePointer())
+			alive = append(alive, s.UnsafePointer())
+		case reflect.Pointer, reflect.UnsafePointer, reflect.Slice:
+			vm.PushPointer(rvalue.UnsafePointer())
+		default:
+			handle, ok := arg.(std.IsPointer)
+			if !ok {
+				return alive, errorString("unsupported variadic argument of type " + rvalue.Type().String())
+			}
+			vm.PushInt64(int64(handle.Pointer()))
+		}
+	}
+	return alive, nil
+}
+
+// keepAlive is like [runtime.KeepAlive] without
+// converting the pointers into an interface.
+//
+//go:noinline
+func keepAlive([]unsafe.Pointer) {}
+
+// plans are keyed by the closure of the function that implements
+// them, such that they can be unlinked.
+var plans sync.Map // map[unsafe.Pointer]*plan
+
+// Unlink the function pointed to by fn, which must have been implemented
+// by [Linker.MakeFunc], such that any further calls to it (or to any copies
+// of it) return, or panic with, [ErrUnlinked] instead of calling the symbol.
+// Functions should be unlinked before the library that defines their symbol
+// is unloaded. Calls that are in progress are not waited for. The destructor
+// of any [std.Memory] returned by fn is unlinked along with it, such that
+// this memory is leaked (rather than freed) afterwards.
+func Unlink(fn any) {
+	if rtype := reflect.TypeOf(fn); rtype == nil || rtype.Kind() != reflect.Pointer || rtype.Elem().Kind() != reflect.Func {
+		panic("cgo.Unlink: fn must be a pointer to a func")
+	}
+	if p, ok := plans.LoadAndDelete(*(*unsafe.Pointer)(reflect.ValueOf(fn).UnsafePointer())); ok {
+		p.(*plan).unlinked.Store(true)
+		if drop := p.(*plan).ret.drop; drop != nil {
+			drop.unlinked.Store(true)
+		}
+	}
+}
+
+// makeFunc implements the fn with the plan, using a specialised
+// implementation when the signature allows for it, or else a
+// reflect based one.
+func (p *plan) makeFunc(fn any) {
+	closure := (*unsafe.Pointer)(reflect.ValueOf(fn).UnsafePointer())
+	rtype := reflect.TypeOf(fn).Elem()
+	if impl := p.specialise(rtype); impl != nil {
+		// the specialised implementation has the same shape as fn, so the
+		// closure can be assigned to fn directly, even though the types
+		// don't match.
+		*closure = (*eface)(unsafe.Pointer(&impl)).value
+		plans.Store(*closure, p)
+		return
+	}
+	reflect.ValueOf(fn).Elem().Set(reflect.MakeFunc(rtype, func(values []reflect.Value) []reflect.Value {
+		var args = make([]unsafe.Pointer, len(values))
+		for i, value := range values {
+			ptr := reflect.New(value.Type())
+			ptr.Elem().Set(value)
+			args[i] = ptr.UnsafePointer()
+		}
+		for _, arg := range p.args {
+			if arg.kind == kindHandle && !arg.direct {
+				handle := values[arg.from].Interface().(std.IsPointer).Pointer()
+				args[arg.from] = unsafe.Pointer(&handle)
+			}
+		}
+		var results = make([]reflect.Value, rtype.NumOut())
+		for i := 0; i < rtype.NumOut(); i++ {
+			results[i] = reflect.New(rtype.Out(i)).Elem()
+		}
+		var ret unsafe.Pointer
+		if p.ret.kind != kindVoid && !p.discard {
+			ret = results[0].Addr().UnsafePointer()
+		}
+		var outs = make([]unsafe.Pointer, len(p.outs))
+		for i, out := range p.outs {
+			outs[i] = results[out.from].Addr().UnsafePointer()
+		}
+		fail := func(err error) {
+			if !p.errors {
+				panic(err)
+			}
+			results[len(results)-1].Set(reflect.ValueOf(err))
+		}
+		if p.spread != nil {
+			spread := reflect.New(p.spread)
+			if err := p.call(args, spread.UnsafePointer(), nil); err != nil {
+				fail(err)
+			}
+			for i := 0; i < p.spread.NumField(); i++ {
+				results[i].Set(spread.Elem().Field(i))
+			}
+			return results
+		}
+		if err := p.call(args, ret, outs); err != nil {
+			fail(err)
+		}
+		return results
+	}))
+	plans.Store(*closure, p)
+}
+
+func (ln Linker) lookup(symbols []string) (string, unsafe.Pointer, error) {
+	for _, sym := range symbols {
+		if symbol := ln(sym); symbol != nil {
+			return sym, symbol, nil
+		}
+	}
+	return "", nil, MissingSymbolError(strings.Join(symbols, ","))
+}
+
+func (ln Linker) makeFunc(fn any, tag std.Tag) error {
+	rtype := reflect.TypeOf(fn).Elem()
+	symbols, ctype, err := tag.Parse()
+	if err != nil {
+		return err
+	}
+	if ctype.Func == nil {
+		return TagCompatiblityError{tag, errorString("symbol is not a function"), rtype}
+	}
+	name, symbol, err := ln.lookup(symbols)
+	if err != nil {
+		if tag.Optional() {
+			return nil
+		}
+		return err
+	}
+	p, err := compile(tag, rtype, ctype, name, symbol)
+	if err != nil {
+		return err
+	}
+	if ctype.Func.Drop.Name != "" && p.ret.kind == kindMemory {
+		if p.ret.drop, err = ln.compileDestructor(tag, ctype.Func.Drop.Name); err != nil {
+			return err
+		}
+	}
+	if ctype.Call.Name == "errno" && len(ctype.Call.Args) == 0 {
+		p.errno = true
+	} else if ctype.Call.Name != "" {
+		if err := ln.compileHandler(p, ctype.Call); err != nil {
+			if _, ok := err.(MissingSymbolError); ok {
+				return err
+			}
+			return TagCompatiblityError{tag, err, rtype}
+		}
+	}
+	p.makeFunc(fn)
+	return nil
+}
+
+// goString copies the null-terminated C string at ptr.
+func goString(ptr unsafe.Pointer) string {
+	if ptr == nil {
+		return ""
+	}
+	var n int
+	for *(*byte)(unsafe.Add(ptr, n)) != 0 {
+		n++
+	}
+	return string(unsafe.Slice((*byte)(ptr), n))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"runtime.link/std"
+)
+
+const fooHeader = `
+#ifndef FOO_H
+#define FOO_H
+
+#define FOO_API __attribute__((visibility("default")))
+#define FOOCALL
+
+/** The major version of foo. */
+#define FOO_VERSION 2u
+#define FOO_NAME "foo"
+#define FOO_MASK (1 << 4 | FOO_VERSION)
+#define FOO_MAX(a, b) ((a) > (b) ? (a) : (b))
+
+typedef struct foo_window foo_window; /* opaque */
+
+/** A rectangle. */
+typedef struct foo_rect {
+	int x, y;
+	unsigned int w, h;
+} foo_rect;
+
+typedef struct foo_event {
+	int type;
+	char text[32];
+	foo_window *window;
+} foo_event;
+
+typedef union { int i; float f; } foo_value;
+
+typedef enum foo_color {
+	FOO_COLOR_RED,  /**< Red. */
+	FOO_COLOR_GREEN,
+} foo_color;
+
+enum foo_flags {
+	FOO_FLAG_SHOWN = 1 << 0,
+	FOO_FLAG_HIDDEN = 1 << 1,
+	FOO_FLAG_NEXT,
+};
+
+typedef int (*foo_compare)(const void *a, const void *b);
+
+/**
+ * Create a new window.
+ *
+ * \param title of the window.
+ */
+extern FOO_API foo_window * FOOCALL foo_create_window(const char *title, int w, int h, foo_color color);
+FOO_API void foo_set_value(foo_value value);
+FOO_API long double foo_precise(void);
+
+static inline int foo_twice(int x) { return x * 2; }
+
+#endif
+`
+
+func generate(t *testing.T, src string) (string, *generator) {
+	t.Helper()
+	var h header
+	if err := parse(&h, src, nil); err != nil {
+		t.Fatal(err)
+	}
+	g := newGenerator(&h, "foo")
+	g.sources = []string{"foo.h"}
+	g.prefixes = []string{"foo_", "FOO_"}
+	g.libs = [][2]string{{"linux", "libfoo.so.1"}}
+	out, err := g.generate()
+	if err != nil {
+		t.Fatal(err, "\n", string(out))
+	}
+	return string(out), g
+}
+
+func TestGenerate(t *testing.T) {
+	out, g := generate(t, fooHeader)
+	file, err := goparser.ParseFile(gotoken.NewFileSet(), "foo.go", out, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every tag within the Library must be valid.
+	var fields int
+	ast.Inspect(file, func(node ast.Node) bool {
+		field, ok := node.(*ast.Field)
+		if !ok || field.Tag == nil {
+			return true
+		}
+		if _, ok := field.Type.(*ast.FuncType); !ok {
+			return true
+		}
+		tag, _ := strconv.Unquote(field.Tag.Value)
+		if _, _, err := std.Tag(reflect.StructTag(tag).Get("std")).Parse(); err != nil {
+			t.Error(err)
+		}
+		fields++
+		return true
+	})
+	if fields == 0 {
+		t.Fatal("expected tagged fields")
+	}
+	for _, expected := range []string{
+		"linux lib.Location `std:\"libfoo.so.1\"`",
+		"Version = 2 // The major version of foo.",
+		"Name    = \"foo\"",
+		"Mask    = (1<<4 | Version)",
+		"Window std.Handle[Window]",
+		"type Rect struct { // A rectangle.",
+		"X int32  `std:\"x int\"`",
+		"Text   [32]byte",
+		"Window Window `std:\"window &foo_window\"`",
+		"ColorRed Color = iota // Red.",
+		"FlagNext   Flags = FlagHidden + 1",
+		"type Compare func(unsafe.Pointer, unsafe.Pointer) int32",
+		"CreateWindow func(title string, w int32, h int32, color Color) Window `std:\"foo_create_window func(&#char,int,int,int)&foo_window\"` // Create a new window.",
+		"// foo_set_value is not supported",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in\n%s", expected, out)
+		}
+	}
+	for _, unexpected := range []string{"Max", "Twice", "Precise"} {
+		if strings.Contains(out, unexpected) {
+			t.Errorf("unexpected %q in\n%s", unexpected, out)
+		}
+	}
+	if len(g.header.skipped) != 1 || !strings.Contains(g.header.skipped[0], "long double") {
+		t.Error("expected long double to be skipped", g.header.skipped)
+	}
+}
+
+func TestBindings(t *testing.T) {
+	for _, test := range []struct {
+		decl, field string
+	}{
+		{"void f(void);", "F func() `std:\"f func()void\"`"},
+		{"const char *f(void);", "F func() string `std:\"f func()&#char\"`"},
+		{"char *f(char *buf, size_t n);", "F func(buf []byte, n uintptr) unsafe.Pointer `std:\"f func(&char,size_t)&char\"`"},
+		{"int f(const int *v, int *out);", "F func(v *int32, out *int32) int32 `std:\"f func(&#int,&int)int\"`"},
+		{"void *f(void **p, int);", "F func(unsafe.Pointer, int32) unsafe.Pointer `std:\"f func(&void,int)&void\"`"},
+		{"unsigned long long f(unsigned, short, _Bool);", "F func(uint32, int16, bool) uint64 `std:\"f func(uint,short,bool)uint64_t\"`"},
+		{"int f(const char *fmt, ...);", "F func(fmt string, args ...any) int32 `std:\"f func(&#char,void...)int\"`"},
+		{"void f(FILE *stream);", "F func(stream File) `std:\"f func(&FILE)void\"`"},
+		{"void f(int (*cmp)(int, int));", "F func(cmp func(int32, int32) int32) `std:\"f func(&func(int,int)int)void\"`"},
+		{"int f(int type);", "F func(type_ int32) int32 `std:\"f func(int)int\"`"},
+		{"API int CALL f(double x[]);", "F func(x *float64) int32 `std:\"f func(&double)int\"`"},
+	} {
+		out, _ := generate(t, test.decl)
+		if !strings.Contains(strings.Join(strings.Fields(out), " "), test.field) {
+			t.Errorf("%s: expected %s in\n%s", test.decl, test.field, out)
+		}
+	}
+}
@@ -0,0 +1,766 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	gotoken "go/token"
+	"strconv"
+	"strings"
+)
+
+// usage of a C type within the bindings.
+type usage int
+
+const (
+	param    usage = iota // function parameter.
+	result                // function result.
+	member                // struct field.
+	callback              // parameter or result of a callback.
+)
+
+// raw is the binding for pointers that cannot be represented any better.
+const raw = "unsafe.Pointer"
+
+// scalars maps the basic C types to their Go type and standard tag
+// name. The sizes are those of LP64 platforms.
+var scalars = map[string][2]string{
+	"void":               {"", "void"},
+	"bool":               {"bool", "bool"},
+	"char":               {"byte", "char"},
+	"signed char":        {"int8", "schar"},
+	"unsigned char":      {"uint8", "uchar"},
+	"short":              {"int16", "short"},
+	"unsigned short":     {"uint16", "ushort"},
+	"int":                {"int32", "int"},
+	"unsigned int":       {"uint32", "uint"},
+	"long":               {"int64", "long"},
+	"unsigned long":      {"uint64", "ulong"},
+	"long long":          {"int64", "int64_t"},
+	"unsigned long long": {"uint64", "uint64_t"},
+	"float":              {"float32", "float"},
+	"double":             {"float64", "double"},
+	"int8_t":             {"int8", "int8_t"},
+	"int16_t":            {"int16", "int16_t"},
+	"int32_t":            {"int32", "int32_t"},
+	"int64_t":            {"int64", "int64_t"},
+	"uint8_t":            {"uint8", "uint8_t"},
+	"uint16_t":           {"uint16", "uint16_t"},
+	"uint32_t":           {"uint32", "uint32_t"},
+	"uint64_t":           {"uint64", "uint64_t"},
+	"size_t":             {"uintptr", "size_t"},
+	"ssize_t":            {"int", "ptrdiff_t"},
+	"ptrdiff_t":          {"int", "ptrdiff_t"},
+	"intptr_t":           {"int", "intptr_t"},
+	"uintptr_t":          {"uintptr", "uintptr_t"},
+}
+
+// named C type with a Go type of its own.
+type named struct {
+	kind   byte   // 's'calar, 'a'ggregate, 'h'andle, 'f'unction or 'c'onstants.
+	goName string // empty for the constants of an anonymous enum.
+	tag    string // standard tag type name.
+	doc    string
+
+	under  string   // Go type underlying a scalar.
+	values []cconst // of an enum.
+	exprs  []string // Go values of the enumerators, empty when unsupported.
+	strct  *cstruct // of an aggregate, or a handle to one.
+	fn     *cfunc   // of a function.
+
+	lazy bool // only declared when used, or else declared.
+	used bool
+}
+
+// ref is a reference to a named type by a C type name, where ptrs is the
+// number of indirections from the name to the named type (negative for
+// typedefs of pointers).
+type ref struct {
+	*named
+	ptrs int
+}
+
+// generator of Go bindings for a parsed header.
+type generator struct {
+	header   *header
+	pkg      string
+	sources  []string // names of the headers.
+	prefixes []string // trimmed from C names.
+	libs     [][2]string
+
+	typedefs map[string]*ctypedef
+	types    map[string]ref    // by C name, either a typedef name or "struct x", "union x" or "enum x".
+	decls    []*named          // in order of declaration.
+	consts   map[string]string // Go names of C constants.
+	names    map[string]bool   // Go names in the package scope.
+
+	warnings []string
+}
+
+func newGenerator(h *header, pkg string) *generator {
+	return &generator{
+		header:   h,
+		pkg:      pkg,
+		typedefs: make(map[string]*ctypedef),
+		types:    make(map[string]ref),
+		consts:   make(map[string]string),
+		names:    map[string]bool{"location": true, "Library": true},
+	}
+}
+
+func (g *generator) warnf(format string, args ...any) {
+	g.warnings = append(g.warnings, fmt.Sprintf(format, args...))
+}
+
+// export returns the exported Go name for the C name, with any of the
+// prefixes trimmed, in CamelCase.
+func (g *generator) export(name string) string {
+	for _, prefix := range g.prefixes {
+		if len(name) > len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) && !isDigit(name[len(prefix)]) {
+			name = name[len(prefix):]
+			break
+		}
+	}
+	return camel(name, true)
+}
+
+// camel converts a C name into a Go name in camelCase, or CamelCase when
+// exported. Words in upper case are converted to title case.
+func camel(name string, exported bool) string {
+	var b strings.Builder
+	for _, word := range strings.Split(name, "_") {
+		if word == "" {
+			continue
+		}
+		if strings.ToUpper(word) == word {
+			word = word[:1] + strings.ToLower(word[1:])
+		}
+		if b.Len() == 0 && !exported {
+			word = strings.ToLower(word[:1]) + word[1:]
+		} else {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		b.WriteString(word)
+	}
+	if b.Len() == 0 || isDigit(b.String()[0]) {
+		return "X" + b.String()
+	}
+	return b.String()
+}
+
+// declare a unique Go name in the package scope.
+func (g *generator) declare(name string) string {
+	for g.names[name] {
+		name += "_"
+	}
+	g.names[name] = true
+	return name
+}
+
+func (g *generator) add(n *named) *named {
+	n.goName = g.declare(n.goName)
+	g.decls = append(g.decls, n)
+	return n
+}
+
+// index the types declared by the header, deciding how each is bound.
+func (g *generator) index() {
+	aliases := make(map[string]*ctypedef) // typedefs that name a struct, union or enum.
+	for _, def := range g.header.typedefs {
+		g.typedefs[def.name] = def
+		t := def.ctype
+		if t.kind != "" && t.ptrs == 0 && t.array == "" && aliases[t.kind+" "+t.name] == nil {
+			aliases[t.kind+" "+t.name] = def
+		}
+	}
+	for _, enum := range g.header.enums {
+		key := "enum " + enum.name
+		n := &named{kind: 's', tag: "int", under: "int32", values: enum.values, doc: enum.doc}
+		switch def := aliases[key]; {
+		case def != nil:
+			n.goName = g.export(def.name)
+		case isIdent(enum.name[0]):
+			n.goName = g.export(enum.name)
+		default:
+			n.kind = 'c'
+			g.decls = append(g.decls, n)
+			g.constants(n)
+			continue
+		}
+		g.types[key] = ref{g.add(n), 0}
+		g.constants(n)
+	}
+	for _, s := range g.header.structs {
+		key := s.kind + " " + s.name
+		n := &named{kind: 'h', tag: s.name, strct: s, doc: s.doc, lazy: true}
+		if s.defined && !s.unsupported {
+			n.kind, n.lazy = 'a', false
+		}
+		if def := aliases[key]; def != nil {
+			n.tag, n.lazy = def.name, false
+		} else if !isIdent(s.name[0]) {
+			continue // anonymous.
+		}
+		n.goName = g.export(n.tag)
+		g.types[key] = ref{g.add(n), 0}
+	}
+	for _, def := range g.header.typedefs {
+		t := def.ctype
+		if t.kind != "" {
+			target, ok := g.types[t.kind+" "+t.name]
+			switch {
+			case ok && t.array == "":
+				// a typedef of a pointer names a handle that is otherwise unnamed.
+				if target.kind == 'h' && target.lazy && t.ptrs == 1 {
+					target.goName, target.lazy = g.declare(g.export(def.name)), false
+				}
+				g.types[def.name] = ref{target.named, target.ptrs - t.ptrs}
+			case !ok && t.kind != "enum" && t.ptrs == 1:
+				n := &named{kind: 'h', goName: g.export(def.name), tag: "void", doc: def.doc}
+				g.types[def.name] = ref{g.add(n), -1}
+			}
+			continue
+		}
+		switch {
+		case t.fn != nil && t.ptrs <= 1:
+			n := &named{kind: 'f', goName: g.export(def.name), fn: t.fn, doc: def.doc}
+			g.types[def.name] = ref{g.add(n), -t.ptrs}
+		case t.name == "void" && t.ptrs == 1:
+			n := &named{kind: 'h', goName: g.export(def.name), tag: "void", doc: def.doc}
+			g.types[def.name] = ref{g.add(n), -1}
+		case t.ptrs == 0 && t.array == "":
+			under, _, err := g.binding(t, member)
+			if err != nil || under == "" || under == raw {
+				continue
+			}
+			if target, ok := g.types[t.name]; ok && target.kind != 's' {
+				continue
+			}
+			n := &named{kind: 's', goName: g.export(def.name), under: under, doc: def.doc}
+			_, n.tag, _ = g.binding(t, member)
+			g.types[def.name] = ref{g.add(n), 0}
+		}
+	}
+	// structs that cannot be passed by value are bound as handles,
+	// which may in turn affect the structs they are members of.
+	for changed := true; changed; {
+		changed = false
+		for _, n := range g.decls {
+			if n.kind != 'a' {
+				continue
+			}
+			if _, err := g.fields(n.strct); err != nil {
+				g.warnf("%s %s is bound as a handle: %v", n.strct.kind, n.tag, err)
+				n.kind, changed = 'h', true
+			}
+		}
+	}
+	for _, n := range g.decls {
+		if n.kind != 'f' {
+			continue
+		}
+		if _, _, err := g.callback(n.fn); err != nil {
+			g.warnf("%s is bound as a handle: %v", n.goName, err)
+			n.kind, n.tag, n.fn = 'h', "void", nil
+		}
+	}
+}
+
+// binding returns the Go type and the standard tag type of the C type,
+// for the given usage.
+func (g *generator) binding(t ctype, use usage) (string, string, error) {
+	own := "&"
+	if t.konst {
+		own += "#"
+	}
+	pointer := func(goType, tag string) (string, string, error) {
+		if use == param && goType != "" {
+			return "*" + goType, own + tag, nil
+		}
+		return raw, own + tag, nil
+	}
+	if t.fn != nil {
+		if t.ptrs != 1 {
+			return raw, "&void", nil
+		}
+		goType, sig, err := g.callback(t.fn)
+		if err != nil || use != param {
+			return raw, "&void", nil
+		}
+		return goType, "&" + sig, nil
+	}
+	key := t.name
+	if t.kind != "" {
+		key = t.kind + " " + t.name
+	}
+	if target, ok := g.types[key]; ok {
+		n, diff := target.named, t.ptrs-target.ptrs
+		n.used = true
+		switch n.kind {
+		case 's':
+			switch diff {
+			case 0:
+				return n.goName, n.tag, nil
+			case 1:
+				return pointer(n.goName, n.tag)
+			}
+		case 'a':
+			switch {
+			case diff == 0 && use == callback:
+				return "", "", fmt.Errorf("%s passed by value to a callback", n.tag)
+			case diff == 0:
+				return n.goName, n.tag, nil
+			case diff == 1:
+				return pointer(n.goName, n.tag)
+			}
+		case 'h':
+			switch {
+			case diff < 1:
+				return "", "", fmt.Errorf("opaque %s passed by value", n.tag)
+			case diff == 1:
+				return n.goName, own + n.tag, nil
+			}
+		case 'f':
+			switch {
+			case diff < 1:
+				return "", "", fmt.Errorf("function %s passed by value", n.goName)
+			case diff == 1 && use == param:
+				_, sig, _ := g.callback(n.fn)
+				return n.goName, "&" + sig, nil
+			}
+		}
+		return raw, "&void", nil
+	}
+	if def, ok := g.typedefs[t.name]; ok && t.kind == "" {
+		under := def.ctype
+		under.ptrs += t.ptrs
+		under.konst = under.konst || t.konst && t.ptrs == 0
+		return g.binding(under, use)
+	}
+	if scalar, ok := scalars[t.name]; ok && t.kind == "" {
+		goType, tag := scalar[0], scalar[1]
+		switch {
+		case t.ptrs == 0 && goType == "" && use != result && use != callback:
+			return "", "", errors.New("void value")
+		case t.ptrs == 0:
+			return goType, tag, nil
+		case t.ptrs == 1 && tag == "char" && (use == param || use == result):
+			switch {
+			case t.konst:
+				return "string", own + "char", nil
+			case use == param:
+				return "[]byte", own + "char", nil
+			default:
+				return raw, own + "char", nil
+			}
+		case t.ptrs == 1 && goType == "":
+			return raw, own + "void", nil
+		case t.ptrs == 1:
+			return pointer(goType, tag)
+		}
+		return raw, "&void", nil
+	}
+	if t.ptrs == 0 {
+		return "", "", fmt.Errorf("unknown type %s", strings.TrimSpace(t.kind+" "+t.name))
+	}
+	// pointers to types declared elsewhere are bound as handles.
+	n := g.add(&named{kind: 'h', goName: g.export(t.name), tag: t.name})
+	g.types[key] = ref{n, 0}
+	return g.binding(t, use)
+}
+
+// callback returns the Go func type and the standard signature of a
+// function pointer.
+func (g *generator) callback(fn *cfunc) (string, string, error) {
+	var goTypes, tags []string
+	for _, p := range fn.params {
+		goType, tag, err := g.binding(p.ctype, callback)
+		if err != nil {
+			return "", "", err
+		}
+		goTypes, tags = append(goTypes, goType), append(tags, tag)
+	}
+	if fn.variadic {
+		return "", "", errors.New("variadic callback")
+	}
+	goType, tag, err := g.binding(fn.result, callback)
+	if err != nil {
+		return "", "", err
+	}
+	return "func(" + strings.Join(goTypes, ", ") + ") " + goType, "func(" + strings.Join(tags, ",") + ")" + tag, nil
+}
+
+// fields returns the Go fields of a struct.
+func (g *generator) fields(s *cstruct) ([]string, error) {
+	var (
+		fields []string
+		names  = make(map[string]bool)
+	)
+	for _, field := range s.fields {
+		t := field.ctype
+		t.array = ""
+		goType, tag, err := g.binding(t, member)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.name, err)
+		}
+		name := camel(field.name, true)
+		for names[name] {
+			name += "_"
+		}
+		names[name] = true
+		if field.ctype.array != "" {
+			size, err := g.expr(strings.Fields(field.ctype.array), nil)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.name, err)
+			}
+			fields = append(fields, fmt.Sprintf("%s [%s]%s", name, size, goType))
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%s %s `std:\"%s %s\"`", name, goType, field.name, tag))
+	}
+	return fields, nil
+}
+
+// constants declares the Go names of the enumerators of an enum,
+// and converts their values into Go.
+func (g *generator) constants(n *named) {
+	var previous string // Go name of the previous enumerator.
+	for i, value := range n.values {
+		var expr string
+		switch {
+		case value.value != nil:
+			var err error
+			if expr, err = g.expr(nil, value.value); err != nil {
+				g.warnf("%s: %v", value.name, err)
+			}
+		case i == 0:
+			expr = "0"
+		case previous != "":
+			expr = previous + " + 1"
+		default:
+			g.warnf("%s: follows an unsupported enumerator", value.name)
+		}
+		n.exprs = append(n.exprs, expr)
+		if previous = ""; expr != "" {
+			previous = g.declare(g.export(value.name))
+			g.consts[value.name] = previous
+		}
+	}
+}
+
+// expr converts a C constant expression into Go, where texts are the
+// tokens of the expression (or tokens is used instead, when non-nil).
+func (g *generator) expr(texts []string, tokens []token) (string, error) {
+	if tokens == nil {
+		for _, text := range texts {
+			kind := byte('p')
+			switch {
+			case isIdent(text[0]):
+				kind = 'i'
+			case isDigit(text[0]):
+				kind = 'n'
+			}
+			tokens = append(tokens, token{kind: kind, text: text})
+		}
+	}
+	if len(tokens) == 0 {
+		return "", errors.New("no value")
+	}
+	var b strings.Builder
+	for i, tok := range tokens {
+		switch tok.kind {
+		case 'n':
+			number, err := literal(tok.text)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(number)
+		case 's':
+			if i > 0 && tokens[i-1].kind == 's' {
+				b.WriteString(" + ")
+			}
+			if _, err := strconv.Unquote(tok.text); err != nil {
+				return "", fmt.Errorf("unsupported string %s", tok.text)
+			}
+			b.WriteString(tok.text)
+		case 'c':
+			if _, _, _, err := strconv.UnquoteChar(tok.text[1:len(tok.text)-1], '\''); err != nil {
+				return "", fmt.Errorf("unsupported character %s", tok.text)
+			}
+			b.WriteString(tok.text)
+		case 'i':
+			name, ok := g.consts[tok.text]
+			if !ok {
+				return "", fmt.Errorf("unsupported identifier %s", tok.text)
+			}
+			b.WriteString(name)
+		default:
+			switch tok.text {
+			case "~":
+				b.WriteString("^")
+			case "+", "-", "*", "/", "%", "<<", ">>", "|", "&", "^", "(", ")":
+				b.WriteString(tok.text)
+			default:
+				return "", fmt.Errorf("unsupported operator %s", tok.text)
+			}
+		}
+		b.WriteByte(' ')
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// literal converts a C number literal into Go.
+func literal(text string) (string, error) {
+	lower := strings.ToLower(text)
+	hex := strings.HasPrefix(lower, "0x")
+	float := strings.ContainsAny(lower, ".p") || !hex && strings.Contains(lower, "e")
+	if float {
+		text = strings.TrimRight(text, "fFlL")
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return "", fmt.Errorf("unsupported number %s", text)
+		}
+		return text, nil
+	}
+	text = strings.TrimRight(text, "uUlL")
+	if _, err := strconv.ParseUint(text, 0, 64); err != nil {
+		return "", fmt.Errorf("unsupported number %s", text)
+	}
+	return text, nil
+}
+
+// summary returns the first sentence of a C comment.
+func summary(doc string) string {
+	var words []string
+	for _, line := range strings.Split(doc, "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), "*!</")
+		for _, word := range strings.Fields(line) {
+			if word == "\\brief" || word == "@brief" {
+				continue
+			}
+			words = append(words, word)
+			if strings.HasSuffix(word, ".") {
+				return strings.Join(words, " ")
+			}
+		}
+		if line == "" && len(words) > 0 {
+			break
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// params returns the Go names of the parameters, or nil if any of them
+// are unnamed.
+func params(list []cparam) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, param := range list {
+		name := camel(param.name, false)
+		if param.name == "" || seen[name] {
+			return nil
+		}
+		if gotoken.IsKeyword(name) {
+			name += "_"
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// function returns the Library field for the C function.
+func (g *generator) function(fn *cfunc) (goType, tag string, err error) {
+	var (
+		goTypes []string
+		tags    []string
+		names   = params(fn.params)
+	)
+	for i, p := range fn.params {
+		goType, tag, err := g.binding(p.ctype, param)
+		if err != nil {
+			return "", "", fmt.Errorf("parameter %d: %w", i+1, err)
+		}
+		if names != nil {
+			goType = names[i] + " " + goType
+		}
+		goTypes, tags = append(goTypes, goType), append(tags, tag)
+	}
+	if fn.variadic {
+		variadic := "...any"
+		if names != nil {
+			variadic = "args " + variadic
+		}
+		goTypes, tags = append(goTypes, variadic), append(tags, "void...")
+	}
+	goResult, tagResult, err := g.binding(fn.result, result)
+	if err != nil {
+		return "", "", fmt.Errorf("result: %w", err)
+	}
+	goType = "func(" + strings.Join(goTypes, ", ") + ") " + goResult
+	tag = fn.name + " func(" + strings.Join(tags, ",") + ")" + tagResult
+	return goType, tag, nil
+}
+
+// generate the Go source of the bindings.
+func (g *generator) generate() ([]byte, error) {
+	g.index()
+	var (
+		library bytes.Buffer
+		fields  = make(map[string]bool)
+	)
+	for _, fn := range g.header.funcs {
+		goType, tag, err := g.function(fn)
+		if err != nil {
+			g.warnf("%s: %v", fn.name, err)
+			fmt.Fprintf(&library, "\n// %s is not supported: %v\n", fn.name, err)
+			continue
+		}
+		name := g.export(fn.name)
+		for fields[name] {
+			name += "_"
+		}
+		fields[name] = true
+		fmt.Fprintf(&library, "%s %s `std:\"%s\"`", name, goType, tag)
+		if doc := summary(fn.doc); doc != "" {
+			fmt.Fprintf(&library, " // %s", doc)
+		}
+		library.WriteByte('\n')
+	}
+	var consts bytes.Buffer
+	for _, def := range g.header.defines {
+		if _, ok := g.consts[def.name]; ok || g.names[g.export(def.name)] {
+			continue
+		}
+		value, err := g.expr(nil, def.value)
+		if err != nil {
+			continue // not a constant.
+		}
+		g.consts[def.name] = g.declare(g.export(def.name))
+		fmt.Fprintf(&consts, "%s = %s", g.consts[def.name], value)
+		if doc := summary(def.doc); doc != "" {
+			fmt.Fprintf(&consts, " // %s", doc)
+		}
+		consts.WriteByte('\n')
+	}
+	var body bytes.Buffer
+	if len(g.libs) > 0 {
+		body.WriteString("\ntype location struct {\n")
+		for _, lib := range g.libs {
+			fmt.Fprintf(&body, "%s lib.Location `std:%q`\n", lib[0], lib[1])
+		}
+		body.WriteString("}\n")
+	}
+	if consts.Len() > 0 {
+		fmt.Fprintf(&body, "\nconst (\n%s)\n", consts.Bytes())
+	}
+	g.declarations(&body)
+	fmt.Fprintf(&body, "\n// Library of the functions declared by %s.\n", strings.Join(g.sources, ", "))
+	body.WriteString("type Library struct {\n")
+	if len(g.libs) > 0 {
+		body.WriteString("location\n\n")
+	}
+	body.Write(library.Bytes())
+	body.WriteString("}\n")
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by bindgen from %s, review before editing.\n\n", strings.Join(g.sources, ", "))
+	fmt.Fprintf(&out, "// Package %s provides bindings for %s.\n", g.pkg, strings.Join(g.sources, ", "))
+	out.WriteString(`//
+// The standard tags are pessimistic: pointers are borrowed for the
+// duration of each call, and pointers returned by the library are never
+// freed. Review each tag against the documentation of the library, and
+// refine its ownership assertions ($ for owned, ^ for static pointers)
+// and error handling.
+`)
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", g.pkg)
+	if bytes.Contains(body.Bytes(), []byte(raw)) {
+		out.WriteString("\"unsafe\"\n\n")
+	}
+	if len(g.libs) > 0 {
+		out.WriteString("\"runtime.link/lib\"\n")
+	}
+	if bytes.Contains(body.Bytes(), []byte("std.")) {
+		out.WriteString("\"runtime.link/std\"\n")
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("generated invalid Go: %w", err)
+	}
+	return src, nil
+}
+
+// declarations writes the Go type declarations.
+func (g *generator) declarations(w *bytes.Buffer) {
+	var handles []*named
+	for _, n := range g.decls {
+		if n.kind == 'h' && (!n.lazy || n.used) {
+			handles = append(handles, n)
+		}
+	}
+	if len(handles) > 0 {
+		w.WriteString("\n// Handles.\ntype (\n")
+		for _, n := range handles {
+			fmt.Fprintf(w, "%s std.Handle[%s]", n.goName, n.goName)
+			if doc := summary(n.doc); doc != "" {
+				fmt.Fprintf(w, " // %s", doc)
+			}
+			w.WriteByte('\n')
+		}
+		w.WriteString(")\n")
+	}
+	for _, n := range g.decls {
+		var comment string
+		if doc := summary(n.doc); doc != "" {
+			comment = " // " + doc
+		}
+		switch n.kind {
+		case 's':
+			fmt.Fprintf(w, "\ntype %s %s%s\n", n.goName, n.under, comment)
+			if n.values != nil {
+				g.enumerators(w, n)
+			}
+		case 'c':
+			g.enumerators(w, n)
+		case 'a':
+			fields, _ := g.fields(n.strct)
+			fmt.Fprintf(w, "\ntype %s struct {%s\n%s\n}\n", n.goName, comment, strings.Join(fields, "\n"))
+		case 'f':
+			goType, _, _ := g.callback(n.fn)
+			fmt.Fprintf(w, "\ntype %s %s%s\n", n.goName, goType, comment)
+		}
+	}
+}
+
+// enumerators writes the constants of an enum.
+func (g *generator) enumerators(w *bytes.Buffer, n *named) {
+	implicit := true
+	for _, value := range n.values {
+		implicit = implicit && value.value == nil
+	}
+	typ := ""
+	if n.goName != "" {
+		typ = " " + n.goName
+	}
+	w.WriteString("\nconst (\n")
+	for i, value := range n.values {
+		switch {
+		case n.exprs[i] == "":
+			fmt.Fprintf(w, "// %s is not supported.\n", value.name)
+			continue
+		case implicit && i == 0:
+			fmt.Fprintf(w, "%s%s = iota", g.consts[value.name], typ)
+		case implicit:
+			w.WriteString(g.consts[value.name])
+		default:
+			fmt.Fprintf(w, "%s%s = %s", g.consts[value.name], typ, n.exprs[i])
+		}
+		if doc := summary(value.doc); doc != "" {
+			fmt.Fprintf(w, " // %s", doc)
+		}
+		w.WriteByte('\n')
+	}
+	w.WriteString(")\n")
+}
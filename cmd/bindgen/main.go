@@ -0,0 +1,155 @@
+// Command bindgen generates a Go package with runtime.link bindings for the
+// functions declared in C header files.
+//
+// Usage:
+//
+//	bindgen [flags] header.h...
+//
+// The headers are not preprocessed, so only the practical subset of C used
+// to declare the interface of a library is understood: function prototypes,
+// typedefs, struct, union and enum declarations, along with object-like
+// #define constants. Anything else is skipped with a warning, as are
+// declarations that cannot be bound. Export and calling convention macros
+// in upper case are skipped, any others can be listed with -ignore.
+//
+// The generated package follows the layout of runtime.link/lib/sdl/v2, a
+// location struct with the [lib.Location] of the library on each GOOS is
+// embedded into a Library struct, with a func field for each function:
+//
+//	type Library struct {
+//		location
+//
+//		CreateWindow func(title string, w int32, h int32) Window `std:"foo_create_window func(&#char,int,int)&foo_window"`
+//	}
+//
+// Opaque structs become typed handles ([std.Handle]), structs with known
+// members become Go structs, enums become typed constants. The ownership
+// assertions of the generated tags are pessimistic, pointers are borrowed
+// and never freed, so that the bindings can be refined by hand.
+//
+// The flags are:
+//
+//	-o file
+//		write the package to file, instead of standard output.
+//	-pkg name
+//		name of the generated package, defaults to the name of the first header.
+//	-prefix list
+//		comma separated prefixes to trim from C names, such as foo_,FOO_
+//	-ignore list
+//		comma separated macros to ignore within declarations.
+//	-linux, -darwin, -windows names
+//		space separated names of the library on each GOOS, defaults to
+//		libpkg.so, libpkg.dylib and pkg.dll respectively.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		output  = flag.String("o", "", "output file (default standard output)")
+		pkg     = flag.String("pkg", "", "package name (default name of the first header)")
+		prefix  = flag.String("prefix", "", "comma separated prefixes to trim from C names")
+		ignore  = flag.String("ignore", "", "comma separated macros to ignore")
+		linux   = flag.String("linux", "", "library names on linux (default libpkg.so)")
+		darwin  = flag.String("darwin", "", "library names on darwin (default libpkg.dylib)")
+		windows = flag.String("windows", "", "library names on windows (default pkg.dll)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: bindgen [flags] header.h...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *pkg == "" {
+		*pkg = packageName(flag.Arg(0))
+	}
+	var (
+		h       header
+		ignored = make(map[string]bool)
+	)
+	for _, name := range split(*ignore) {
+		ignored[name] = true
+	}
+	var sources []string
+	for _, path := range flag.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fatal(err)
+		}
+		if err := parse(&h, string(src), ignored); err != nil {
+			fatal(fmt.Errorf("%s: %w", path, err))
+		}
+		sources = append(sources, filepath.Base(path))
+	}
+	g := newGenerator(&h, *pkg)
+	g.sources = sources
+	g.prefixes = split(*prefix)
+	g.libs = [][2]string{
+		{"linux", or(*linux, "lib"+*pkg+".so")},
+		{"darwin", or(*darwin, "lib"+*pkg+".dylib")},
+		{"windows", or(*windows, *pkg+".dll")},
+	}
+	src, err := g.generate()
+	for _, skipped := range h.skipped {
+		fmt.Fprintln(os.Stderr, "bindgen: skipped", skipped)
+	}
+	for _, warning := range g.warnings {
+		fmt.Fprintln(os.Stderr, "bindgen:", warning)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	if *output == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		fatal(err)
+	}
+}
+
+// packageName returns the Go package name for the header at path.
+func packageName(path string) string {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, name)
+	if name == "" || isDigit(name[0]) {
+		name = "lib" + name
+	}
+	return name
+}
+
+func split(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func or(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "bindgen:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,691 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ctype is a C type, as far as bindings are concerned.
+type ctype struct {
+	name  string // builtin type, typedef name or struct/union/enum tag.
+	kind  string // "struct", "union" or "enum" when name is a tag.
+	konst bool   // the value (or for pointers, the value pointed to) is const.
+	ptrs  int    // levels of indirection.
+	array string // length of a struct field array.
+	fn    *cfunc // function type, ptrs counts the pointers to it.
+}
+
+// cfunc is a function declaration or function type.
+type cfunc struct {
+	name     string
+	doc      string
+	result   ctype
+	params   []cparam
+	variadic bool
+}
+
+// cparam is a function parameter or struct field.
+type cparam struct {
+	name  string
+	ctype ctype
+}
+
+// cstruct is a struct or union declaration.
+type cstruct struct {
+	kind   string // "struct" or "union"
+	name   string
+	fields []cparam
+	doc    string
+
+	defined     bool // the members are known.
+	unsupported bool // the members cannot be represented in Go.
+}
+
+// cenum is an enum declaration.
+type cenum struct {
+	name   string
+	values []cconst
+	doc    string
+}
+
+// cconst is an enumerator, or a constant #define.
+type cconst struct {
+	name  string
+	value []token // nil for implicit enumerator values.
+	doc   string
+}
+
+// ctypedef is a typedef declaration.
+type ctypedef struct {
+	name  string
+	ctype ctype
+	doc   string
+}
+
+// header is the result of parsing C headers.
+type header struct {
+	funcs    []*cfunc
+	structs  []*cstruct
+	enums    []*cenum
+	typedefs []*ctypedef
+	defines  []define
+	skipped  []string // declarations that could not be parsed.
+}
+
+// parser for the subset of C used to declare the interface of a library:
+// function prototypes, typedefs, struct, union and enum declarations. The
+// declarations of anything else is skipped.
+type parser struct {
+	tokens []token
+	pos    int
+	header *header
+	ignore map[string]bool // macros to ignore, such as calling conventions.
+
+	typedefs map[string]bool
+	funcs    map[string]bool
+	tags     map[string]*cstruct // by "struct name" and "union name".
+	anon     int
+}
+
+// bailout is panicked to abandon the current declaration.
+type bailout struct{ msg string }
+
+func (p *parser) failf(format string, args ...any) {
+	panic(bailout{fmt.Sprintf(format, args...)})
+}
+
+func parse(h *header, src string, ignore map[string]bool) error {
+	tokens, defines, err := scan(src)
+	if err != nil {
+		return err
+	}
+	h.defines = append(h.defines, defines...)
+	p := parser{
+		tokens:   tokens,
+		header:   h,
+		ignore:   make(map[string]bool),
+		typedefs: make(map[string]bool),
+		funcs:    make(map[string]bool),
+		tags:     make(map[string]*cstruct),
+	}
+	for name := range ignore {
+		p.ignore[name] = true
+	}
+	for _, def := range h.typedefs {
+		p.typedefs[def.name] = true
+	}
+	for _, fn := range h.funcs {
+		p.funcs[fn.name] = true
+	}
+	for _, s := range h.structs {
+		p.tags[s.kind+" "+s.name] = s
+	}
+	// macros that are empty (in any branch of a conditional), or only
+	// qualifiers, are ignored. Those that name a basic type are typedefs.
+	for _, def := range h.defines {
+		var words []string
+		ignored := true
+		for _, tok := range def.value {
+			words = append(words, tok.text)
+			ignored = ignored && (tok.text == "const" || qualifiers[tok.text])
+		}
+		switch {
+		case ignored:
+			p.ignore[def.name] = true
+		case !p.typedefs[def.name] && isBuiltin(words):
+			p.typedefs[def.name] = true
+			h.typedefs = append(h.typedefs, &ctypedef{name: def.name, ctype: ctype{name: builtin(words)}, doc: def.doc})
+		}
+	}
+	for p.pos < len(p.tokens) {
+		p.external()
+	}
+	return nil
+}
+
+func (p *parser) peek(n int) token {
+	if p.pos+n >= len(p.tokens) {
+		return token{}
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *parser) next() token {
+	tok := p.peek(0)
+	if p.pos >= len(p.tokens) {
+		p.failf("unexpected end of file")
+	}
+	p.pos++
+	return tok
+}
+
+func (p *parser) accept(text string) bool {
+	if tok := p.peek(0); tok.kind != 0 && tok.kind != 's' && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) {
+	if !p.accept(text) {
+		p.failf("expected %q, found %q", text, p.peek(0).text)
+	}
+}
+
+// external parses a declaration at file scope, skipping over it (and
+// recording why) if it cannot be parsed.
+func (p *parser) external() {
+	start := p.pos
+	defer func() {
+		if r := recover(); r != nil {
+			failure, ok := r.(bailout)
+			if !ok {
+				panic(r)
+			}
+			p.pos = start
+			p.skip()
+			p.header.skipped = append(p.header.skipped, fmt.Sprintf("line %d: %s", p.tokens[start].line, failure.msg))
+		}
+	}()
+	switch {
+	case p.accept(";"), p.accept("}"): // end of an extern "C" block.
+	case p.peek(0).text == "extern" && p.peek(1).kind == 's':
+		p.pos += 2
+		p.accept("{")
+	case p.accept("typedef"):
+		p.typedef()
+	default:
+		p.declaration()
+	}
+}
+
+// skip to the end of the current declaration, which is either a ';' or
+// the '}' closing the body of a function.
+func (p *parser) skip() {
+	var depth int
+	var params bool
+	for p.pos < len(p.tokens) {
+		tok := p.next()
+		if tok.kind != 'p' {
+			continue
+		}
+		switch tok.text {
+		case "(", "[", "{":
+			if tok.text == "(" && depth == 0 {
+				params = true
+			}
+			depth++
+		case ")", "]", "}":
+			depth--
+			if tok.text == "}" && depth == 0 && params {
+				return
+			}
+		case ";":
+			if depth <= 0 {
+				return
+			}
+		}
+	}
+}
+
+// skipAttributes skips over compiler specific attributes.
+func (p *parser) skipAttributes() bool {
+	switch p.peek(0).text {
+	case "__attribute__", "__attribute", "__declspec", "__asm__", "__asm", "asm", "_Alignas", "alignas":
+		p.pos++
+		p.balanced()
+		return true
+	}
+	return false
+}
+
+// balanced skips a parenthesized list of tokens, returning them.
+func (p *parser) balanced() []token {
+	start := p.pos
+	p.expect("(")
+	for depth := 1; depth > 0; {
+		switch p.next().text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+	}
+	return p.tokens[start+1 : p.pos-1]
+}
+
+// qualifiers and storage classes that do not affect the bindings.
+var qualifiers = map[string]bool{
+	"volatile": true, "restrict": true, "__restrict": true, "__restrict__": true,
+	"extern": true, "register": true, "_Noreturn": true, "__extension__": true,
+	"__cdecl": true, "__stdcall": true, "_Nonnull": true, "_Nullable": true,
+}
+
+// builtins are the keywords that make up the basic C types.
+var builtins = map[string]bool{
+	"void": true, "char": true, "short": true, "int": true, "long": true,
+	"float": true, "double": true, "signed": true, "unsigned": true,
+	"_Bool": true, "bool": true,
+}
+
+// specifiers parses the declaration specifiers before a declarator,
+// reporting whether the declaration is static or inline.
+func (p *parser) specifiers() (ctype, bool) {
+	var (
+		t     ctype
+		words []string
+		local bool
+		macro bool // t.name may be an unknown macro, rather than a type.
+	)
+loop:
+	for {
+		if p.skipAttributes() {
+			continue
+		}
+		tok := p.peek(0)
+		if tok.kind != 'i' {
+			break
+		}
+		switch {
+		case tok.text == "const":
+			t.konst = true
+		case tok.text == "static" || tok.text == "inline" || tok.text == "__inline" || tok.text == "__inline__":
+			local = true
+		case qualifiers[tok.text] || p.ignore[tok.text]:
+		case builtins[tok.text]:
+			words = append(words, tok.text)
+		case tok.text == "struct" || tok.text == "union" || tok.text == "enum":
+			p.pos++
+			t.kind, t.name = tok.text, p.tagged(tok.text)
+			macro = false
+			continue
+		default:
+			// an identifier that follows a type is the declarator, unless
+			// the type was actually a macro, such as an API export macro.
+			next := p.peek(1)
+			switch {
+			case len(words) == 0 && t.name == "":
+				t.name, macro = tok.text, !p.typedefs[tok.text] && isMacro(tok.text)
+			case len(words) == 0 && macro && (next.kind == 'i' || next.text == "*"):
+				t.name, macro = tok.text, false
+			default:
+				break loop
+			}
+		}
+		p.pos++
+	}
+	if len(words) > 0 {
+		if t.kind == "" && t.name != "" && !macro {
+			p.failf("unexpected %s after %s", strings.Join(words, " "), t.name)
+		}
+		t.name, t.kind = builtin(words), ""
+		if t.name == "" {
+			p.failf("unsupported type %s", strings.Join(words, " "))
+		}
+	}
+	if t.name == "" {
+		p.failf("expected a type, found %q", p.peek(0).text)
+	}
+	return t, local
+}
+
+// isBuiltin reports whether the words make up a basic C type.
+func isBuiltin(words []string) bool {
+	for _, word := range words {
+		if !builtins[word] {
+			return false
+		}
+	}
+	return len(words) > 0 && builtin(words) != ""
+}
+
+// isMacro reports whether the name follows the naming convention for
+// macros, such that it is likely to be an export or calling convention
+// macro when it appears before a type.
+func isMacro(name string) bool {
+	return len(name) > 1 && strings.ToUpper(name) == name
+}
+
+// builtin returns the canonical name of the basic C type
+// made up of the given keywords.
+func builtin(words []string) string {
+	count := make(map[string]int)
+	for _, word := range words {
+		count[word]++
+	}
+	var sign string
+	switch {
+	case count["unsigned"] > 0:
+		sign = "unsigned "
+	case count["signed"] > 0:
+		sign = "signed "
+	}
+	switch {
+	case count["void"] > 0:
+		return "void"
+	case count["_Bool"] > 0 || count["bool"] > 0:
+		return "bool"
+	case count["char"] > 0:
+		return sign + "char"
+	case count["float"] > 0:
+		return "float"
+	case count["double"] > 0 && count["long"] > 0:
+		return "" // long double
+	case count["double"] > 0:
+		return "double"
+	}
+	sign = strings.TrimPrefix(sign, "signed ")
+	switch {
+	case count["short"] > 0:
+		return sign + "short"
+	case count["long"] > 1:
+		return sign + "long long"
+	case count["long"] > 0:
+		return sign + "long"
+	default:
+		return sign + "int"
+	}
+}
+
+// tagged parses the tag and/or body of a struct, union or enum,
+// returning the tag (which is generated for anonymous types).
+func (p *parser) tagged(kind string) string {
+	for p.skipAttributes() {
+	}
+	var name string
+	if p.peek(0).kind == 'i' {
+		name = p.next().text
+	}
+	doc := p.peek(0).doc
+	if !p.accept("{") {
+		if name == "" {
+			p.failf("expected %s tag", kind)
+		}
+		if kind != "enum" {
+			p.declareStruct(kind, name)
+		}
+		return name
+	}
+	if name == "" {
+		p.anon++
+		name = fmt.Sprintf("%d", p.anon) // not an identifier, so it cannot clash.
+	}
+	if kind == "enum" {
+		p.enumBody(name, doc)
+		return name
+	}
+	s := p.declareStruct(kind, name)
+	if s.defined {
+		p.failf("redefinition of %s %s", kind, name)
+	}
+	s.defined, s.doc = true, doc
+	if kind == "union" {
+		s.unsupported = true
+	}
+	for !p.accept("}") {
+		base, _ := p.specifiers()
+		if base.kind != "" && base.kind != "enum" && !isIdent(base.name[0]) {
+			s.unsupported = true // nested anonymous struct or union.
+		}
+		if p.accept(";") {
+			continue
+		}
+		for {
+			name, member := p.declarator(base, true)
+			if p.accept(":") {
+				p.next()
+				s.unsupported = true // bitfield.
+			}
+			if member.fn != nil && member.ptrs == 0 {
+				p.failf("function %s declared as a member of %s %s", name, kind, s.name)
+			}
+			s.fields = append(s.fields, cparam{name: name, ctype: member})
+			if !p.accept(",") {
+				break
+			}
+		}
+		p.expect(";")
+	}
+	for p.skipAttributes() {
+	}
+	return name
+}
+
+func (p *parser) declareStruct(kind, name string) *cstruct {
+	if s, ok := p.tags[kind+" "+name]; ok {
+		return s
+	}
+	s := &cstruct{kind: kind, name: name}
+	p.tags[kind+" "+name] = s
+	p.header.structs = append(p.header.structs, s)
+	return s
+}
+
+func (p *parser) enumBody(name, doc string) {
+	enum := &cenum{name: name, doc: doc}
+	for !p.accept("}") {
+		tok := p.next()
+		if tok.kind != 'i' {
+			p.failf("expected enumerator, found %q", tok.text)
+		}
+		value := cconst{name: tok.text, doc: tok.doc}
+		if p.accept("=") {
+			value.value = []token{}
+			for depth := 0; depth > 0 || p.peek(0).text != "," && p.peek(0).text != "}"; {
+				tok := p.next()
+				switch tok.text {
+				case "(":
+					depth++
+				case ")":
+					depth--
+				}
+				value.value = append(value.value, tok)
+			}
+		}
+		last := !p.accept(",")
+		if value.doc == "" {
+			value.doc = p.tokens[p.pos-1].post
+		}
+		enum.values = append(enum.values, value)
+		if last {
+			p.expect("}")
+			break
+		}
+	}
+	p.header.enums = append(p.header.enums, enum)
+}
+
+// declarator parses the pointers, name, array dimensions and parameter
+// lists that make up a declarator. Within a struct, array dimensions are
+// kept, otherwise they decay into pointers.
+func (p *parser) declarator(base ctype, member bool) (string, ctype) {
+	t := base
+pointers:
+	for {
+		tok := p.peek(0)
+		switch {
+		case p.accept("*"):
+			t.ptrs++
+		case p.accept("const"), p.skipAttributes():
+		case qualifiers[tok.text] || p.ignore[tok.text] && !p.wrapper():
+			p.pos++
+		case tok.kind == 'i' && isMacro(tok.text) && p.peek(1).text == "*":
+			p.pos++ // such as FAR
+		default:
+			break pointers
+		}
+	}
+	var (
+		name   string
+		fnptrs = -1 // pointers to a function, when parenthesized.
+	)
+	// any identifiers before the name are macros, such as calling conventions.
+	for p.peek(0).kind == 'i' && !p.wrapper() {
+		name = p.next().text
+	}
+	if name == "" && p.peek(0).text == "(" && !p.startsParams() {
+		p.pos++
+		for p.peek(0).kind == 'i' && p.peek(1).text != ")" {
+			p.pos++ // calling convention.
+		}
+		fnptrs = 0
+		for p.accept("*") {
+			fnptrs++
+		}
+		p.accept("const")
+		if p.peek(0).kind == 'i' {
+			name = p.next().text
+		}
+		for p.accept("[") {
+			p.dimension()
+			fnptrs++
+		}
+		p.expect(")")
+	}
+	wrapped := p.wrapper()
+	if wrapped {
+		p.pos += 2
+	}
+	if p.accept("(") {
+		fn := &cfunc{name: name, result: t}
+		fn.params, fn.variadic = p.params()
+		if wrapped {
+			p.expect(")")
+		}
+		t = ctype{fn: fn, ptrs: max(fnptrs, 0)}
+		for p.skipAttributes() {
+		}
+		return name, t
+	}
+	if fnptrs >= 0 {
+		p.failf("expected parameters for %s", name)
+	}
+	for p.accept("[") {
+		size := p.dimension()
+		switch {
+		case !member:
+			t.ptrs++
+		case t.array != "":
+			p.failf("multi-dimensional array %s", name)
+		case size == "":
+			p.failf("flexible array member %s", name)
+		default:
+			t.array = size
+		}
+	}
+	for p.skipAttributes() {
+	}
+	return name, t
+}
+
+// wrapper reports whether the current token is an ignored macro that
+// wraps a parameter list, such as OF((int x)) within zlib headers.
+func (p *parser) wrapper() bool {
+	return p.ignore[p.peek(0).text] && p.peek(1).text == "(" && p.peek(2).text == "("
+}
+
+// startsParams reports whether the '(' at the current position
+// starts a parameter list, rather than a nested declarator.
+func (p *parser) startsParams() bool {
+	next := p.peek(1)
+	return next.text == ")" || next.text == "..." || builtins[next.text] || next.text == "const" ||
+		next.text == "struct" || next.text == "union" || next.text == "enum" || p.typedefs[next.text]
+}
+
+// dimension parses an array dimension, up to the closing ']'.
+func (p *parser) dimension() string {
+	var size []string
+	for !p.accept("]") {
+		size = append(size, p.next().text)
+	}
+	return strings.Join(size, " ")
+}
+
+func (p *parser) params() ([]cparam, bool) {
+	if p.accept(")") {
+		return nil, false
+	}
+	if p.peek(0).text == "void" && p.peek(1).text == ")" {
+		p.pos += 2
+		return nil, false
+	}
+	var params []cparam
+	for {
+		if p.accept("...") {
+			p.expect(")")
+			return params, true
+		}
+		base, _ := p.specifiers()
+		name, t := p.declarator(base, false)
+		if t.fn != nil && t.ptrs == 0 {
+			t.ptrs = 1 // function parameters decay into function pointers.
+		}
+		params = append(params, cparam{name: name, ctype: t})
+		if !p.accept(",") {
+			p.expect(")")
+			return params, false
+		}
+	}
+}
+
+func (p *parser) typedef() {
+	doc := p.tokens[p.pos-1].doc
+	base, _ := p.specifiers()
+	// the documentation of the typedef applies to any type it defines.
+	if s, ok := p.tags[base.kind+" "+base.name]; ok && s.doc == "" {
+		s.doc = doc
+	}
+	for _, enum := range p.header.enums {
+		if base.kind == "enum" && enum.name == base.name && enum.doc == "" {
+			enum.doc = doc
+		}
+	}
+	for {
+		name, t := p.declarator(base, true)
+		if name == "" {
+			p.failf("typedef without a name")
+		}
+		if !p.typedefs[name] {
+			p.typedefs[name] = true
+			p.header.typedefs = append(p.header.typedefs, &ctypedef{name: name, ctype: t, doc: doc})
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(";")
+}
+
+func (p *parser) declaration() {
+	doc := p.peek(0).doc
+	base, local := p.specifiers()
+	if p.accept(";") {
+		return // struct, union or enum declaration.
+	}
+	for {
+		name, t := p.declarator(base, false)
+		if p.peek(0).text == "{" {
+			p.skip()
+			if !local {
+				p.failf("function definition %s", name)
+			}
+			return
+		}
+		switch {
+		case local:
+		case t.fn != nil && t.ptrs == 0:
+			if !p.funcs[name] {
+				p.funcs[name] = true
+				t.fn.doc = doc
+				p.header.funcs = append(p.header.funcs, t.fn)
+			}
+		default:
+			p.header.skipped = append(p.header.skipped, fmt.Sprintf("line %d: variable %s", p.tokens[p.pos-1].line, name))
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(";")
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// token of C source.
+type token struct {
+	kind byte   // 'i'dentifier, 'n'umber, 's'tring, 'c'haracter or 'p'unctuation.
+	text string // as written in the source.
+	doc  string // text of the comments immediately before the token.
+	post string // text of a trailing documentation comment after the token.
+	line int
+}
+
+// define is an object-like #define directive.
+type define struct {
+	name  string
+	value []token
+	doc   string
+}
+
+// punctuation of C, longest first.
+var punctuation = []string{
+	"...", "<<=", ">>=",
+	"<<", ">>", "->", "++", "--", "&&", "||", "==", "!=", "<=", ">=",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "##",
+}
+
+// scanner splits C source into tokens, collecting object-like #define
+// directives along the way. Other preprocessor directives are ignored,
+// so the source is scanned as if every conditional was true.
+type scanner struct {
+	src     string
+	pos     int
+	line    int
+	doc     []string // comments since the last token.
+	docEnd  int      // line on which the last comment ended.
+	tokens  []token
+	defines []define
+}
+
+func scan(src string) ([]token, []define, error) {
+	s := scanner{src: src, line: 1}
+	if err := s.scan(true); err != nil {
+		return nil, nil, err
+	}
+	return s.tokens, s.defines, nil
+}
+
+func (s *scanner) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", s.line, fmt.Sprintf(format, args...))
+}
+
+func (s *scanner) scan(directives bool) error {
+	bol := true // at the beginning of a line.
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\n':
+			s.pos++
+			s.line++
+			bol = true
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			s.pos++
+		case c == '\\' && strings.HasPrefix(s.src[s.pos:], "\\\n"):
+			s.pos += 2
+			s.line++
+		case strings.HasPrefix(s.src[s.pos:], "//"):
+			end := strings.IndexByte(s.src[s.pos:], '\n')
+			if end < 0 {
+				end = len(s.src) - s.pos
+			}
+			s.comment(s.src[s.pos+2 : s.pos+end])
+			s.pos += end
+		case strings.HasPrefix(s.src[s.pos:], "/*"):
+			end := strings.Index(s.src[s.pos+2:], "*/")
+			if end < 0 {
+				return s.errorf("unterminated comment")
+			}
+			comment := s.src[s.pos+2 : s.pos+2+end]
+			s.comment(comment)
+			s.line += strings.Count(comment, "\n")
+			s.pos += end + 4
+		case c == '#' && bol && directives:
+			if err := s.directive(); err != nil {
+				return err
+			}
+		default:
+			bol = false
+			if err := s.token(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// comment records the text of a comment, as documentation for the next
+// token, or for the previous one when the comment trails it on the same
+// line, or is a trailing documentation comment, such as /**< or ///<
+func (s *scanner) comment(text string) {
+	if len(s.tokens) > 0 {
+		last := &s.tokens[len(s.tokens)-1]
+		trailing := len(text) > 1 && strings.ContainsRune("*/!", rune(text[0])) && text[1] == '<'
+		if trailing || last.line == s.line {
+			if trailing {
+				text = text[2:]
+			}
+			last.post = strings.TrimSpace(last.post + "\n" + text)
+			return
+		}
+	}
+	// documentation is only kept when it is directly above a token.
+	if s.line > s.docEnd+1 {
+		s.doc = nil
+	}
+	s.doc = append(s.doc, text)
+	s.docEnd = s.line + strings.Count(text, "\n")
+}
+
+// documentation returns the comments directly above the current line.
+func (s *scanner) documentation() string {
+	doc := strings.Join(s.doc, "\n")
+	if s.line > s.docEnd+1 {
+		doc = ""
+	}
+	s.doc = nil
+	return doc
+}
+
+// directive scans a preprocessor directive, up to the end of the line.
+func (s *scanner) directive() error {
+	start, line := s.pos+1, s.line
+	for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+		switch {
+		case strings.HasPrefix(s.src[s.pos:], "\\\n"):
+			s.pos += 2
+			s.line++
+		case strings.HasPrefix(s.src[s.pos:], "/*"):
+			end := strings.Index(s.src[s.pos:], "*/")
+			if end < 0 {
+				return s.errorf("unterminated comment")
+			}
+			s.line += strings.Count(s.src[s.pos:s.pos+end], "\n")
+			s.pos += end + 2
+		default:
+			s.pos++
+		}
+	}
+	doc := s.documentation()
+	text := s.src[start:s.pos]
+	sub := scanner{src: text, line: line}
+	if err := sub.scan(false); err != nil {
+		return err
+	}
+	tokens := sub.tokens
+	if len(tokens) < 2 || tokens[0].text != "define" || tokens[1].kind != 'i' {
+		return nil
+	}
+	// function-like macros have their parameter list immediately
+	// after the name.
+	name := tokens[1].text
+	after := strings.Index(text, name) + len(name)
+	if after < len(text) && text[after] == '(' {
+		return nil
+	}
+	if last := tokens[len(tokens)-1]; doc == "" {
+		doc = last.post
+	}
+	s.defines = append(s.defines, define{name: name, value: tokens[2:], doc: doc})
+	return nil
+}
+
+func (s *scanner) emit(kind byte, end int) {
+	s.tokens = append(s.tokens, token{
+		kind: kind,
+		text: s.src[s.pos:end],
+		doc:  s.documentation(),
+		line: s.line,
+	})
+	s.pos = end
+}
+
+func (s *scanner) token() error {
+	c := s.src[s.pos]
+	end := s.pos + 1
+	switch {
+	case isIdent(c):
+		for end < len(s.src) && (isIdent(s.src[end]) || isDigit(s.src[end])) {
+			end++
+		}
+		s.emit('i', end)
+	case isDigit(c) || c == '.' && s.pos+1 < len(s.src) && isDigit(s.src[s.pos+1]):
+		for end < len(s.src) {
+			d := s.src[end]
+			exponent := (d == '+' || d == '-') && strings.ContainsRune("eEpP", rune(s.src[end-1])) &&
+				!strings.HasPrefix(s.src[s.pos:], "0x") && !strings.HasPrefix(s.src[s.pos:], "0X")
+			if !isIdent(d) && !isDigit(d) && d != '.' && !exponent {
+				break
+			}
+			end++
+		}
+		s.emit('n', end)
+	case c == '"' || c == '\'':
+		for end < len(s.src) && s.src[end] != c {
+			if s.src[end] == '\\' {
+				end++
+			}
+			if end < len(s.src) && s.src[end] == '\n' {
+				return s.errorf("unterminated literal")
+			}
+			end++
+		}
+		if end >= len(s.src) {
+			return s.errorf("unterminated literal")
+		}
+		kind := byte('s')
+		if c == '\'' {
+			kind = 'c'
+		}
+		s.emit(kind, end+1)
+	default:
+		for _, punct := range punctuation {
+			if strings.HasPrefix(s.src[s.pos:], punct) {
+				end = s.pos + len(punct)
+				break
+			}
+		}
+		s.emit('p', end)
+	}
+	return nil
+}
+
+func isIdent(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"runtime.link/std"
+)
+
+// source formats the std tags within the Go source of the named file. The
+// std tags of func fields that are invalid are left as they are, and
+// reported to warn.
+func source(filename string, src []byte, warn func(error)) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	ast.Inspect(file, func(node ast.Node) bool {
+		field, ok := node.(*ast.Field)
+		if !ok || field.Tag == nil {
+			return true
+		}
+		_, strict := field.Type.(*ast.FuncType)
+		text, err := tag(field.Tag.Value, strict)
+		if err != nil {
+			warn(fmt.Errorf("%s: %w", fset.Position(field.Tag.Pos()), err))
+		}
+		if text != field.Tag.Value {
+			edits = append(edits, edit{
+				start: fset.Position(field.Tag.Pos()).Offset,
+				end:   fset.Position(field.Tag.End()).Offset,
+				text:  text,
+			})
+		}
+		return true
+	})
+	if len(edits) == 0 {
+		return src, nil
+	}
+	var b bytes.Buffer
+	last := 0
+	for _, edit := range edits {
+		b.Write(src[last:edit.start])
+		b.WriteString(edit.text)
+		last = edit.end
+	}
+	b.Write(src[last:])
+	// tags may change in length, so the alignment of trailing comments
+	// needs to be updated.
+	return format.Source(b.Bytes())
+}
+
+// tag returns the struct tag literal with its std tag in canonical form.
+// An error is only returned for invalid std tags of func fields, others
+// (such as those of a lib.Location) are left as they are, unless they can
+// be parsed.
+func tag(literal string, strict bool) (string, error) {
+	value, err := strconv.Unquote(literal)
+	if err != nil {
+		return literal, err
+	}
+	var (
+		b       strings.Builder
+		changed bool
+	)
+	for value != "" {
+		// same conventions as reflect.StructTag.Lookup
+		i := 0
+		for i < len(value) && value[i] == ' ' {
+			i++
+		}
+		b.WriteString(value[:i])
+		value = value[i:]
+		i = 0
+		for i < len(value) && value[i] > ' ' && value[i] != ':' && value[i] != '"' && value[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(value) || value[i] != ':' || value[i+1] != '"' {
+			b.WriteString(value)
+			break
+		}
+		key := value[:i]
+		value = value[i+1:]
+		i = 1
+		for i < len(value) && value[i] != '"' {
+			if value[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(value) {
+			b.WriteString(key + ":" + value)
+			break
+		}
+		quoted := value[:i+1]
+		value = value[i+1:]
+		b.WriteString(key + ":")
+		val, err := strconv.Unquote(quoted)
+		if key != "std" || err != nil {
+			b.WriteString(quoted)
+			continue
+		}
+		if strict && accessor(val) {
+			b.WriteString(quoted)
+			continue
+		}
+		canonical, err := std.Tag(val).Canonical()
+		if err != nil {
+			if strict {
+				return literal, err
+			}
+			b.WriteString(quoted)
+			continue
+		}
+		if string(canonical) != val {
+			changed = true
+		}
+		b.WriteString(strconv.Quote(string(canonical)))
+	}
+	if !changed {
+		return literal, nil
+	}
+	if literal[0] == '`' && !strings.Contains(b.String(), "`") {
+		return "`" + b.String() + "`", nil
+	}
+	return strconv.Quote(b.String()), nil
+}
+
+// accessor reports whether the std tag names a struct field, as those of
+// std.Getters and std.Setters do, rather than a C function.
+func accessor(tag string) bool {
+	sym, _, _ := strings.Cut(strings.TrimSpace(tag), " ")
+	return strings.Contains(sym, ".")
+}
@@ -0,0 +1,125 @@
+// Command stdfmt formats the std tags of Go struct fields, so that each
+// one is written in its canonical form ([std.Tag.Canonical]), much like
+// gofmt does for Go source.
+//
+// Usage:
+//
+//	stdfmt [flags] [path ...]
+//
+// Without an explicit path, it processes the current directory. Given a
+// file, it operates on that file; given a directory, it operates on all
+// .go files in that directory, recursively (files and directories
+// starting with a period or underscore, along with testdata directories,
+// are ignored). By default, stdfmt prints the formatted sources to
+// standard output.
+//
+// The std tags of func fields should be valid, stdfmt warns about any that
+// are not and leaves them as they are. Other std tags, such as those of a
+// lib.Location, are only formatted if they can be parsed.
+//
+// The flags are:
+//
+//	-l
+//		list files whose formatting differs from stdfmt's, instead of
+//		printing them.
+//	-w
+//		write the result to the (source) file, instead of standard output.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		list  = flag.Bool("l", false, "list files whose formatting differs from stdfmt's")
+		write = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: stdfmt [flags] [path ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	failed := false
+	process := func(path string) {
+		if err := file(path, *list, *write); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		if !info.IsDir() {
+			process(path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			name := entry.Name()
+			ignored := path != "." && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_"))
+			if entry.IsDir() {
+				if ignored || name == "testdata" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !ignored && strings.HasSuffix(name, ".go") {
+				process(path)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// file formats the std tags of the Go file at path.
+func file(path string, list, write bool) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	res, err := source(path, src, func(err error) {
+		fmt.Fprintln(os.Stderr, "warning:", err)
+	})
+	if err != nil {
+		return err
+	}
+	changed := !bytes.Equal(src, res)
+	if list && changed {
+		fmt.Println(path)
+	}
+	if write && changed {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, res, info.Mode().Perm())
+	}
+	if !list && !write {
+		_, err = os.Stdout.Write(res)
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const input = "package libc\n\n" +
+	"type Library struct {\n" +
+	"\tlinux lib.Location `std:\"libc.so.6 libm.so.6\"`\n\n" +
+	"\tPuts  func(string) int32                         `std:\"puts func(&char)int\"` // write a line.\n" +
+	"\tRead  func([]byte, uintptr, uintptr, File) uintptr `std:\"fread func(&void[=@3], size_t*=@1, size_t, &FILE)size_t=@3;ferror(@4)\" json:\"-\"` // read.\n" +
+	"\tSqrt  func(float64) float64 \"std:\\\"sqrt func(double)double;errno\\\"\"\n" +
+	"}\n\n" +
+	"type Div struct {\n" +
+	"\tQuotient int32 `std:\"quot int\"`\n" +
+	"}\n\n" +
+	"var divs = std.Getters[struct {\n" +
+	"\tQuotient func(Div) int32 `std:\"div_t.quot\"`\n" +
+	"}]()\n"
+
+const output = "package libc\n\n" +
+	"type Library struct {\n" +
+	"\tlinux lib.Location `std:\"libc.so.6 libm.so.6\"`\n\n" +
+	"\tPuts func(string) int32                           `std:\"puts func(&char)int\"`                                                          // write a line.\n" +
+	"\tRead func([]byte, uintptr, uintptr, File) uintptr `std:\"fread func(&void[=@3],size_t*=@1,size_t,&FILE)size_t=@3; ferror(@4)\" json:\"-\"` // read.\n" +
+	"\tSqrt func(float64) float64                        \"std:\\\"sqrt func(double)double; errno\\\"\"\n" +
+	"}\n\n" +
+	"type Div struct {\n" +
+	"\tQuotient int32 `std:\"quot int\"`\n" +
+	"}\n\n" +
+	"var divs = std.Getters[struct {\n" +
+	"\tQuotient func(Div) int32 `std:\"div_t.quot\"`\n" +
+	"}]()\n"
+
+func TestSource(t *testing.T) {
+	res, err := source("libc.go", []byte(input), func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != output {
+		t.Fatalf("expected\n%s\ngot\n%s", output, res)
+	}
+	again, err := source("libc.go", res, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(res) {
+		t.Fatal("expected formatting to be idempotent")
+	}
+}
+
+func TestSourceInvalid(t *testing.T) {
+	const src = "package libc\n\ntype Library struct {\n\tPuts func(string) int32 `std:\"puts func(&char\"`\n\tSqrt func(float64) float64 `std:\"sqrt func(double) double\"`\n}\n"
+	var warnings []error
+	res, err := source("libc.go", []byte(src), func(err error) { warnings = append(warnings, err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0].Error(), "libc.go:4:26: ") {
+		t.Fatal("expected a warning for the tag of Puts, got", warnings)
+	}
+	if !strings.Contains(string(res), "`std:\"puts func(&char\"`") || !strings.Contains(string(res), "`std:\"sqrt func(double)double\"`") {
+		t.Fatal("expected the tag of Puts to be left as is, and the tag of Sqrt to be formatted, got", string(res))
+	}
+}
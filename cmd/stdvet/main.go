@@ -0,0 +1,15 @@
+// Command stdvet checks the std tags of library structs, see
+// [runtime.link/std/stdtag] for the checks that it makes.
+//
+// It can be run on its own, or by go vet:
+//
+//	go vet -vettool=$(which stdvet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"runtime.link/std/stdtag"
+)
+
+func main() { singlechecker.Main(stdtag.Analyzer) }
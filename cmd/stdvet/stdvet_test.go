@@ -0,0 +1,16 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestStandalone(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs stdvet")
+	}
+	out, err := exec.Command("go", "run", ".", "runtime.link/cmd/stdfmt").CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected stdvet to run on its own: %v\n%s", err, out)
+	}
+}
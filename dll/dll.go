@@ -2,12 +2,12 @@
 package dll
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"unsafe"
 
 	"runtime.link/cgo"
@@ -34,25 +34,195 @@ import (
 // to be memory safe.
 func Import[Library any](names ...string) Library {
 	var lib Library
+	if err := load(&lib, names, false, func(err error) { log.Println(err) }); err != nil {
+		panic(err)
+	}
+	return lib
+}
+
+// Load is like [Import], except that instead of logging failures, it
+// returns a [LoadError] that lists every library that could not be
+// found, every missing symbol (as a [cgo.MissingSymbolError]) and
+// every incompatible tag (as a [cgo.TagCompatiblityError]). Functions
+// that could be linked are available, even when an error is returned,
+// such that the caller can degrade gracefully.
+func Load[Library any](names ...string) (Library, error) {
+	var lib Library
+	var errs LoadError
+	if err := load(&lib, names, false, func(err error) { errs = append(errs, err) }); err != nil {
+		return lib, err
+	}
+	if len(errs) > 0 {
+		return lib, errs
+	}
+	return lib, nil
+}
+
+// ImportLazy is like [Import], except that each function is linked
+// to its symbol on its first call, rather than upfront. This reduces
+// the startup cost of importing large libraries, of which only a few
+// functions are used. The libraries themselves are still opened by
+// ImportLazy. A function that cannot be linked returns the error
+// through its error result, or else panics, when it is called.
+func ImportLazy[Library any](names ...string) Library {
+	var lib Library
+	if err := load(&lib, names, true, func(err error) { log.Println(err) }); err != nil {
+		panic(err)
+	}
+	return lib
+}
+
+// Available reports whether the function pointed to by fn has been
+// linked, such that optional symbols (see [std.Tag.Optional]) can be
+// detected at runtime before calling them. Functions imported with
+// [ImportLazy] are linked by Available, if they haven't been already.
+//
+//	if dll.Available(&lib.GetDefaultAudioInfo) {
+//		lib.GetDefaultAudioInfo(...)
+//	}
+func Available(fn any) bool {
+	rvalue := reflect.ValueOf(fn)
+	if rvalue.Kind() != reflect.Pointer || rvalue.Type().Elem().Kind() != reflect.Func {
+		panic("dll.Available: fn must be a pointer to a func")
+	}
+	if rvalue.Elem().IsNil() {
+		return false
+	}
+	value, ok := bindings.Load(closure(fn))
+	if !ok {
+		return true
+	}
+	b := value.(*binding)
+	if b.unloaded.Load() {
+		return false
+	}
+	if b.link != nil {
+		impl, err := b.link()
+		return err == nil && !impl.IsNil()
+	}
+	return true
+}
+
+// stub sets the function pointed to by ptr to a function that calls
+// link on its first call, in order to implement it.
+func stub(ptr any, b *binding, link func(fn any) error) {
+	rvalue := reflect.ValueOf(ptr).Elem()
+	rtype := rvalue.Type()
+	b.link = sync.OnceValues(func() (reflect.Value, error) {
+		fn := reflect.New(rtype)
+		if err := link(fn.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+		return fn.Elem(), nil
+	})
+	rvalue.Set(reflect.MakeFunc(rtype, func(args []reflect.Value) []reflect.Value {
+		var (
+			fn  reflect.Value
+			err error = cgo.ErrUnlinked
+		)
+		if !b.unloaded.Load() {
+			fn, err = b.link()
+		}
+		if err == nil && fn.IsNil() {
+			err = ErrUnavailable
+		}
+		if err != nil {
+			n := rtype.NumOut()
+			if n == 0 || rtype.Out(n-1) != reflect.TypeOf([0]error{}).Elem() {
+				panic(err)
+			}
+			results := make([]reflect.Value, n)
+			for i := range results {
+				results[i] = reflect.Zero(rtype.Out(i))
+			}
+			results[n-1] = reflect.ValueOf(&err).Elem()
+			return results
+		}
+		if rtype.IsVariadic() {
+			return fn.CallSlice(args)
+		}
+		return fn.Call(args)
+	}))
+	bindings.Store(closure(ptr), b)
+}
+
+const (
+	ErrUnavailable errorString = "function is not available"                             // returned by optional functions imported with [ImportLazy] that are not available.
+	ErrNamespaces  errorString = "library namespaces are not supported on this platform" // returned by [Open] for a [Namespace] that is not supported.
+)
+
+type errorString string
+
+func (e errorString) Error() string { return string(e) }
+
+// MissingLibraryError is returned when a library
+// couldn't be found with the given name.
+type MissingLibraryError string
+
+func (e MissingLibraryError) Error() string { return "library " + string(e) + " not found" }
+
+// LoadError lists every failure encountered by [Load], each
+// of which can be matched with [errors.As].
+type LoadError []error
+
+func (e LoadError) Error() string {
+	var b strings.Builder
+	for i, err := range e {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (e LoadError) Unwrap() []error { return e }
+
+// load links lib to the first of the given library names that can
+// be found, or else to the location tagged for the current platform.
+// Failures are passed to report, an error is only returned if the
+// library is not available on the current platform.
+func load(lib any, names []string, lazy bool, report func(error)) error {
+	var missing []error
 	for _, name := range names {
-		if err := set(&lib, name); err == nil {
-			return lib
+		var errs []error
+		if set(lib, name, 0, lazy, func(err error) { errs = append(errs, err) }) {
+			for _, err := range errs {
+				report(err)
+			}
+			return nil
 		}
+		missing = append(missing, errs...)
 	}
-	location := reflect.TypeOf(&lib).Elem()
+	location := reflect.TypeOf(lib).Elem()
 	found, ok := location.FieldByName(runtime.GOOS)
-	if !ok {
+	if !ok && location.NumField() > 0 && location.Field(0).Type.Kind() == reflect.Struct {
 		found, ok = location.Field(0).Type.FieldByName(runtime.GOOS)
 	}
 	if !ok && len(names) == 0 {
-		panic(fmt.Sprintf("library for %T not available on %s", lib, runtime.GOOS))
+		return fmt.Errorf("library for %s not available on %s", location, runtime.GOOS)
 	}
 	if ok {
-		if err := set(&lib, string(found.Tag.Get("std"))); err != nil {
-			log.Println(err)
+		var mode Flags
+		if tag := found.Tag.Get("dll"); tag != "" {
+			for _, name := range strings.Split(tag, ",") {
+				flag, ok := flags[name]
+				if !ok {
+					return fmt.Errorf("unknown flag %q in dll tag of %s", name, location)
+				}
+				mode |= flag
+			}
+		}
+		var errs []error
+		if set(lib, found.Tag.Get("std"), mode, lazy, func(err error) { errs = append(errs, err) }) {
+			missing = nil
 		}
+		missing = append(missing, errs...)
 	}
-	return lib
+	for _, err := range missing {
+		report(err)
+	}
+	return nil
 }
 
 /*func sigRune(t reflect.Type) rune {
@@ -221,22 +391,66 @@ func newCallback(signature dyncall.Signature, function reflect.Value) dyncall.Ca
 	}
 }*/
 
-func set(library any, tag string) error {
+// set links the functions of library to the libraries listed in the
+// tag, reporting any functions that could not be linked. If none of
+// the libraries can be found, they are reported and set returns false.
+// When lazy, each function is linked on its first call instead. The
+// libraries are opened with the given flags, if they are isolated,
+// they are all loaded into the same new namespace.
+func set(library any, tag string, mode Flags, lazy bool, report func(error)) bool {
 	var (
-		libs []unsafe.Pointer
+		handles []*Handle
+		missing []error
 	)
 	for _, location := range strings.Split(tag, ",") {
 		for _, name := range strings.Split(location, " ") {
-			lib := dlopen(name)
-			if lib == nil {
+			options := []Option{mode}
+			if mode&Isolated != 0 && len(handles) > 0 {
+				options = []Option{mode &^ Isolated, handles[0].Namespace()}
+			}
+			handle, err := Open(name, options...)
+			if err != nil {
+				missing = append(missing, err)
 				continue
 			}
-			libs = append(libs, lib)
+			handles = append(handles, handle)
 		}
 	}
-	if len(libs) == 0 {
-		return errors.New(tag + " not found")
+	if len(handles) == 0 {
+		for _, err := range missing {
+			report(err)
+		}
+		return false
 	}
+	linker := cgo.Linker(func(name string) unsafe.Pointer {
+		for _, handle := range handles {
+			if ptr := handle.Symbol(name); ptr != nil {
+				return ptr
+			}
+		}
+		return nil
+	})
+	each(library, func(ptr any, field reflect.StructField) {
+		tag := std.Tag(field.Tag.Get("std"))
+		b := &binding{handles: handles}
+		if lazy {
+			stub(ptr, b, func(fn any) error { return linker.MakeFunc(fn, tag) })
+			return
+		}
+		if err := linker.MakeFunc(ptr, tag); err != nil {
+			report(err)
+			return
+		}
+		if fn := closure(ptr); fn != nil {
+			bindings.Store(fn, b)
+		}
+	})
+	return true
+}
+
+// each calls fn with a pointer to each func field of the struct pointed
+// to by library, including the func fields of nested exported structs.
+func each(library any, fn func(ptr any, field reflect.StructField)) {
 	var (
 		rtype  = reflect.TypeOf(library).Elem()
 		rvalue = reflect.ValueOf(library).Elem()
@@ -245,30 +459,15 @@ func set(library any, tag string) error {
 		field := rtype.Field(i)
 		value := rvalue.Field(i)
 		if field.IsExported() && field.Type.Kind() == reflect.Struct {
-			if err := set(value.Addr().Interface(), tag); err != nil {
-				return err
-			}
+			each(value.Addr().Interface(), fn)
 		}
 		if field.Type.Kind() != reflect.Func {
 			continue
 		}
-		var ptr any
 		if field.IsExported() {
-			ptr = value.Addr().Interface()
+			fn(value.Addr().Interface(), field)
 		} else {
-			ptr = reflect.NewAt(field.Type, unsafe.Add(rvalue.Addr().UnsafePointer(), field.Offset)).Interface()
-		}
-
-		if err := cgo.Linker(func(name string) unsafe.Pointer {
-			for _, lib := range libs {
-				if ptr := dlsym(lib, name); ptr != nil {
-					return ptr
-				}
-			}
-			return nil
-		}).MakeFunc(ptr, std.Tag(field.Tag.Get("std"))); err != nil {
-			log.Println(err)
+			fn(reflect.NewAt(field.Type, unsafe.Add(rvalue.Addr().UnsafePointer(), field.Offset)).Interface(), field)
 		}
 	}
-	return nil
 }
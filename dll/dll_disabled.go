@@ -0,0 +1,22 @@
+//go:build !cgo && !(linux && amd64)
+
+package dll
+
+import "unsafe"
+
+// namespaces are not supported when cgo is disabled.
+const namespaces = false
+
+func dlopen(filename string, flags Flags, ns int64) (handle unsafe.Pointer) { return nil }
+
+func dlnamespace(handle unsafe.Pointer) int64 { return 0 }
+
+func dlerror() string { return "cgo is disabled" }
+
+func dlsym(handle unsafe.Pointer, symbol string) unsafe.Pointer { return nil }
+
+func dlvsym(handle unsafe.Pointer, symbol, version string) unsafe.Pointer { return nil }
+
+func dlclose(handle unsafe.Pointer) int32 { return 0 }
+
+func dlpath(handle unsafe.Pointer) string { return "" }
@@ -1,26 +1,99 @@
+//go:build cgo
+
 package dll
 
 /*
+#define _GNU_SOURCE
 #include <dlfcn.h>
 #include <stdlib.h>
+
+#if defined(__APPLE__)
+#include <mach-o/dyld.h>
+
+static const char *dlpath(void *handle) {
+	for (uint32_t i = 0; i < _dyld_image_count(); i++) {
+		const char *name = _dyld_get_image_name(i);
+		void *image = dlopen(name, RTLD_NOLOAD);
+		if (image == NULL) {
+			continue;
+		}
+		dlclose(image);
+		if (image == handle) {
+			return name;
+		}
+	}
+	return NULL;
+}
+#else
+#include <link.h>
+
+static const char *dlpath(void *handle) {
+	struct link_map *map = NULL;
+	if (dlinfo(handle, RTLD_DI_LINKMAP, &map) != 0 || map == NULL) {
+		return NULL;
+	}
+	return map->l_name;
+}
+#endif
+
+#if defined(__GLIBC__)
+enum { namespaces = 1 };
+
+static void *dlopen_in(long ns, const char *file, int mode) {
+	return dlmopen((Lmid_t)ns, file, mode);
+}
+
+static long dlnamespace(void *handle) {
+	Lmid_t ns = 0;
+	dlinfo(handle, RTLD_DI_LMID, &ns);
+	return ns;
+}
+
+static void *dlsym_version(void *handle, const char *symbol, const char *version) {
+	return dlvsym(handle, symbol, version);
+}
+#else
+enum { namespaces = 0 };
+
+static void *dlopen_in(long ns, const char *file, int mode) { return NULL; }
+
+static long dlnamespace(void *handle) { return 0; }
+
+static void *dlsym_version(void *handle, const char *symbol, const char *version) { return NULL; }
+#endif
 */
 import "C"
 import (
 	"unsafe"
 )
 
-func Open(filename string) (handle unsafe.Pointer) {
-	return dlopen(filename)
-}
-
-func Sym(handle unsafe.Pointer, symbol string) unsafe.Pointer {
-	return dlsym(handle, symbol)
-}
+// namespaces reports whether [Namespace]s are supported.
+const namespaces = C.namespaces != 0
 
-func dlopen(filename string) (handle unsafe.Pointer) {
+func dlopen(filename string, flags Flags, ns int64) (handle unsafe.Pointer) {
 	s := C.CString(filename + "\x00")
 	defer C.free(unsafe.Pointer(s))
-	return C.dlopen(s, C.RTLD_NOW)
+	var mode C.int = C.RTLD_NOW | C.RTLD_LOCAL
+	if flags&Lazy != 0 {
+		mode = mode&^C.RTLD_NOW | C.RTLD_LAZY
+	}
+	if flags&Global != 0 {
+		mode = mode&^C.RTLD_LOCAL | C.RTLD_GLOBAL
+	}
+	if flags&NoDelete != 0 {
+		mode |= C.RTLD_NODELETE
+	}
+	if flags&NoLoad != 0 {
+		mode |= C.RTLD_NOLOAD
+	}
+	if ns != 0 {
+		return C.dlopen_in(C.long(ns), s, mode)
+	}
+	return C.dlopen(s, mode)
+}
+
+func dlnamespace(handle unsafe.Pointer) int64 {
+	return int64(C.dlnamespace(handle))
 }
 
 func dlerror() string {
@@ -32,3 +105,19 @@ func dlsym(handle unsafe.Pointer, symbol string) unsafe.Pointer {
 	defer C.free(unsafe.Pointer(s))
 	return C.dlsym(handle, s)
 }
+
+func dlvsym(handle unsafe.Pointer, symbol, version string) unsafe.Pointer {
+	s := C.CString(symbol + "\x00")
+	defer C.free(unsafe.Pointer(s))
+	v := C.CString(version + "\x00")
+	defer C.free(unsafe.Pointer(v))
+	return C.dlsym_version(handle, s, v)
+}
+
+func dlclose(handle unsafe.Pointer) int32 {
+	return int32(C.dlclose(handle))
+}
+
+func dlpath(handle unsafe.Pointer) string {
+	return C.GoString(C.dlpath(handle))
+}
@@ -0,0 +1,150 @@
+//go:build !cgo
+
+package dll
+
+import (
+	"unsafe"
+
+	"runtime.link/cgo"
+)
+
+//go:cgo_import_dynamic libdl_dlopen dlopen "libdl.so.2"
+//go:cgo_import_dynamic libdl_dlsym dlsym "libdl.so.2"
+//go:cgo_import_dynamic libdl_dlerror dlerror "libdl.so.2"
+//go:cgo_import_dynamic libdl_dlclose dlclose "libdl.so.2"
+//go:cgo_import_dynamic libdl_dlinfo dlinfo "libdl.so.2"
+//go:cgo_import_dynamic libdl_dlmopen dlmopen "libdl.so.2"
+//go:cgo_import_dynamic libdl_dlvsym dlvsym "libdl.so.2"
+//go:cgo_import_dynamic _ _ "libdl.so.2"
+
+const (
+	rtldLazy     = 0x1
+	rtldNow      = 0x2
+	rtldNoLoad   = 0x4
+	rtldGlobal   = 0x100
+	rtldNoDelete = 0x1000
+
+	rtldDiLmid        = 1
+	rtldDiLinkmap     = 2
+	linkMapNameOffset = 8 // of l_name in struct link_map.
+)
+
+// namespaces are supported by glibc.
+const namespaces = true
+
+// Implemented in dll_linux_amd64.s
+var (
+	dlopenABI0  uintptr
+	dlsymABI0   uintptr
+	dlerrorABI0 uintptr
+	dlcloseABI0 uintptr
+	dlinfoABI0  uintptr
+	dlmopenABI0 uintptr
+	dlvsymABI0  uintptr
+)
+
+var libdl struct {
+	dlopen  func(string, int32) unsafe.Pointer
+	dlmopen func(int64, string, int32) unsafe.Pointer
+	dlsym   func(unsafe.Pointer, string) unsafe.Pointer
+	dlvsym  func(unsafe.Pointer, string, string) unsafe.Pointer
+	dlerror func() string
+	dlclose func(unsafe.Pointer) int32
+	dlinfo  func(unsafe.Pointer, int32, unsafe.Pointer) int32
+}
+
+func init() {
+	link := cgo.Linker(func(sym string) unsafe.Pointer {
+		var addr *uintptr
+		switch sym {
+		case "dlopen":
+			addr = &dlopenABI0
+		case "dlsym":
+			addr = &dlsymABI0
+		case "dlerror":
+			addr = &dlerrorABI0
+		case "dlclose":
+			addr = &dlcloseABI0
+		case "dlinfo":
+			addr = &dlinfoABI0
+		case "dlmopen":
+			addr = &dlmopenABI0
+		case "dlvsym":
+			addr = &dlvsymABI0
+		default:
+			return nil
+		}
+		return *(*unsafe.Pointer)(unsafe.Pointer(addr))
+	})
+	for _, err := range []error{
+		link.MakeFunc(&libdl.dlopen, "dlopen func(&char,int)$void"),
+		link.MakeFunc(&libdl.dlmopen, "dlmopen func(long,&char,int)$void"),
+		link.MakeFunc(&libdl.dlsym, "dlsym func(&void,&char)void"),
+		link.MakeFunc(&libdl.dlvsym, "dlvsym func(&void,&char,&char)void"),
+		link.MakeFunc(&libdl.dlerror, "dlerror func()&char"),
+		link.MakeFunc(&libdl.dlclose, "dlclose func($void)int"),
+		link.MakeFunc(&libdl.dlinfo, "dlinfo func(&void,int,+void)int"),
+	} {
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func dlopen(filename string, flags Flags, ns int64) (handle unsafe.Pointer) {
+	var mode int32 = rtldNow
+	if flags&Lazy != 0 {
+		mode = rtldLazy
+	}
+	if flags&Global != 0 {
+		mode |= rtldGlobal
+	}
+	if flags&NoDelete != 0 {
+		mode |= rtldNoDelete
+	}
+	if flags&NoLoad != 0 {
+		mode |= rtldNoLoad
+	}
+	if ns != 0 {
+		return libdl.dlmopen(ns, filename, mode)
+	}
+	return libdl.dlopen(filename, mode)
+}
+
+func dlnamespace(handle unsafe.Pointer) int64 {
+	var ns int64
+	libdl.dlinfo(handle, rtldDiLmid, unsafe.Pointer(&ns))
+	return ns
+}
+
+func dlerror() string {
+	return libdl.dlerror()
+}
+
+func dlsym(handle unsafe.Pointer, symbol string) unsafe.Pointer {
+	return libdl.dlsym(handle, symbol)
+}
+
+func dlvsym(handle unsafe.Pointer, symbol, version string) unsafe.Pointer {
+	return libdl.dlvsym(handle, symbol, version)
+}
+
+func dlclose(handle unsafe.Pointer) int32 {
+	return libdl.dlclose(handle)
+}
+
+func dlpath(handle unsafe.Pointer) string {
+	var linkmap unsafe.Pointer
+	if libdl.dlinfo(handle, rtldDiLinkmap, unsafe.Pointer(&linkmap)) != 0 || linkmap == nil {
+		return ""
+	}
+	name := *(*unsafe.Pointer)(unsafe.Add(linkmap, linkMapNameOffset))
+	if name == nil {
+		return ""
+	}
+	var n int
+	for *(*byte)(unsafe.Add(name, n)) != 0 {
+		n++
+	}
+	return string(unsafe.Slice((*byte)(name), n))
+}
@@ -0,0 +1,39 @@
+//go:build !cgo
+
+#include "textflag.h"
+
+TEXT dlopen_trampoline<>(SB),NOSPLIT|NOFRAME,$0
+	JMP	libdl_dlopen(SB)
+
+TEXT dlsym_trampoline<>(SB),NOSPLIT|NOFRAME,$0
+	JMP	libdl_dlsym(SB)
+
+TEXT dlerror_trampoline<>(SB),NOSPLIT|NOFRAME,$0
+	JMP	libdl_dlerror(SB)
+
+TEXT dlclose_trampoline<>(SB),NOSPLIT|NOFRAME,$0
+	JMP	libdl_dlclose(SB)
+
+TEXT dlinfo_trampoline<>(SB),NOSPLIT|NOFRAME,$0
+	JMP	libdl_dlinfo(SB)
+
+TEXT dlmopen_trampoline<>(SB),NOSPLIT|NOFRAME,$0
+	JMP	libdl_dlmopen(SB)
+
+TEXT dlvsym_trampoline<>(SB),NOSPLIT|NOFRAME,$0
+	JMP	libdl_dlvsym(SB)
+
+DATA	·dlopenABI0(SB)/8, $dlopen_trampoline<>(SB)
+GLOBL	·dlopenABI0(SB), NOPTR|RODATA, $8
+DATA	·dlsymABI0(SB)/8, $dlsym_trampoline<>(SB)
+GLOBL	·dlsymABI0(SB), NOPTR|RODATA, $8
+DATA	·dlerrorABI0(SB)/8, $dlerror_trampoline<>(SB)
+GLOBL	·dlerrorABI0(SB), NOPTR|RODATA, $8
+DATA	·dlcloseABI0(SB)/8, $dlclose_trampoline<>(SB)
+GLOBL	·dlcloseABI0(SB), NOPTR|RODATA, $8
+DATA	·dlinfoABI0(SB)/8, $dlinfo_trampoline<>(SB)
+GLOBL	·dlinfoABI0(SB), NOPTR|RODATA, $8
+DATA	·dlmopenABI0(SB)/8, $dlmopen_trampoline<>(SB)
+GLOBL	·dlmopenABI0(SB), NOPTR|RODATA, $8
+DATA	·dlvsymABI0(SB)/8, $dlvsym_trampoline<>(SB)
+GLOBL	·dlvsymABI0(SB), NOPTR|RODATA, $8
@@ -1,11 +1,24 @@
 package dll_test
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
+	"unsafe"
 
+	"runtime.link/cgo"
 	"runtime.link/dll"
 	"runtime.link/lib"
+	"runtime.link/std"
 )
 
 var libc = dll.Import[struct {
@@ -15,6 +28,26 @@ var libc = dll.Import[struct {
 
 	puts func(string) error    `std:"puts func(&char)int<0"`
 	sqrt func(float64) float64 `std:"sqrt func(double)double"`
+
+	div   func(num, denom int32) divT           `std:"div func(int,int)div_t"`
+	ldiv  func(num, denom int64) (int64, int64) `std:"ldiv func(long,long)ldiv_t"`
+	cabs  func(complexT) float64                `std:"cabs func(complex)double"`
+	csqrt func(complexT) complexT               `std:"csqrt func(complex)complex"`
+
+	qsort      func(unsafe.Pointer, uintptr, uintptr, func(a, b unsafe.Pointer) int32) `std:"qsort func(&void,size_t,size_t,&func(&void,&void)int)void"`
+	qsortOwned func(unsafe.Pointer, uintptr, uintptr, func(a, b unsafe.Pointer) int32) `std:"qsort func(&void,size_t,size_t,$func)void"`
+
+	fopen  func(path, mode string) unsafe.Pointer `std:"fopen func(&char,&char)$FILE"`
+	fclose func(unsafe.Pointer) int32             `std:"fclose func(&FILE)int"`
+	fputc  func(int32, unsafe.Pointer) error      `std:"fputc func(int,&FILE)int=EOF; ferror(@2)"`
+
+	sqrtErrno   func(float64) (float64, error)                  `std:"sqrt func(double)double; errno"`
+	fopenErrno  func(path, mode string) (unsafe.Pointer, error) `std:"fopen func(&char,&char)$FILE=NULL; errno"`
+	fopenStrerr func(path, mode string) (unsafe.Pointer, error) `std:"fopen func(&char,&char)$FILE=NULL; strerror(errno)"`
+
+	abs      func(int32) (int32, error)                                    `std:"abs func(int)int<0"`
+	memcpy   func(dst, src []byte, n uintptr)                              `std:"memcpy func(&void~@2,&void[>=@3],size_t)void"`
+	snprintf func(buf []byte, n uintptr, format string, args ...any) int32 `std:"snprintf func(&char[>=@2],size_t,&char,void...?@3)int"`
 }]()
 
 func TestHelloWorld(*testing.T) {
@@ -23,3 +56,624 @@ func TestHelloWorld(*testing.T) {
 	fmt.Println(libc.sqrt == nil)
 	fmt.Println(libc.sqrt(2))
 }
+
+type divT struct {
+	Quotient  int32 `std:"quot int"`
+	Remainder int32 `std:"rem int"`
+}
+
+type complexT struct {
+	Real, Imag float64
+}
+
+func TestAggregates(t *testing.T) {
+	if div := libc.div(7, 2); div != (divT{3, 1}) {
+		t.Fatal("unexpected div result", div)
+	}
+	if quo, rem := libc.ldiv(-7, 2); quo != -3 || rem != -1 {
+		t.Fatal("unexpected ldiv result", quo, rem)
+	}
+	if abs := libc.cabs(complexT{3, 4}); abs != 5 {
+		t.Fatal("unexpected cabs result", abs)
+	}
+	if sqrt := libc.csqrt(complexT{-4, 0}); sqrt != (complexT{0, 2}) {
+		t.Fatal("unexpected csqrt result", sqrt)
+	}
+}
+
+type valueT struct {
+	Value int32 `std:"value int"`
+}
+
+type bitfieldT struct {
+	Value int32  `std:"value int:31"`
+	Sign  uint32 `std:"sign uint:1"`
+}
+
+func TestAggregateBitfield(t *testing.T) {
+	// a struct with a single int is passed like the int.
+	byValue, err := dll.Load[struct {
+		linux   lib.Location `std:"libc.so.6"`
+		darwin  lib.Location `std:"libSystem.dylib"`
+		windows lib.Location `std:"msvcrt.dll"`
+
+		abs func(valueT) int32 `std:"int abs(value_t)"`
+	}]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := byValue.abs(valueT{-3}); n != 3 {
+		t.Fatal("unexpected abs result", n)
+	}
+	_, err = dll.Load[struct {
+		linux   lib.Location `std:"libc.so.6"`
+		darwin  lib.Location `std:"libSystem.dylib"`
+		windows lib.Location `std:"msvcrt.dll"`
+
+		abs func(bitfieldT) int32 `std:"int abs(bitfield_t)"`
+	}]()
+	var incompatible cgo.TagCompatiblityError
+	if !errors.As(err, &incompatible) {
+		t.Fatal("expected bitfields to be rejected", err)
+	}
+}
+
+func compareInt32(a, b unsafe.Pointer) int32 {
+	return *(*int32)(a) - *(*int32)(b)
+}
+
+func TestCallbacks(t *testing.T) {
+	if libc.qsort == nil {
+		t.Skip("callbacks require cgo")
+	}
+	values := []int32{3, 1, 2}
+	libc.qsort(unsafe.Pointer(&values[0]), uintptr(len(values)), 4, compareInt32)
+	if !slices.Equal(values, []int32{1, 2, 3}) {
+		t.Fatal("unexpected qsort result", values)
+	}
+	values = []int32{6, 5, 4}
+	libc.qsortOwned(unsafe.Pointer(&values[0]), uintptr(len(values)), 4, compareInt32)
+	cgo.FreeCallback(compareInt32)
+	if !slices.Equal(values, []int32{4, 5, 6}) {
+		t.Fatal("unexpected qsort result", values)
+	}
+}
+
+type texture std.Handle[texture]
+
+var textureData byte
+
+func TestCallbackHandle(t *testing.T) {
+	if libc.qsort == nil {
+		t.Skip("callbacks require cgo")
+	}
+	lib, err := dll.Load[struct {
+		linux lib.Location `std:"libc.so.6"`
+
+		// memcpy returns its first argument, which is the C function
+		// pointer of the callback.
+		memcpy func(func() texture, unsafe.Pointer, uintptr) unsafe.Pointer `std:"&void memcpy($func,&void,size_t)"`
+	}]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTexture := func() texture {
+		var tex texture
+		tex.SetPointer(unsafe.Pointer(&textureData))
+		return tex
+	}
+	fn := lib.memcpy(newTexture, nil, 0)
+	defer cgo.FreeCallback(newTexture)
+	var call func() unsafe.Pointer
+	if err := cgo.Linker(func(string) unsafe.Pointer { return fn }).MakeFunc(&call, "&void call(void)"); err != nil {
+		t.Fatal(err)
+	}
+	if ptr := call(); ptr != unsafe.Pointer(&textureData) {
+		t.Fatal("expected the callback to return the handle to C", ptr)
+	}
+}
+
+func TestSqrtAllocs(t *testing.T) {
+	if allocs := testing.AllocsPerRun(100, func() { libc.sqrt(2) }); allocs != 0 {
+		t.Fatalf("sqrt allocated %v times per call", allocs)
+	}
+}
+
+func BenchmarkSqrt(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		libc.sqrt(2)
+	}
+}
+
+func expectAssertion(t *testing.T, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		if _, ok := recover().(cgo.AssertionError); !ok {
+			t.Fatal("expected assertion to fail")
+		}
+	}()
+	fn()
+}
+
+func expectPanic(t *testing.T, expected error, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		if err, _ := recover().(error); err != expected {
+			t.Fatal("expected a panic with", expected, "got", err)
+		}
+	}()
+	fn()
+}
+
+func TestAssertions(t *testing.T) {
+	buf := make([]byte, 8)
+	if n := libc.snprintf(buf, uintptr(len(buf)), "%d %s", 42, "go"); n != 5 || string(buf[:n]) != "42 go" {
+		t.Fatal("unexpected snprintf result", n, string(buf))
+	}
+	expectAssertion(t, func() { libc.snprintf(buf, 32, "%d", 1) })
+	expectAssertion(t, func() { libc.snprintf(buf, 8, "%d %s", 1) })
+	expectAssertion(t, func() { libc.snprintf(buf, 8, "%s", 1) })
+	expectAssertion(t, func() { libc.snprintf(buf, 8, "%n", new(int32)) })
+
+	libc.memcpy(buf[4:], buf[:4], 4)
+	if string(buf[4:]) != "42 g" {
+		t.Fatal("unexpected memcpy result", string(buf))
+	}
+	expectAssertion(t, func() { libc.memcpy(buf[2:], buf[:4], 4) })
+	expectAssertion(t, func() { libc.memcpy(buf[4:], buf[:2], 4) })
+	expectAssertion(t, func() { libc.memcpy(buf[4:], buf[:4], 1<<63) })
+	expectAssertion(t, func() { libc.snprintf(buf, 1<<63, "%d", 1) })
+
+	if n, err := libc.abs(-3); n != 3 || err != nil {
+		t.Fatal("unexpected abs result", n, err)
+	}
+	if _, err := libc.abs(math.MinInt32); !errors.As(err, new(cgo.AssertionError)) {
+		t.Fatal("expected abs to fail", err)
+	}
+}
+
+func TestFailureHandler(t *testing.T) {
+	file := libc.fopen("/dev/null", "r")
+	if file == nil {
+		t.Skip("cannot open /dev/null")
+	}
+	defer libc.fclose(file)
+	err := libc.fputc('x', file)
+	if err == nil {
+		t.Fatal("expected fputc to fail on a read-only file")
+	}
+	if err.Error() != "fputc returned -1: ferror = 1" {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestFailureHandlerSold(t *testing.T) {
+	lib, err := dll.Load[struct {
+		linux lib.Location `std:"libc.so.6"`
+
+		fopen  func(path, mode string) file `std:"$FILE@fclose fopen(&char,&char)"`
+		fileno func(file) int32             `std:"int fileno(&FILE)"`
+		fputc  func(int32, file) error      `std:"int=EOF fputc(int,$FILE); ferror(@2)"`
+	}]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := lib.fopen("/dev/null", "r")
+	if f.Pointer() == 0 {
+		t.Skip("cannot open /dev/null")
+	}
+	fd := lib.fileno(f)
+	defer syscall.Close(int(fd))
+	err = lib.fputc('x', f)
+	if err == nil || err.Error() != "fputc returned -1: ferror = 1" {
+		t.Fatal("expected ferror to explain the failure of fputc", err)
+	}
+}
+
+func TestErrno(t *testing.T) {
+	if n, err := libc.sqrtErrno(4); n != 2 || err != nil {
+		t.Fatal("unexpected sqrt result", n, err)
+	}
+	if _, err := libc.sqrtErrno(-1); !errors.Is(err, std.ErrDomain) || !errors.Is(err, syscall.EDOM) {
+		t.Fatal("expected sqrt to fail with EDOM", err)
+	}
+	if _, err := libc.fopenErrno("/does/not/exist", "r"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("expected fopen to fail with ENOENT", err)
+	}
+	if _, err := libc.fopenStrerr("/does/not/exist", "r"); err == nil || err.Error() != "fopen returned 0: No such file or directory" {
+		t.Fatal("unexpected fopen error", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	lib, err := dll.Load[struct {
+		linux   lib.Location `std:"libc.so.6"`
+		darwin  lib.Location `std:"libSystem.dylib"`
+		windows lib.Location `std:"msvcrt.dll"`
+
+		abs     func(int32) int32 `std:"abs func(int)int"`
+		missing func()            `std:"runtime_link_missing func()void"`
+		wrong   func() int32      `std:"abs func(int)int"`
+	}]()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	var missing cgo.MissingSymbolError
+	if !errors.As(err, &missing) || missing != "runtime_link_missing" {
+		t.Fatal("expected a missing symbol error", err)
+	}
+	var incompatible cgo.TagCompatiblityError
+	if !errors.As(err, &incompatible) {
+		t.Fatal("expected a tag compatibility error", err)
+	}
+	if lib.abs(-3) != 3 {
+		t.Fatal("unexpected abs result")
+	}
+	_, err = dll.Load[struct {
+		puts func(string) error `std:"puts func(&char)int<0"`
+	}]("libruntime_link_missing.so")
+	var library dll.MissingLibraryError
+	if !errors.As(err, &library) || library != "libruntime_link_missing.so" {
+		t.Fatal("expected a missing library error", err)
+	}
+}
+
+func TestOptional(t *testing.T) {
+	lib, err := dll.Load[struct {
+		linux   lib.Location `std:"libc.so.6"`
+		darwin  lib.Location `std:"libSystem.dylib"`
+		windows lib.Location `std:"msvcrt.dll"`
+
+		abs     func(int32) int32 `std:"abs? func(int)int"`
+		missing func()            `std:"runtime_link_missing? func()void"`
+	}]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dll.Available(&lib.abs) || lib.abs(-3) != 3 {
+		t.Fatal("expected abs to be available")
+	}
+	if dll.Available(&lib.missing) {
+		t.Fatal("expected missing symbol to be unavailable")
+	}
+}
+
+func TestImportLazy(t *testing.T) {
+	lib := dll.ImportLazy[struct {
+		linux   lib.Location `std:"libc.so.6"`
+		darwin  lib.Location `std:"libSystem.dylib"`
+		windows lib.Location `std:"msvcrt.dll"`
+
+		abs      func(int32) int32 `std:"abs func(int)int"`
+		missing  func() error      `std:"runtime_link_missing func()void"`
+		optional func()            `std:"runtime_link_missing? func()void"`
+	}]()
+	if lib.abs(-3) != 3 {
+		t.Fatal("unexpected abs result")
+	}
+	var missing cgo.MissingSymbolError
+	if err := lib.missing(); !errors.As(err, &missing) {
+		t.Fatal("expected a missing symbol error", err)
+	}
+	if !dll.Available(&lib.abs) || dll.Available(&lib.missing) || dll.Available(&lib.optional) {
+		t.Fatal("unexpected availability")
+	}
+}
+
+func TestHandle(t *testing.T) {
+	libc, err := dll.Open("libc.so.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path := libc.Path(); !strings.Contains(path, "libc") {
+		t.Fatal("unexpected path", path)
+	}
+	if libc.Symbol("abs") == nil {
+		t.Fatal("expected abs symbol")
+	}
+	if err := libc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if libc.Symbol("abs") != nil {
+		t.Fatal("expected no symbols after close")
+	}
+	var missing dll.MissingLibraryError
+	if _, err := dll.Open("libruntime_link_missing.so"); !errors.As(err, &missing) {
+		t.Fatal("expected a missing library error", err)
+	}
+}
+
+func TestUnload(t *testing.T) {
+	type Library struct {
+		linux   lib.Location `std:"libc.so.6"`
+		darwin  lib.Location `std:"libSystem.dylib"`
+		windows lib.Location `std:"msvcrt.dll"`
+
+		abs      func(int32) int32          `std:"abs func(int)int"`
+		absError func(int32) (int32, error) `std:"abs func(int)int<0"`
+	}
+	libc := dll.Import[Library]()
+	lazy := dll.ImportLazy[Library]()
+	abs := libc.abs
+	if abs(-3) != 3 || lazy.abs(-3) != 3 {
+		t.Fatal("unexpected abs result")
+	}
+	if err := dll.Unload(&libc); err != nil {
+		t.Fatal(err)
+	}
+	if err := dll.Unload(&lazy); err != nil {
+		t.Fatal(err)
+	}
+	if dll.Available(&libc.abs) || dll.Available(&lazy.abs) {
+		t.Fatal("expected unloaded functions to be unavailable")
+	}
+	for _, fn := range []func(){
+		func() { abs(-3) },
+		func() { lazy.abs(-3) },
+	} {
+		func() {
+			defer func() {
+				if err, _ := recover().(error); !errors.Is(err, cgo.ErrUnlinked) {
+					t.Fatal("expected an unlinked panic", err)
+				}
+			}()
+			fn()
+		}()
+	}
+	if _, err := libc.absError(-3); !errors.Is(err, cgo.ErrUnlinked) {
+		t.Fatal("expected an unlinked error", err)
+	}
+	if _, err := lazy.absError(-3); !errors.Is(err, cgo.ErrUnlinked) {
+		t.Fatal("expected an unlinked error", err)
+	}
+}
+
+func TestOpenFlags(t *testing.T) {
+	if _, err := dll.Open("libruntime_link_missing.so", dll.NoLoad); err == nil {
+		t.Fatal("expected noload to fail for a library that isn't loaded")
+	}
+	libc, err := dll.Open("libc.so.6", dll.Lazy|dll.Global|dll.NoDelete|dll.NoLoad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer libc.Close()
+	if libc.Namespace() != (dll.Namespace{}) {
+		t.Fatal("expected libc to be in the default namespace")
+	}
+	isolated, err := dll.Open("libm.so.6", dll.Isolated)
+	if errors.Is(err, dll.ErrNamespaces) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer isolated.Close()
+	ns := isolated.Namespace()
+	if ns == (dll.Namespace{}) {
+		t.Fatal("expected an isolated namespace")
+	}
+	copied, err := dll.Open("libm.so.6", ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copied.Close()
+	if copied.Namespace() != ns || copied.Symbol("sqrt") != isolated.Symbol("sqrt") {
+		t.Fatal("expected libm to be shared within the namespace")
+	}
+	shared, err := dll.Open("libm.so.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shared.Close()
+	if shared.Symbol("sqrt") == isolated.Symbol("sqrt") {
+		t.Fatal("expected libm to be isolated from the default namespace")
+	}
+	libm, err := dll.Load[struct {
+		linux lib.Location `std:"libm.so.6" dll:"isolated,nodelete"`
+
+		sqrt func(float64) float64 `std:"sqrt func(double)double"`
+	}]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if libm.sqrt(4) != 2 {
+		t.Fatal("unexpected sqrt result")
+	}
+}
+
+func TestSearchPath(t *testing.T) {
+	libm, err := dll.Open("libm.so.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer libm.Close()
+	dir := t.TempDir()
+	if err := os.Symlink(libm.Path(), filepath.Join(dir, "libruntime_link_test.so")); err != nil {
+		t.Skip(err)
+	}
+	t.Setenv(dll.SearchPathEnv, dir)
+	found, err := dll.Open("libruntime_link_test.so")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer found.Close()
+	if found.Symbol("sqrt") == nil {
+		t.Fatal("expected sqrt symbol")
+	}
+	_, err = dll.Open("libruntime_link_missing.so")
+	var search dll.SearchError
+	if !errors.As(err, &search) || !strings.Contains(err.Error(), filepath.Join(dir, "libruntime_link_missing.so")+": ") {
+		t.Fatal("expected the search error to list the search path", err)
+	}
+}
+
+func TestSymbolVersions(t *testing.T) {
+	libc, err := dll.Open("libc.so.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer libc.Close()
+	if libc.Symbol("memcpy@GLIBC_2.14") == nil || libc.Symbol("memcpy@GLIBC_2.2.5") == nil {
+		t.Skip("memcpy is not versioned on this platform")
+	}
+	lib, err := dll.Load[struct {
+		linux lib.Location `std:"libc.so.6"`
+
+		memcpy  func(dst, src []byte, n uintptr) `std:"memcpy@GLIBC_2.14 func(&void~@2,&void[>=@3],size_t)void"`
+		missing func(dst, src []byte, n uintptr) `std:"memcpy@GLIBC_0.0 func(&void~@2,&void[>=@3],size_t)void"`
+	}]()
+	var missing cgo.MissingSymbolError
+	if !errors.As(err, &missing) || missing.Error() != "symbol memcpy (version GLIBC_0.0) not found" {
+		t.Fatal("expected a missing symbol version error", err)
+	}
+	buf := make([]byte, 4)
+	lib.memcpy(buf, []byte("abcd"), 4)
+	if string(buf) != "abcd" {
+		t.Fatal("unexpected memcpy result", buf)
+	}
+}
+
+type file std.Memory[file]
+
+func TestDestructor(t *testing.T) {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("open files cannot be listed")
+	}
+	lib, err := dll.Load[struct {
+		linux lib.Location `std:"libc.so.6"`
+
+		fopen  func(path, mode string) (file, error) `std:"$FILE@fclose=NULL fopen(&char,&char); errno"`
+		fclose func(file) int32                      `std:"int fclose($FILE)"`
+		fileno func(file) int32                      `std:"int fileno(&FILE)"`
+		malloc func(uintptr) std.Memory[byte]        `std:"$void@free malloc(size_t)"`
+	}]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func(fd int32) bool {
+		_, err := os.Stat(fmt.Sprintf("/proc/self/fd/%d", fd))
+		return err == nil
+	}
+	f, err := lib.fopen("/dev/null", "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := lib.fileno(f)
+	if !open(fd) {
+		t.Fatal("expected fopen to open a file")
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if open(fd) {
+		t.Fatal("expected Close to call fclose")
+	}
+	if err := f.Close(); err != std.ErrDoubleFree {
+		t.Fatal("expected fclose to be called once", err)
+	}
+	if _, err := lib.fopen("/does/not/exist", "r"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("expected fopen to fail with ENOENT", err)
+	}
+	f, err = lib.fopen("/dev/null", "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lib.fclose(f) != 0 || f.Close() != std.ErrDoubleFree {
+		t.Fatal("expected the file to be sold to fclose")
+	}
+	m := lib.malloc(16)
+	if m.Pointer() == 0 {
+		t.Fatal("expected malloc to allocate memory")
+	}
+	m.Free()
+	expectPanic(t, std.ErrDoubleFree, m.Free)
+	expectPanic(t, std.ErrUseAfterFree, func() { lib.fileno(f) })
+	for i := 0; i < 16; i++ {
+		if _, err := lib.fopen("/dev/null", "r"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		if now, _ := os.ReadDir("/proc/self/fd"); len(now) <= len(fds) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected files that were never closed to be closed by the finalizer")
+}
+
+func TestUnloadDestructor(t *testing.T) {
+	if _, err := os.ReadDir("/proc/self/fd"); err != nil {
+		t.Skip("open files cannot be listed")
+	}
+	lib, err := dll.Load[struct {
+		linux lib.Location `std:"libc.so.6"`
+
+		fopen  func(path, mode string) (file, error) `std:"$FILE@fclose=NULL fopen(&char,&char); errno"`
+		fileno func(file) int32                      `std:"int fileno(&FILE)"`
+	}]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := lib.fopen("/dev/null", "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := lib.fileno(f)
+	if err := dll.Unload(&lib); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(fmt.Sprintf("/proc/self/fd/%d", fd)); err != nil {
+		t.Fatal("expected fclose to be unlinked along with fopen", err)
+	}
+	syscall.Close(int(fd))
+}
+
+type tm struct {
+	Second, Minute, Hour, Day, Month, Year, Weekday, YearDay, DST int32
+
+	Offset int64
+	Zone   string
+}
+
+func TestMemoryResults(t *testing.T) {
+	lib, err := dll.Load[struct {
+		linux lib.Location `std:"libc.so.6"`
+
+		strdup func(string) std.Pointer[byte]  `std:"$char@free strdup(&char)"`
+		gmtime func(*int64) std.Struct[tm]     `std:"&tm gmtime(&#long)"`
+		strlen func(std.Pointer[byte]) uintptr `std:"size_t strlen(&char)"`
+	}]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := lib.strdup("hello")
+	if s.Get() != 'h' || lib.strlen(s) != 5 {
+		t.Fatal("unexpected strdup result", s.Get())
+	}
+	s.Free()
+	expectPanic(t, std.ErrUseAfterFree, func() { lib.strlen(s) })
+	var epoch int64
+	utc := lib.gmtime(&epoch).Get()
+	if utc.Year != 70 || utc.Day != 1 || utc.Zone != "GMT" {
+		t.Fatal("unexpected gmtime result", utc)
+	}
+	alloc, err := dll.Load[std.Allocator]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	str := std.NewStruct[struct{ Name string }](&alloc)
+	defer str.Free()
+	std.FieldOf[string](str, "Name").Set("hello")
+	if name := (*[6]byte)(*(*unsafe.Pointer)(str.UnsafePointer())); string(name[:]) != "hello\x00" {
+		t.Fatal("expected the name to be copied into C memory", name)
+	}
+}
@@ -0,0 +1,354 @@
+package elf
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"runtime.link/std"
+)
+
+// Prototypes of the functions defined by a shared object, keyed
+// by symbol, as described by its DWARF debug information.
+type Prototypes map[string]Prototype
+
+// Prototype of a C function.
+type Prototype struct {
+	Result   dwarf.Type // nil for void functions.
+	Params   []dwarf.Type
+	Variadic bool
+}
+
+// DebugDirectory is searched for separate debug-symbols files by
+// build-id, when a shared object has no debug information itself.
+var DebugDirectory = "/usr/lib/debug"
+
+// ReadPrototypes reads the prototypes of the functions from the debug
+// information of the ELF file at the given path, which can either be the
+// shared object or its separate debug-symbols file. If the shared object
+// has been stripped, its debug-symbols file is looked up by its build-id.
+func ReadPrototypes(path string) (Prototypes, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := f.DWARF()
+	if err != nil {
+		id := buildID(f)
+		if id == "" {
+			return nil, err
+		}
+		debug, err := elf.Open(filepath.Join(DebugDirectory, ".build-id", id[:2], id[2:]+".debug"))
+		if err != nil {
+			return nil, fmt.Errorf("%s has no debug information: %w", path, err)
+		}
+		defer debug.Close()
+		if data, err = debug.DWARF(); err != nil {
+			return nil, err
+		}
+	}
+	return readPrototypes(data)
+}
+
+// buildID returns the hex encoded GNU build-id of the file, if any.
+func buildID(f *elf.File) string {
+	section := f.Section(".note.gnu.build-id")
+	if section == nil {
+		return ""
+	}
+	note, err := section.Data()
+	if err != nil || len(note) < 16 {
+		return ""
+	}
+	namesz := f.ByteOrder.Uint32(note[0:])
+	descsz := f.ByteOrder.Uint32(note[4:])
+	start := 12 + (int(namesz)+3)&^3
+	if start+int(descsz) > len(note) || descsz < 2 {
+		return ""
+	}
+	return hex.EncodeToString(note[start : start+int(descsz)])
+}
+
+func readPrototypes(data *dwarf.Data) (Prototypes, error) {
+	protos := make(Prototypes)
+	r := data.Reader()
+	for {
+		entry, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return protos, nil
+		}
+		if entry.Tag != dwarf.TagSubprogram {
+			continue
+		}
+		// out-of-line copies of inlined functions refer to
+		// their abstract instance for the prototype.
+		for _, attr := range []dwarf.Attr{dwarf.AttrAbstractOrigin, dwarf.AttrSpecification} {
+			if offset, ok := entry.Val(attr).(dwarf.Offset); ok {
+				if origin, err := readEntry(data, offset); err == nil {
+					entry = origin
+				}
+				break
+			}
+		}
+		name, _ := entry.Val(dwarf.AttrName).(string)
+		external, _ := entry.Val(dwarf.AttrExternal).(bool)
+		if name == "" || !external {
+			r.SkipChildren()
+			continue
+		}
+		if _, ok := protos[name]; ok {
+			r.SkipChildren()
+			continue
+		}
+		proto, err := readPrototype(data, entry)
+		if err != nil {
+			return nil, err
+		}
+		protos[name] = proto
+		r.SkipChildren()
+	}
+}
+
+func readEntry(data *dwarf.Data, offset dwarf.Offset) (*dwarf.Entry, error) {
+	r := data.Reader()
+	r.Seek(offset)
+	return r.Next()
+}
+
+func readPrototype(data *dwarf.Data, entry *dwarf.Entry) (Prototype, error) {
+	var proto Prototype
+	if offset, ok := entry.Val(dwarf.AttrType).(dwarf.Offset); ok {
+		result, err := data.Type(offset)
+		if err != nil {
+			return proto, err
+		}
+		proto.Result = result
+	}
+	if !entry.Children {
+		return proto, nil
+	}
+	r := data.Reader()
+	r.Seek(entry.Offset)
+	if _, err := r.Next(); err != nil {
+		return proto, err
+	}
+	for {
+		child, err := r.Next()
+		if err != nil {
+			return proto, err
+		}
+		if child == nil || child.Tag == 0 {
+			return proto, nil
+		}
+		switch child.Tag {
+		case dwarf.TagFormalParameter:
+			offset, ok := child.Val(dwarf.AttrType).(dwarf.Offset)
+			if !ok {
+				return proto, errors.New("parameter without a type")
+			}
+			param, err := data.Type(offset)
+			if err != nil {
+				return proto, err
+			}
+			proto.Params = append(proto.Params, param)
+		case dwarf.TagUnspecifiedParameters:
+			proto.Variadic = true
+		}
+		if child.Children {
+			r.SkipChildren()
+		}
+	}
+}
+
+// Verify the [std.Tag] of each function of the library struct pointed to
+// by library (including those of nested exported structs) against these
+// prototypes, by checking that the number of parameters matches, and that
+// each parameter and the result are passed in the same way. Each error
+// names the field and explains the mismatch. Functions without a prototype
+// are reported, unless the tag is optional.
+func (protos Prototypes) Verify(library any) error {
+	rtype := reflect.TypeOf(library)
+	if rtype == nil || rtype.Kind() != reflect.Pointer || rtype.Elem().Kind() != reflect.Struct {
+		return errors.New("elf.Verify: library must be a pointer to a struct")
+	}
+	var errs []error
+	protos.verify(rtype.Elem(), "", &errs)
+	return errors.Join(errs...)
+}
+
+func (protos Prototypes) verify(rtype reflect.Type, prefix string, errs *[]error) {
+	for i := 0; i < rtype.NumField(); i++ {
+		field := rtype.Field(i)
+		if field.IsExported() && field.Type.Kind() == reflect.Struct {
+			protos.verify(field.Type, prefix+field.Name+".", errs)
+		}
+		if field.Type.Kind() != reflect.Func {
+			continue
+		}
+		tag := std.Tag(field.Tag.Get("std"))
+		if err := protos.check(tag); err != nil {
+			*errs = append(*errs, FieldError{prefix + field.Name, err})
+		}
+	}
+}
+
+// check the tag against the prototype of its first symbol that has one.
+func (protos Prototypes) check(tag std.Tag) error {
+	symbols, ctype, err := tag.Parse()
+	if err != nil {
+		return err
+	}
+	for _, symbol := range symbols {
+		name, _, _ := strings.Cut(symbol, "@")
+		proto, ok := protos[name]
+		if !ok {
+			continue
+		}
+		if ctype.Func == nil {
+			return fmt.Errorf("%s is a function", name)
+		}
+		var (
+			args     = ctype.Args
+			variadic = len(args) > 0 && args[len(args)-1].More
+		)
+		if variadic {
+			args = args[:len(args)-1]
+		}
+		if len(args) != len(proto.Params) {
+			return fmt.Errorf("tag has %d parameters, %s has %d", len(args), name, len(proto.Params))
+		}
+		if variadic != proto.Variadic {
+			return fmt.Errorf("tag variadic is %v, %s variadic is %v", variadic, name, proto.Variadic)
+		}
+		for i := range args {
+			if err := compare(&args[i], proto.Params[i]); err != nil {
+				return fmt.Errorf("parameter %d of %s: %w", i+1, name, err)
+			}
+		}
+		if err := compare(ctype.Func, proto.Result); err != nil {
+			return fmt.Errorf("result of %s: %w", name, err)
+		}
+		return nil
+	}
+	if tag.Optional() {
+		return nil
+	}
+	return fmt.Errorf("no debug information for %s", strings.Join(symbols, ","))
+}
+
+// class of a C type, as far as the calling convention is concerned.
+type class struct {
+	kind byte // 'v'oid, 'i'nteger, 'f'loat, 'p'ointer, 'c'omplex or 'a'ggregate.
+	size int64
+}
+
+func (c class) String() string {
+	switch c.kind {
+	case 'v':
+		return "void"
+	case 'p':
+		return "a pointer"
+	case 'i':
+		return fmt.Sprintf("a %d-byte integer", c.size)
+	case 'f':
+		return fmt.Sprintf("a %d-byte float", c.size)
+	case 'c':
+		return fmt.Sprintf("a %d-byte complex", c.size)
+	default:
+		return fmt.Sprintf("a %d-byte aggregate", c.size)
+	}
+}
+
+// tagClasses of the standard C type names within a [std.Tag],
+// sizes are for LP64 platforms.
+var tagClasses = map[string]class{
+	"void":      {'v', 0},
+	"bool":      {'i', 1},
+	"char":      {'i', 1},
+	"schar":     {'i', 1},
+	"uchar":     {'i', 1},
+	"short":     {'i', 2},
+	"ushort":    {'i', 2},
+	"int":       {'i', 4},
+	"uint":      {'i', 4},
+	"long":      {'i', 8},
+	"ulong":     {'i', 8},
+	"int8_t":    {'i', 1},
+	"int16_t":   {'i', 2},
+	"int32_t":   {'i', 4},
+	"int64_t":   {'i', 8},
+	"uint8_t":   {'i', 1},
+	"uint16_t":  {'i', 2},
+	"uint32_t":  {'i', 4},
+	"uint64_t":  {'i', 8},
+	"size_t":    {'i', 8},
+	"ptrdiff":   {'i', 8},
+	"ptrdiff_t": {'i', 8},
+	"intptr_t":  {'i', 8},
+	"uintptr_t": {'i', 8},
+	"float":     {'f', 4},
+	"double":    {'f', 8},
+	"complex":   {'c', 16},
+}
+
+// compare the tag type to the DWARF type.
+func compare(tag *std.Type, dtype dwarf.Type) error {
+	var want class
+	switch {
+	case tag.Free != 0:
+		want = class{'p', 8}
+	default:
+		known, ok := tagClasses[tag.Name]
+		if !ok {
+			// a named struct or typedef.
+			if name := typeName(dtype); name == tag.Name {
+				return nil
+			}
+			return fmt.Errorf("tag type %s does not match %s", tag.Name, typeName(dtype))
+		}
+		want = known
+	}
+	if have := classOf(dtype); have != want {
+		return fmt.Errorf("tag type %s is %s, but %s is %s", tag.Name, want, typeName(dtype), have)
+	}
+	return nil
+}
+
+func typeName(dtype dwarf.Type) string {
+	if dtype == nil {
+		return "void"
+	}
+	return dtype.String()
+}
+
+func classOf(dtype dwarf.Type) class {
+	for {
+		switch t := dtype.(type) {
+		case nil, *dwarf.VoidType:
+			return class{'v', 0}
+		case *dwarf.TypedefType:
+			dtype = t.Type
+		case *dwarf.QualType:
+			dtype = t.Type
+		case *dwarf.FloatType:
+			return class{'f', t.ByteSize}
+		case *dwarf.ComplexType:
+			return class{'c', t.ByteSize}
+		case *dwarf.PtrType, *dwarf.FuncType, *dwarf.ArrayType:
+			return class{'p', 8}
+		case *dwarf.StructType:
+			return class{'a', t.ByteSize}
+		default:
+			return class{'i', dtype.Size()}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package elf inspects ELF shared objects without loading them, such that
+// runtime.link library structs can be checked against them in advance.
+package elf
+
+import (
+	"debug/elf"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"runtime.link/cgo"
+	"runtime.link/std"
+)
+
+// Library describes an ELF shared object.
+type Library struct {
+	Name    string   // DT_SONAME
+	Needed  []string // DT_NEEDED
+	RPath   []string // DT_RPATH
+	RunPath []string // DT_RUNPATH
+	Symbols []Symbol // exported dynamic symbols.
+}
+
+// Symbol exported by a [Library].
+type Symbol struct {
+	Name    string
+	Version string // empty for unversioned symbols.
+	Default bool   // the version of the symbol that is linked to when no version is specified.
+	Func    bool   // the symbol is a function, rather than data.
+}
+
+// String returns the symbol in the name@version (or name@@version for
+// default versions) notation.
+func (sym Symbol) String() string {
+	switch {
+	case sym.Version == "":
+		return sym.Name
+	case sym.Default:
+		return sym.Name + "@@" + sym.Version
+	default:
+		return sym.Name + "@" + sym.Version
+	}
+}
+
+// Open the ELF shared object at the given path and inspect it.
+func Open(path string) (*Library, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return inspect(f)
+}
+
+// Read the ELF shared object from r and inspect it.
+func Read(r io.ReaderAt) (*Library, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	return inspect(f)
+}
+
+func inspect(f *elf.File) (*Library, error) {
+	if f.Type != elf.ET_DYN {
+		return nil, errors.New("not a shared object: " + f.Type.String())
+	}
+	var lib Library
+	dynamic := func(tag elf.DynTag) []string {
+		values, _ := f.DynString(tag)
+		return values
+	}
+	if names := dynamic(elf.DT_SONAME); len(names) > 0 {
+		lib.Name = names[0]
+	}
+	lib.Needed = dynamic(elf.DT_NEEDED)
+	for _, rpath := range dynamic(elf.DT_RPATH) {
+		lib.RPath = append(lib.RPath, strings.Split(rpath, ":")...)
+	}
+	for _, runpath := range dynamic(elf.DT_RUNPATH) {
+		lib.RunPath = append(lib.RunPath, strings.Split(runpath, ":")...)
+	}
+	symbols, err := f.DynamicSymbols()
+	if err != nil && !errors.Is(err, elf.ErrNoSymbols) {
+		return nil, err
+	}
+	versions, err := readVersions(f)
+	if err != nil {
+		return nil, err
+	}
+	for i, sym := range symbols {
+		if sym.Section == elf.SHN_UNDEF || sym.Name == "" {
+			continue
+		}
+		switch elf.ST_BIND(sym.Info) {
+		case elf.STB_GLOBAL, elf.STB_WEAK, elf.STB_LOOS: // STB_GNU_UNIQUE
+		default:
+			continue
+		}
+		switch elf.ST_VISIBILITY(sym.Other) {
+		case elf.STV_DEFAULT, elf.STV_PROTECTED:
+		default:
+			continue
+		}
+		kind := elf.ST_TYPE(sym.Info)
+		if kind == elf.STT_SECTION || kind == elf.STT_FILE {
+			continue
+		}
+		// the symbol table includes the null symbol, which
+		// is skipped by DynamicSymbols.
+		version, hidden := versions.of(i + 1)
+		lib.Symbols = append(lib.Symbols, Symbol{
+			Name:    sym.Name,
+			Version: version,
+			Default: !hidden,
+			Func:    kind == elf.STT_FUNC || kind == elf.STT_LOOS, // STT_GNU_IFUNC
+		})
+	}
+	return &lib, nil
+}
+
+// Lookup returns the symbol with the given name, which may include a
+// version (name@version), otherwise the default version is returned.
+func (lib *Library) Lookup(name string) (Symbol, bool) {
+	name, version, versioned := strings.Cut(name, "@")
+	version = strings.TrimPrefix(version, "@")
+	for _, sym := range lib.Symbols {
+		if sym.Name != name {
+			continue
+		}
+		if versioned && sym.Version == version || !versioned && sym.Default {
+			return sym, true
+		}
+	}
+	return Symbol{}, false
+}
+
+// Check reports each function of the library struct pointed to by library
+// (including those of nested exported structs) that would fail to bind to
+// this library, because none of the symbols in its tag are exported. Each
+// error names the field, and wraps a [cgo.MissingSymbolError] (or a tag
+// parsing error). Optional symbols are not reported.
+func (lib *Library) Check(library any) error {
+	rtype := reflect.TypeOf(library)
+	if rtype == nil || rtype.Kind() != reflect.Pointer || rtype.Elem().Kind() != reflect.Struct {
+		return errors.New("elf.Check: library must be a pointer to a struct")
+	}
+	var errs []error
+	lib.check(rtype.Elem(), "", &errs)
+	return errors.Join(errs...)
+}
+
+func (lib *Library) check(rtype reflect.Type, prefix string, errs *[]error) {
+	for i := 0; i < rtype.NumField(); i++ {
+		field := rtype.Field(i)
+		if field.IsExported() && field.Type.Kind() == reflect.Struct {
+			lib.check(field.Type, prefix+field.Name+".", errs)
+		}
+		if field.Type.Kind() != reflect.Func {
+			continue
+		}
+		tag := std.Tag(field.Tag.Get("std"))
+		symbols, _, err := tag.Parse()
+		if err != nil {
+			*errs = append(*errs, FieldError{prefix + field.Name, err})
+			continue
+		}
+		if tag.Optional() || lib.bound(symbols) {
+			continue
+		}
+		*errs = append(*errs, FieldError{prefix + field.Name, cgo.MissingSymbolError(strings.Join(symbols, ","))})
+	}
+}
+
+func (lib *Library) bound(symbols []string) bool {
+	for _, symbol := range symbols {
+		if _, ok := lib.Lookup(symbol); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldError is reported by [Library.Check] for each
+// field of a library struct that would fail to bind.
+type FieldError struct {
+	field string
+	err   error
+}
+
+func (e FieldError) Error() string { return e.field + ": " + e.err.Error() }
+
+func (e FieldError) Unwrap() error { return e.err }
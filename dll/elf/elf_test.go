@@ -0,0 +1,131 @@
+package elf_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"runtime.link/cgo"
+	"runtime.link/dll"
+	"runtime.link/dll/elf"
+	"runtime.link/lib"
+)
+
+func openLibc(t *testing.T) *elf.Library {
+	handle, err := dll.Open("libc.so.6")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer handle.Close()
+	libc, err := elf.Open(handle.Path())
+	if err != nil {
+		t.Skip(err)
+	}
+	return libc
+}
+
+func TestInspect(t *testing.T) {
+	libc := openLibc(t)
+	if libc.Name != "libc.so.6" {
+		t.Fatal("unexpected soname", libc.Name)
+	}
+	if len(libc.Needed) == 0 || !strings.HasPrefix(libc.Needed[0], "ld-linux") {
+		t.Fatal("expected libc to depend on the dynamic linker", libc.Needed)
+	}
+	puts, ok := libc.Lookup("puts")
+	if !ok || !puts.Func || !puts.Default || !strings.HasPrefix(puts.Version, "GLIBC_") {
+		t.Fatal("expected puts to be a versioned function", puts)
+	}
+	if _, ok := libc.Lookup("puts@" + puts.Version); !ok {
+		t.Fatal("expected to lookup puts by version")
+	}
+	if _, ok := libc.Lookup("runtime_link_missing"); ok {
+		t.Fatal("unexpected symbol")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	libc := openLibc(t)
+	err := libc.Check(&struct {
+		linux lib.Location `std:"libc.so.6"`
+
+		puts     func(string) error `std:"puts func(&char)int<0"`
+		missing  func()             `std:"runtime_link_missing func()void"`
+		optional func()             `std:"runtime_link_missing? func()void"`
+
+		Nested struct {
+			version func() `std:"puts@GLIBC_0.0 func()void"`
+		}
+	}{})
+	var missing cgo.MissingSymbolError
+	if !errors.As(err, &missing) || missing != "runtime_link_missing" {
+		t.Fatal("expected a missing symbol error", err)
+	}
+	if !strings.Contains(err.Error(), "Nested.version: symbol puts (version GLIBC_0.0) not found") {
+		t.Fatal("expected the nested field to be reported", err)
+	}
+	if strings.Contains(err.Error(), "optional") || strings.Contains(err.Error(), "puts:") {
+		t.Fatal("unexpected errors", err)
+	}
+}
+
+const prototypes = `
+#include <stddef.h>
+
+typedef struct { int quot, rem; } div_t;
+
+double scale(double x, int n) { return x * n; }
+div_t divide(int num, int denom) { div_t d = {num / denom, num % denom}; return d; }
+size_t length(const char *s) { size_t n = 0; while (s[n]) n++; return n; }
+int format(char *buf, const char *fmt, ...) { return 0; }
+`
+
+func TestVerify(t *testing.T) {
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "prototypes.c")
+	if err := os.WriteFile(source, []byte(prototypes), 0644); err != nil {
+		t.Fatal(err)
+	}
+	library := filepath.Join(dir, "libprototypes.so")
+	if out, err := exec.Command(cc, "-g", "-O2", "-shared", "-fPIC", "-o", library, source).CombinedOutput(); err != nil {
+		t.Skip(string(out))
+	}
+	protos, err := elf.ReadPrototypes(library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := protos.Verify(&struct {
+		scale  func(float64, int32) float64       `std:"scale func(double,int)double"`
+		divide func(int32, int32) (int32, int32)  `std:"divide func(int,int)div_t"`
+		length func(string) uintptr               `std:"length func(&char)size_t"`
+		format func([]byte, string, ...any) int32 `std:"format func(&char,&char,void...)int"`
+		absent func()                             `std:"runtime_link_missing? func()void"`
+	}{}); err != nil {
+		t.Fatal(err)
+	}
+	err = protos.Verify(&struct {
+		count    func(float64) float64        `std:"scale func(double)double"`
+		param    func(int32, int32) float64   `std:"scale func(int,int)double"`
+		result   func(float64, int32) float32 `std:"scale func(double,int)float"`
+		variadic func(string) int32           `std:"format func(&char,&char)int"`
+		missing  func()                       `std:"runtime_link_missing func()void"`
+	}{})
+	for _, expected := range []string{
+		"count: tag has 1 parameters, scale has 2",
+		"param: parameter 1 of scale: tag type int is a 4-byte integer, but double is a 8-byte float",
+		"result: result of scale: tag type float is a 4-byte float, but double is a 8-byte float",
+		"variadic: tag variadic is false, format variadic is true",
+		"missing: no debug information for runtime_link_missing",
+	} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatal("expected", expected, "in", err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package elf
+
+import (
+	"debug/elf"
+	"errors"
+)
+
+// versions of the dynamic symbols, as defined by the GNU symbol
+// versioning sections (.gnu.version and .gnu.version_d).
+type versions struct {
+	versym  []uint16          // version index of each dynamic symbol.
+	defined map[uint16]string // names of the version indices.
+}
+
+const (
+	verFlagBase   = 0x1    // VER_FLG_BASE, the version of the library itself.
+	versymHidden  = 0x8000 // VERSYM_HIDDEN, not the default version.
+	versymVersion = 0x7fff // VERSYM_VERSION
+	verdefSize    = 20     // sizeof(Elf_Verdef)
+	verdauxSize   = 8      // sizeof(Elf_Verdaux)
+)
+
+var errVersions = errors.New("malformed symbol versions")
+
+func readVersions(f *elf.File) (versions, error) {
+	var v versions
+	versym := f.SectionByType(elf.SHT_GNU_VERSYM)
+	verdef := f.SectionByType(elf.SHT_GNU_VERDEF)
+	if versym == nil || verdef == nil {
+		return v, nil
+	}
+	data, err := versym.Data()
+	if err != nil {
+		return v, err
+	}
+	for i := 0; i+2 <= len(data); i += 2 {
+		v.versym = append(v.versym, f.ByteOrder.Uint16(data[i:]))
+	}
+	if int(verdef.Link) >= len(f.Sections) {
+		return v, errVersions
+	}
+	strtab, err := f.Sections[verdef.Link].Data()
+	if err != nil {
+		return v, err
+	}
+	str := func(offset uint32) string {
+		if int(offset) >= len(strtab) {
+			return ""
+		}
+		end := int(offset)
+		for end < len(strtab) && strtab[end] != 0 {
+			end++
+		}
+		return string(strtab[offset:end])
+	}
+	data, err = verdef.Data()
+	if err != nil {
+		return v, err
+	}
+	v.defined = make(map[uint16]string)
+	for offset := 0; offset+verdefSize <= len(data); {
+		entry := data[offset:]
+		flags := f.ByteOrder.Uint16(entry[2:])
+		index := f.ByteOrder.Uint16(entry[4:])
+		aux := int(f.ByteOrder.Uint32(entry[12:]))
+		next := int(f.ByteOrder.Uint32(entry[16:]))
+		if offset+aux+verdauxSize > len(data) {
+			return v, errVersions
+		}
+		if flags&verFlagBase == 0 {
+			v.defined[index] = str(f.ByteOrder.Uint32(data[offset+aux:]))
+		}
+		if next == 0 {
+			break
+		}
+		offset += next
+	}
+	return v, nil
+}
+
+// of returns the version of the dynamic symbol at the given index
+// in the symbol table, and whether it is hidden.
+func (v versions) of(symbol int) (string, bool) {
+	if symbol >= len(v.versym) {
+		return "", false
+	}
+	index := v.versym[symbol]
+	return v.defined[index&versymVersion], index&versymHidden != 0
+}
@@ -0,0 +1,211 @@
+package dll
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"runtime.link/cgo"
+)
+
+// Handle to an open shared library. The platform counts the
+// references to each library, such that it is only unloaded
+// once every handle to it has been closed.
+type Handle struct {
+	name  string
+	mutex sync.RWMutex
+	ptr   unsafe.Pointer // nil once closed.
+}
+
+// Option for [Open], either [Flags] or a [Namespace].
+type Option interface {
+	option()
+}
+
+// Flags control how [Open] loads a library. By default, every symbol
+// is bound upfront (RTLD_NOW) and the symbols are not made available
+// to libraries that are loaded afterwards (RTLD_LOCAL).
+type Flags uint
+
+const (
+	Lazy     Flags = 1 << iota // bind symbols of the library on their first use (RTLD_LAZY).
+	Global                     // make the symbols available to libraries loaded afterwards (RTLD_GLOBAL).
+	NoDelete                   // never unload the library, even after it has been closed (RTLD_NODELETE).
+	NoLoad                     // fail unless the library has already been loaded (RTLD_NOLOAD).
+	Isolated                   // load the library into a new [Namespace].
+)
+
+func (Flags) option() {}
+
+// flags are the names of [Flags] within the 'dll' tag of a lib.Location.
+var flags = map[string]Flags{
+	"lazy":     Lazy,
+	"global":   Global,
+	"nodelete": NoDelete,
+	"noload":   NoLoad,
+	"isolated": Isolated,
+}
+
+// Namespace that libraries are loaded into, each namespace has its
+// own copy of every library loaded into it, such that libraries in
+// different namespaces are isolated from each other. The zero value
+// is the default namespace. Namespaces are only supported on glibc.
+type Namespace struct {
+	id int64
+}
+
+func (Namespace) option() {}
+
+// lmidNew is the namespace id that requests a new namespace (LM_ID_NEWLM).
+const lmidNew = -1
+
+// Open the shared library with the given name, optionally with [Flags]
+// or into a [Namespace] (such as the one returned by [Handle.Namespace]).
+// Unless the name is a path, the directories in [SearchPathEnv] are
+// searched first, then the directory of the executable (and the lib
+// directory next to it), then the platform's search path and finally
+// the ldconfig cache. If the library cannot be found, the error is a
+// [SearchError] that lists each path that was tried.
+func Open(name string, options ...Option) (*Handle, error) {
+	var (
+		mode Flags
+		ns   Namespace
+	)
+	for _, option := range options {
+		switch option := option.(type) {
+		case Flags:
+			mode |= option
+		case Namespace:
+			ns = option
+		}
+	}
+	if mode&Isolated != 0 {
+		ns.id = lmidNew
+	}
+	if ns.id != 0 && !namespaces {
+		return nil, ErrNamespaces
+	}
+	ptr, err := open(name, search(name), mode, ns.id)
+	if err != nil {
+		return nil, err
+	}
+	return &Handle{name: name, ptr: ptr}, nil
+}
+
+// Close the handle, such that the library can be unloaded. Symbols
+// looked up through the handle must not be used after it has been
+// closed. Closing a handle more than once has no effect.
+func (h *Handle) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.ptr == nil {
+		return nil
+	}
+	if dlclose(h.ptr) != 0 {
+		return errorString("cannot close " + h.name + ": " + dlerror())
+	}
+	h.ptr = nil
+	return nil
+}
+
+// Path returns the path that the library was loaded from, or
+// the name that it was opened with, if the platform can't tell.
+func (h *Handle) Path() string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if h.ptr != nil {
+		if path := dlpath(h.ptr); path != "" {
+			return path
+		}
+	}
+	return h.name
+}
+
+// Namespace returns the namespace that the library was loaded into,
+// such that other libraries can be loaded into the same namespace.
+func (h *Handle) Namespace() Namespace {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if h.ptr == nil || !namespaces {
+		return Namespace{}
+	}
+	return Namespace{dlnamespace(h.ptr)}
+}
+
+// Symbol returns the address of the symbol with the given name, or
+// nil if the library has no such symbol, or the handle is closed.
+// The name may include a version, such as memcpy@GLIBC_2.14, in
+// which case only that version of the symbol is returned. Symbol
+// versions are only supported on glibc.
+func (h *Handle) Symbol(name string) unsafe.Pointer {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if h.ptr == nil {
+		return nil
+	}
+	if symbol, version, ok := strings.Cut(name, "@"); ok {
+		return dlvsym(h.ptr, symbol, strings.TrimPrefix(version, "@"))
+	}
+	return dlsym(h.ptr, name)
+}
+
+// binding of a library function to the handles that
+// its symbol is looked up in.
+type binding struct {
+	handles  []*Handle
+	link     func() (reflect.Value, error) // links a lazy stub, nil if linked upfront.
+	unloaded atomic.Bool
+}
+
+// bindings are keyed by the closure of the function that
+// they are bound to, such that copies of it are included.
+var bindings sync.Map // map[unsafe.Pointer]*binding
+
+// closure returns the closure pointer of the func pointed to
+// by ptr, which identifies it (and any copies of it).
+func closure(ptr any) unsafe.Pointer {
+	return *(*unsafe.Pointer)(reflect.ValueOf(ptr).UnsafePointer())
+}
+
+// Unload the libraries that were imported into lib, which must be
+// a pointer to a library struct returned by [Import], [ImportLazy]
+// or [Load]. Afterwards, its functions (and any copies of them)
+// return, or panic with, [cgo.ErrUnlinked] when called. Unload
+// must not be called while any of its functions are being called.
+func Unload(lib any) error {
+	if rtype := reflect.TypeOf(lib); rtype == nil || rtype.Kind() != reflect.Pointer || rtype.Elem().Kind() != reflect.Struct {
+		panic("dll.Unload: lib must be a pointer to a struct")
+	}
+	var (
+		handles []*Handle
+		seen    = make(map[*Handle]bool)
+	)
+	each(lib, func(ptr any, _ reflect.StructField) {
+		if reflect.ValueOf(ptr).Elem().IsNil() {
+			return
+		}
+		value, ok := bindings.Load(closure(ptr))
+		if !ok {
+			return
+		}
+		b := value.(*binding)
+		b.unloaded.Store(true)
+		cgo.Unlink(ptr)
+		for _, h := range b.handles {
+			if !seen[h] {
+				seen[h] = true
+				handles = append(handles, h)
+			}
+		}
+	})
+	var errs []error
+	for _, h := range handles {
+		if err := h.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
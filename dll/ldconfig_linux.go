@@ -0,0 +1,121 @@
+package dll
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ldconfigMagic identifies the (new) format of /etc/ld.so.cache,
+// which may follow the entries of the old format.
+const ldconfigMagic = "glibc-ld.so.cache1.1"
+
+// ldconfigArch is the required flag of each cache entry for the GOARCH.
+var ldconfigArch = map[string]int32{
+	"386":     0x0000,
+	"amd64":   0x0300,
+	"arm64":   0x0a00,
+	"riscv64": 0x1000,
+}
+
+// ldconfigCache maps each library name in the cache to its paths.
+var ldconfigCache = sync.OnceValue(func() map[string][]string {
+	data, err := os.ReadFile("/etc/ld.so.cache")
+	if err != nil {
+		return make(map[string][]string)
+	}
+	return parseLdconfig(data)
+})
+
+// parseLdconfig parses the contents of an ldconfig cache, ignoring any
+// entries that are truncated.
+func parseLdconfig(data []byte) map[string][]string {
+	cache := make(map[string][]string)
+	start := bytes.Index(data, []byte(ldconfigMagic))
+	if start < 0 {
+		return cache
+	}
+	const (
+		headerSize = 48
+		entrySize  = 24
+	)
+	data = data[start:]
+	if len(data) < headerSize {
+		return cache
+	}
+	nlibs := int(binary.LittleEndian.Uint32(data[20:]))
+	arch, filter := ldconfigArch[runtime.GOARCH]
+	str := func(offset uint32) string {
+		if int(offset) >= len(data) {
+			return ""
+		}
+		s := data[offset:]
+		if end := bytes.IndexByte(s, 0); end >= 0 {
+			s = s[:end]
+		}
+		return string(s)
+	}
+	for i := 0; i < nlibs && headerSize+(i+1)*entrySize <= len(data); i++ {
+		entry := data[headerSize+i*entrySize:]
+		flags := int32(binary.LittleEndian.Uint32(entry))
+		if filter && flags&0xff00 != arch {
+			continue
+		}
+		key := str(binary.LittleEndian.Uint32(entry[4:]))
+		cache[key] = append(cache[key], str(binary.LittleEndian.Uint32(entry[8:])))
+	}
+	return cache
+}
+
+// ldconfig returns the paths in the ldconfig cache for the library with
+// the given name, followed by the paths of any versions of it (highest
+// first), such that an unversioned name like libfoo.so can resolve to
+// libfoo.so.1
+func ldconfig(name string) []string {
+	return ldconfigPaths(ldconfigCache(), name)
+}
+
+func ldconfigPaths(cache map[string][]string, name string) []string {
+	paths := slices.Clone(cache[name])
+	var versions []string
+	for key := range cache {
+		if strings.HasPrefix(key, name+".") {
+			versions = append(versions, key)
+		}
+	}
+	slices.SortFunc(versions, func(a, b string) int {
+		return compareVersions(b[len(name)+1:], a[len(name)+1:])
+	})
+	for _, key := range versions {
+		paths = append(paths, cache[key]...)
+	}
+	return paths
+}
+
+// compareVersions compares dot-separated versions, such as 1.10 and 1.9,
+// numerically where possible.
+func compareVersions(a, b string) int {
+	for a != "" || b != "" {
+		var x, y string
+		x, a, _ = strings.Cut(a, ".")
+		y, b, _ = strings.Cut(b, ".")
+		n, errx := strconv.Atoi(x)
+		m, erry := strconv.Atoi(y)
+		if errx == nil && erry == nil {
+			if c := cmp.Compare(n, m); c != 0 {
+				return c
+			}
+			continue
+		}
+		if c := strings.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,54 @@
+package dll
+
+import (
+	"encoding/binary"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// ldconfigData returns an ldconfig cache with an entry for each pair
+// of name and path.
+func ldconfigData(pairs ...string) []byte {
+	const (
+		headerSize = 48
+		entrySize  = 24
+	)
+	data := make([]byte, headerSize+len(pairs)/2*entrySize)
+	copy(data, ldconfigMagic)
+	binary.LittleEndian.PutUint32(data[20:], uint32(len(pairs)/2))
+	for i, s := range pairs {
+		entry := data[headerSize+i/2*entrySize:]
+		if i%2 == 0 {
+			binary.LittleEndian.PutUint32(entry, uint32(ldconfigArch[runtime.GOARCH]))
+		}
+		binary.LittleEndian.PutUint32(entry[4+i%2*4:], uint32(len(data)))
+		data = append(append(data, s...), 0)
+	}
+	return data
+}
+
+func TestLdconfig(t *testing.T) {
+	data := ldconfigData(
+		"libfoo.so.1", "/lib/libfoo.so.1",
+		"libfoo.so.10", "/lib/libfoo.so.10",
+		"libfoo.so", "/lib/libfoo.so",
+		"libfoo.so.9.2", "/lib/libfoo.so.9.2",
+		"libfoobar.so", "/lib/libfoobar.so",
+	)
+	cache := parseLdconfig(data)
+	for range 10 {
+		paths := ldconfigPaths(cache, "libfoo.so")
+		if !slices.Equal(paths, []string{"/lib/libfoo.so", "/lib/libfoo.so.10", "/lib/libfoo.so.9.2", "/lib/libfoo.so.1"}) {
+			t.Fatal("unexpected paths", paths)
+		}
+	}
+	binary.LittleEndian.PutUint32(data[20:], 1<<20)
+	var entries int
+	for _, paths := range parseLdconfig(data[:48+2*24+10]) {
+		entries += len(paths)
+	}
+	if entries != 2 {
+		t.Fatal("expected the truncated entries of the cache to be ignored", entries)
+	}
+}
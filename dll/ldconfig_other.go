@@ -0,0 +1,6 @@
+//go:build !linux
+
+package dll
+
+// ldconfig returns nil, as there is no ldconfig cache on this platform.
+func ldconfig(name string) []string { return nil }
@@ -0,0 +1,98 @@
+package dll
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unsafe"
+)
+
+// SearchPathEnv is the environment variable with a list of directories
+// (separated by [os.PathListSeparator]) that are searched for libraries
+// before any other location.
+const SearchPathEnv = "RUNTIME_LINK_PATH"
+
+// search returns the paths to try when opening the library with the given
+// name, in order of preference. Names that are paths are not searched for.
+//
+//  1. each directory in $RUNTIME_LINK_PATH
+//  2. the directory of the executable, and the lib directory next to it.
+//  3. the name itself, as searched for by the platform.
+//  4. any matching paths in the ldconfig cache.
+func search(name string) []string {
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		return []string{name}
+	}
+	var (
+		paths []string
+		seen  = make(map[string]bool)
+	)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	for _, dir := range filepath.SplitList(os.Getenv(SearchPathEnv)) {
+		if dir != "" {
+			add(filepath.Join(dir, name))
+		}
+	}
+	if exe, err := os.Executable(); err == nil {
+		dir := filepath.Dir(exe)
+		add(filepath.Join(dir, name))
+		add(filepath.Join(dir, "..", "lib", name))
+	}
+	add(name)
+	for _, path := range ldconfig(name) {
+		add(path)
+	}
+	return paths
+}
+
+// SearchError lists every path that was tried when searching for a
+// library, along with the reason why it could not be opened.
+type SearchError struct {
+	name  string
+	paths []string
+	errs  []string // dlerror for each path.
+}
+
+func (e SearchError) Error() string {
+	var b strings.Builder
+	b.WriteString(MissingLibraryError(e.name).Error())
+	b.WriteString(", tried:")
+	for i, path := range e.paths {
+		b.WriteString("\n\t")
+		if !strings.HasPrefix(e.errs[i], path) {
+			b.WriteString(path)
+			b.WriteString(": ")
+		}
+		b.WriteString(e.errs[i])
+	}
+	return b.String()
+}
+
+// Unwrap returns the [MissingLibraryError] for the library.
+func (e SearchError) Unwrap() error { return MissingLibraryError(e.name) }
+
+// open the first of the paths that can be opened, or else
+// return a [SearchError] explaining why none of them could.
+func open(name string, paths []string, mode Flags, ns int64) (unsafe.Pointer, error) {
+	// dlerror is thread-local.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	var errs []string
+	for _, path := range paths {
+		if ptr := dlopen(path, mode, ns); ptr != nil {
+			return ptr, nil
+		}
+		reason := dlerror()
+		if reason == "" {
+			reason = "unknown error"
+		}
+		errs = append(errs, reason)
+	}
+	return nil, SearchError{name: name, paths: paths, errs: errs}
+}
@@ -0,0 +1,165 @@
+// Package cconv converts values between Go types and their C representation,
+// such that the fields of C structs (std) and the arguments and results of C
+// functions (cgo) follow the same rules.
+package cconv
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// IsPointer is the std.IsPointer interface, implemented by handles
+// and by memory.
+type IsPointer interface {
+	Pointer() uintptr
+}
+
+// The memory of a std.Memory, std.Pointer and std.Struct, along with the
+// layout of C structs, are defined by package std, which sets these when
+// it is initialized.
+var (
+	// Memory is the type of the memory shared by the copies of a
+	// std.Memory, std.Pointer or std.Struct.
+	Memory reflect.Type
+
+	// Borrow returns a new *Memory for C memory at ptr, that is
+	// not owned by Go, so it is never freed.
+	Borrow func(ptr unsafe.Pointer) unsafe.Pointer
+
+	// Layout returns the C layout of a Go struct, as computed by
+	// std.LayoutOf.
+	Layout func(rtype reflect.Type) (size, align uintptr, fields []Field, err error)
+)
+
+// Field is the location of a field within a C struct.
+type Field struct {
+	GoOffset uintptr // of the Go field.
+	Offset   uintptr // in bytes, from the start of the C struct.
+	Size     uintptr // of the C type, in bytes.
+	Bits     int     // width of a bitfield, zero for other fields.
+}
+
+// MemoryOffset returns the offset of the pointer to the memory of a
+// std.Memory, std.Pointer or std.Struct (or of a type defined by one),
+// these point to either the memory, or to a struct that only embeds it.
+func MemoryOffset(rtype reflect.Type) (uintptr, bool) {
+	if rtype.Kind() != reflect.Struct || rtype.Size() != unsafe.Sizeof(uintptr(0)) {
+		return 0, false
+	}
+	for i := 0; i < rtype.NumField(); i++ {
+		field := rtype.Field(i)
+		if field.Type.Size() == 0 {
+			continue
+		}
+		if field.Type.Kind() != reflect.Pointer {
+			return 0, false
+		}
+		elem := field.Type.Elem()
+		if elem == Memory {
+			return field.Offset, true
+		}
+		ok := elem.Kind() == reflect.Struct && elem.NumField() == 1 && elem.Field(0).Anonymous &&
+			elem.Field(0).Type == Memory
+		return field.Offset, ok
+	}
+	return 0, false
+}
+
+// IsMemory reports whether the type is a std.Memory, std.Pointer or
+// std.Struct, or is defined by one.
+func IsMemory(rtype reflect.Type) bool {
+	_, ok := MemoryOffset(rtype)
+	return ok
+}
+
+// HandleOffset returns the offset of the uintptr field that is returned
+// by the [IsPointer] method of the given struct type, when this is the
+// only field in the struct with a non-zero size, as for a std.Handle.
+func HandleOffset(rtype reflect.Type) (uintptr, bool) {
+	const probe = 0x5eed
+	if rtype.Kind() != reflect.Struct || !rtype.Implements(reflect.TypeFor[IsPointer]()) {
+		return 0, false
+	}
+	for i := 0; i < rtype.NumField(); i++ {
+		field := rtype.Field(i)
+		if field.Type.Size() == 0 {
+			continue
+		}
+		if field.Type.Kind() != reflect.Uintptr || field.Type.Size() != rtype.Size() {
+			return 0, false
+		}
+		value := reflect.New(rtype)
+		*(*uintptr)(unsafe.Add(value.UnsafePointer(), field.Offset)) = probe
+		if value.Elem().Interface().(IsPointer).Pointer() != probe {
+			return 0, false
+		}
+		return field.Offset, true
+	}
+	return 0, false
+}
+
+// Convertible reports whether values can be converted between the C
+// representation repr and the Go type.
+func Convertible(repr, rtype reflect.Type) bool {
+	switch repr.Kind() {
+	case reflect.UnsafePointer:
+		_, handle := HandleOffset(rtype)
+		return handle || IsMemory(rtype) || rtype.Kind() == reflect.UnsafePointer ||
+			rtype.Kind() == reflect.Uintptr || rtype.Kind() == reflect.Pointer
+	case reflect.String:
+		return rtype.Kind() == reflect.String
+	case reflect.Struct, reflect.Array:
+		return repr == rtype
+	}
+	return rtype.Kind() == reflect.Bool || repr.Kind() == reflect.Bool && rtype.ConvertibleTo(reflect.TypeFor[int]()) ||
+		rtype.ConvertibleTo(repr) && rtype.Kind() != reflect.String && rtype.Kind() != reflect.Pointer && rtype.Kind() != reflect.UnsafePointer
+}
+
+// Convert the src value into dst, where one of them is the C representation
+// of the other and they are [Convertible]. Memory converted from a C pointer
+// is borrowed, it is never freed by Go.
+func Convert(dst, src reflect.Value) {
+	switch {
+	case src.Type() == dst.Type():
+		dst.Set(src)
+	case dst.Kind() == reflect.UnsafePointer:
+		var addr uintptr
+		switch {
+		case src.Kind() == reflect.Uintptr:
+			addr = uintptr(src.Uint())
+		case src.Kind() == reflect.Pointer:
+			addr = uintptr(src.UnsafePointer())
+		default:
+			addr = src.Interface().(IsPointer).Pointer()
+		}
+		dst.SetPointer(*(*unsafe.Pointer)(unsafe.Pointer(&addr)))
+	case src.Kind() == reflect.UnsafePointer:
+		ptr := src.UnsafePointer()
+		switch {
+		case dst.Kind() == reflect.Uintptr:
+			dst.SetUint(uint64(uintptr(ptr)))
+		case dst.Kind() == reflect.Pointer:
+			dst.Set(reflect.NewAt(dst.Type().Elem(), ptr))
+		case IsMemory(dst.Type()):
+			offset, _ := MemoryOffset(dst.Type())
+			var m unsafe.Pointer
+			if ptr != nil {
+				m = Borrow(ptr)
+			}
+			*(*unsafe.Pointer)(unsafe.Add(dst.Addr().UnsafePointer(), offset)) = m
+		default:
+			offset, _ := HandleOffset(dst.Type())
+			*(*unsafe.Pointer)(unsafe.Add(dst.Addr().UnsafePointer(), offset)) = ptr
+		}
+	case dst.Kind() == reflect.Bool:
+		dst.SetBool(!src.IsZero())
+	case src.Kind() == reflect.Bool:
+		if src.Bool() {
+			dst.Set(reflect.ValueOf(1).Convert(dst.Type()))
+		} else {
+			dst.SetZero()
+		}
+	default:
+		dst.Set(src.Convert(dst.Type()))
+	}
+}
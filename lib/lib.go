@@ -11,4 +11,10 @@ package lib
 //		darwin  lib.Location `std:"libSystem.dylib"`
 //		windows lib.Location `std:"msvcrt.dll"`
 //	}
+//
+// A 'dll' tag can be added to control how the library is loaded,
+// with a comma separated list of lazy, global, nodelete, noload
+// and isolated (see runtime.link/dll.Flags).
+//
+//	linux lib.Location `std:"libplugin.so" dll:"global,isolated"`
 type Location struct{}
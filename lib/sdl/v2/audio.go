@@ -177,15 +177,15 @@ const (
 type AudioCallback std.Func[func(std.UnsafePointer, std.Buffer)]
 
 type AudioSpec struct {
-	Freq     std.Int           /**< DSP frequency -- samples per second */
-	Format   AudioFormat       /**< Audio data format */
-	Channels std.Uint8         /**< Number of channels: 1 mono, 2 stereo */
-	Silence  std.Uint8         /**< Audio buffer silence value (calculated) */
-	Samples  std.Uint16        /**< Audio buffer size in samples (power of 2) */
-	Padding  std.Uint16        /**< Necessary for some compile environments */
-	Size     std.Uint32        /**< Audio buffer size in bytes (calculated) */
-	Callback AudioCallback     /**< Callback that feeds the audio device (NULL to use SDL_QueueAudio()). */
-	Userdata std.UnsafePointer /**< Userdata passed to callback (ignored for NULL callbacks). */
+	Freq     std.Int           `std:"freq int"`                   /**< DSP frequency -- samples per second */
+	Format   AudioFormat       `std:"format uint16_t"`            /**< Audio data format */
+	Channels std.Uint8         `std:"channels uint8_t"`           /**< Number of channels: 1 mono, 2 stereo */
+	Silence  std.Uint8         `std:"silence uint8_t"`            /**< Audio buffer silence value (calculated) */
+	Samples  std.Uint16        `std:"samples uint16_t"`           /**< Audio buffer size in samples (power of 2) */
+	Padding  std.Uint16        `std:"padding uint16_t"`           /**< Necessary for some compile environments */
+	Size     std.Uint32        `std:"size uint32_t"`              /**< Audio buffer size in bytes (calculated) */
+	Callback AudioCallback     `std:"callback SDL_AudioCallback"` /**< Callback that feeds the audio device (NULL to use SDL_QueueAudio()). */
+	Userdata std.UnsafePointer `std:"userdata &void"`             /**< Userdata passed to callback (ignored for NULL callbacks). */
 }
 
 type AudioFilter std.Func[func(*AudioCVT, AudioFormat)]
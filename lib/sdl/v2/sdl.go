@@ -180,12 +180,47 @@ type Windows struct {
 	Destroy       func(Window)                  `ffi:"SDL_DestroyWindow"`
 }
 
-type Surface std.Handle[Surface]
+type Surface std.Handle[surface]
+
+// surface is the beginning of an SDL_Surface, such that the offsets of
+// its fields can be computed for the current platform.
+type surface struct {
+	Flags  std.Uint32     `std:"flags uint32_t"`
+	Format unsafe.Pointer `std:"format &void"`
+	W      std.Int        `std:"w int"`
+	H      std.Int        `std:"h int"`
+	Pitch  std.Int        `std:"pitch int"`
+	Pixels unsafe.Pointer `std:"pixels &void"`
+}
+
+// surfaces reads the fields of an SDL_Surface.
+var surfaces = std.Getters[struct {
+	Width  func(Surface) std.Int        `std:"SDL_Surface.w"`
+	Height func(Surface) std.Int        `std:"SDL_Surface.h"`
+	Pitch  func(Surface) std.Int        `std:"SDL_Surface.pitch"`
+	Pixels func(Surface) unsafe.Pointer `std:"SDL_Surface.pixels"`
+}]()
+
+// Width of the surface, in pixels.
+func (s Surface) Width() std.Int { return surfaces.Width(s) }
+
+// Height of the surface, in pixels.
+func (s Surface) Height() std.Int { return surfaces.Height(s) }
+
+// Pitch is the length of a row of pixels, in bytes.
+func (s Surface) Pitch() std.Int { return surfaces.Pitch(s) }
+
+// Pixels of the surface, which may need to be locked before they
+// are read or written.
+func (s Surface) Pixels() unsafe.Pointer { return surfaces.Pixels(s) }
 
 type Color std.Uint32
 
 type Rect struct {
-	X, Y, W, H std.Int
+	X std.Int `std:"x int"`
+	Y std.Int `std:"y int"`
+	W std.Int `std:"w int"`
+	H std.Int `std:"h int"`
 }
 
 type Draw struct {
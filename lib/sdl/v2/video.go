@@ -38,11 +38,11 @@ const (
 )
 
 type DisplayMode struct {
-	Format      std.Uint32
-	W           std.Int
-	H           std.Int
-	RefreshRate std.Int
-	DriverData  std.UnsafePointer
+	Format      std.Uint32        `std:"format uint32_t"`
+	W           std.Int           `std:"w int"`
+	H           std.Int           `std:"h int"`
+	RefreshRate std.Int           `std:"refresh_rate int"`
+	DriverData  std.UnsafePointer `std:"driverdata &void"`
 }
 
 type Renderer std.Handle[Renderer]
@@ -12,6 +12,24 @@ type is placed after the parameter list.
 
 	abs func(int)int // simple C function, no pointer semantics.
 
+Tags may also be written in C declaration order, with the result type
+before the symbols and their parameter list. A missing result type is
+void, as is a parameter list of (void). These tags are equivalent:
+
+	isalnum func(int)int
+	int isalnum(int)
+
+Symbols that are only available in some versions of a library can be
+marked as optional with a trailing '?', such that it is not an error
+for none of them to be found.
+
+	SDL_GetDefaultAudioInfo? func(&&char,&SDL_AudioSpec,int)int
+
+A symbol name may be followed by '@' and a version, to refer to that
+specific version of the symbol, rather than its default version.
+
+	memcpy@GLIBC_2.14 func(&void~@2,&void[>=@3],size_t)void
+
 Assertions can be added to type identifiers to document pointer ownership,
 error handling and memory safety assertions to make when interacting with
 the function.
@@ -31,7 +49,9 @@ the function.
 	             └── fread borrows this buffer for the duration of the call.
 
 Any '@n' component inside a tag may be substituted with a standard C
-constant name or an integer literal.
+constant name or an integer literal, which may be negative.
+
+	fileno func(&FILE)int>=-1; errno
 
 # Ownership Assertions
 
@@ -41,6 +61,10 @@ pointer types, their presence signals that the value is a pointer.
   - $type - memory ownership is sold to the reciever of the value,
     the receiver becomes responsible for freeing it and
     can do so immediately.
+  - $type@sym - as $type, where sym is the destructor that frees
+    the value, such as fclose for the FILE returned by fopen.
+    runtime.link/cgo returns such values as a [Memory] that calls
+    the destructor once, when it is freed or garbage collected.
   - &type - the receiver borrows this pointer and will not keep a
     reference to it beyond the the lifetime of the
     function call. If specified on a return value, the
@@ -51,6 +75,10 @@ pointer types, their presence signals that the value is a pointer.
     nor the sender may free it.
   - +type - the receiver borrows this pointer so that they can
     initialize it. The existing value is overwritten.
+  - free@sym{args}type - the receiver borrows this pointer until
+    sym is called with args, each of which may refer to a Go argument
+    with %v or %[n]v. The type is void when omitted, such as the
+    buffer of setvbuf, which is borrowed until fclose{%v} is called.
 
 # Safety Assertions
 
@@ -79,14 +107,48 @@ identifier in the standard tag.
   - type!=@n; must not equal @n
   - type=@n; must equal @n
 
+These assertions are checked by runtime.link/cgo on each call. A call
+with arguments that violate an assertion is not made, instead an error
+is returned (or panicked, if the Go function has no error result). The
+capacity of Go slices, strings and pointers is known, assertions that
+depend on the capacity of other pointers cannot be checked.
+
 # Failure Handling
 
-When Safety Assertions are placed on the return value of a function
-a semicolon can be used to indicate what to do when the assertion
-fails. The following options are available:
+When Safety Assertions are placed on the return value of a function,
+they describe the error condition of the function, such that the
+error result of the Go function is set whenever the return value
+matches the assertion. A semicolon can be used to indicate what
+to do when the assertion fails. The following options are available:
 
   - sym - refer to the specified symbol for information about why
     this assertion failed.
+  - errno - the function reports errors through errno, which is
+    cleared before the call and read immediately afterwards. Without
+    an assertion on the return value, any non-zero errno is an error.
+
+The symbol is called on the same thread, with the listed arguments,
+each either an '@n' argument of the failed call, errno or a constant. Well
+known symbols that return a description of the error (such as strerror
+and SDL_GetError) are included in the error, otherwise the symbol is
+assumed to return an int.
+
+	fputc func(int,&FILE)int=EOF; ferror(@2)
+	fopen func(&char,&char)$FILE=NULL; strerror(errno)
+	sqrt func(double)double; errno
+
+# Callbacks
+
+Go functions can be passed to C as function pointers, the callback's
+type is documented with a nested func type. When the parameter list is
+omitted, the signature is inferred from the Go function type.
+
+	qsort func(&void,size_t,size_t,&func(&void,&void)int)
+	atexit func($func)
+
+A borrowed '&func' callback is only valid for the duration of the call,
+whereas an owned '$func' callback remains valid until it is explicitly
+freed.
 
 # Macros
 
@@ -115,28 +177,74 @@ tagged.
 		Name string `std:"name &char"`
 	}
 
+The C layout of a struct is computed from these tags by [LayoutOf], for
+the C ABI of the current platform. The width of a bitfield follows its
+C type and a blank field tagged as packed removes any padding.
+
+	type Flags struct {
+		_ struct{} `std:"packed"`
+
+		Visible bool   `std:"visible bool:1"`
+		Depth   uint8  `std:"depth uint:4"`
+		ID      uint32 `std:"id uint32_t"`
+	}
+
+[Layout.Mismatch] reports whether the C layout differs from the Go
+layout, otherwise a [Struct] converts between them. Structs passed to
+(or returned from) C functions by value are laid out in the same way,
+although these cannot be packed, nor have any bitfields.
+
 # Deep Copies
 
 By default, values are deep-copied between languages. In order to
-avoid these copies, foreign ownership can be preserved with [String] and
-[Pointer] types. Which need to be manually freed. Struct fields
-can be accessed directly this way by specifying getter and setter
-functions. These types are safe to pass back and forth between
-languages (although may panic when misused).
+avoid these copies, foreign ownership can be preserved with [String],
+[Memory], [Pointer] and [Struct] types. Which need to be manually freed.
+These types are safe to pass back and forth between languages (although
+they panic when misused, such as when they are used after being freed,
+or are freed twice).
+
+C memory is allocated with an [Allocator], imported from the C standard
+library, or else it is returned by a C function, in which case it is freed
+by the destructor of the tag (or not at all, when it is borrowed).
+
+	var libc = dll.Import[std.Allocator]()
+
+	ptr := std.New[int32](&libc)
+	defer ptr.Free()
+	ptr.Set(42)
+
+A [Struct] is copied field by field, with the layout of a C struct, such
+that strings are copied to and from C strings. [FieldOf] can be used to
+read and write a single field.
+
+	person := std.NewStruct[struct{
+		Name string `std:"name &char"`
+	}](&libc)
+	defer person.Free()
+	std.FieldOf[string](person, "Name").Set("Alice")
+
+Struct fields can also be accessed directly by specifying getter and setter
+functions, the struct and field are named by the std tag of each function.
 
 	// MyStruct is always passed by reference between languages.
-	type MyStruct std.Pointer[struct{
+	type MyStruct std.Struct[struct {
 		Name string `std:"name &char"`
 	}]
 
-	var getMyStruct = std.Getters[struct{
-		Name(std.Pointer[MyStruct]) string `std:"my_struct.Name"`
+	var getMyStruct = std.Getters[struct {
+		Name func(MyStruct) string `std:"my_struct.name"`
 	}]()
-	var setMyStruct = std.Setters[struct{
-		Name(std.Pointer[MyStruct], string) `std:"my_struct.Name"`
+	var setMyStruct = std.Setters[struct {
+		Name func(MyStruct, string) `std:"my_struct.name"`
 	}]()
 
-	func (ptr MyStruct) Name() string { return getMyStruct.Name(ptr) }
+	func (ptr MyStruct) Name() string        { return getMyStruct.Name(ptr) }
 	func (ptr MyStruct) SetName(name string) { setMyStruct.Name(ptr, name) }
+
+The fields of opaque C structs are accessed by their offset and C type.
+
+	var getSurface = std.Getters[struct {
+		Width func(Surface) int32 `std:"SDL_Surface.w+16 int"`
+	}]()
 */
 package std
@@ -0,0 +1,23 @@
+package std
+
+// Error represents a C errno value, where 0 is success and any
+// other value is an error. On Unix systems, Error unwraps to the
+// equivalent [syscall.Errno], such that it can be compared against
+// syscall and io/fs errors with [errors.Is].
+type Error int32
+
+// Errors types, with the values of <errno.h> in the C library of
+// the platform.
+const (
+	ErrDomain              = Error(c_EDOM)
+	ErrIllegalByteSequence = Error(c_EILSEQ)
+	ErrResultTooLarge      = Error(c_ERANGE)
+)
+
+// Err returns nil if err is 0, otherwise it returns err.
+func (err Error) Err() error {
+	if err == 0 {
+		return nil
+	}
+	return err
+}
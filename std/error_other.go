@@ -0,0 +1,25 @@
+//go:build !unix && !windows
+
+package std
+
+import "strconv"
+
+// <errno.h> of the WebAssembly C libraries (wasi-libc and emscripten),
+// which number errors as WASI does.
+const (
+	c_EDOM   = 18
+	c_EILSEQ = 25
+	c_ERANGE = 68
+)
+
+func (err Error) Error() string {
+	switch err {
+	case ErrDomain:
+		return "domain error"
+	case ErrIllegalByteSequence:
+		return "illegal byte sequence"
+	case ErrResultTooLarge:
+		return "result too large"
+	}
+	return "errno " + strconv.Itoa(int(err))
+}
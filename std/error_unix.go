@@ -0,0 +1,18 @@
+//go:build unix
+
+package std
+
+import "syscall"
+
+// the syscall package is generated from the <errno.h> of each Unix
+// system, which is shared with its C library.
+const (
+	c_EDOM   = syscall.EDOM
+	c_EILSEQ = syscall.EILSEQ
+	c_ERANGE = syscall.ERANGE
+)
+
+func (err Error) Error() string { return syscall.Errno(err).Error() }
+
+// Unwrap returns the [syscall.Errno] for err.
+func (err Error) Unwrap() error { return syscall.Errno(err) }
@@ -0,0 +1,23 @@
+package std
+
+import "strconv"
+
+// <errno.h> of the Microsoft C runtime, the syscall.Errno values of
+// windows are unrelated Win32 error codes.
+const (
+	c_EDOM   = 33
+	c_EILSEQ = 42
+	c_ERANGE = 34
+)
+
+func (err Error) Error() string {
+	switch err {
+	case ErrDomain:
+		return "domain error"
+	case ErrIllegalByteSequence:
+		return "illegal byte sequence"
+	case ErrResultTooLarge:
+		return "result too large"
+	}
+	return "errno " + strconv.Itoa(int(err))
+}
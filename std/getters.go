@@ -0,0 +1,197 @@
+package std
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"runtime.link/internal/cconv"
+)
+
+// Getters returns an implementation of the func fields of T, such that
+// each one reads a field of a C struct, without copying the rest of it.
+// The only parameter of each func is a pointer to the struct, usually a
+// [Pointer], [Struct] or [Handle], while the result is the field.
+//
+// The std tag of each func field names the C struct and field, followed
+// by the C type of the field.
+//
+//	Width func(Surface) int32 `std:"SDL_Surface.w+16 int"`
+//
+// The offset of the field (in bytes) follows a '+', otherwise the field
+// is found within the Go struct pointed to by the parameter, either by
+// the name in its std tag, or by its Go name. The C type may be omitted
+// for fields of a Go struct, in which case it is the type of the Go field.
+// Values are converted between C and Go types in the same way as for the
+// arguments and results of C functions.
+//
+// Getters panics if T has a func field that cannot be implemented.
+func Getters[T any]() T {
+	var getters T
+	accessors(reflect.ValueOf(&getters).Elem(), "std.Getters", false)
+	return getters
+}
+
+// Setters is like [Getters], except that each func writes a field of a C
+// struct. The first parameter of each func is a pointer to the struct and
+// the second is the value to write. Strings are copied into C memory that
+// is owned by the [Struct] or [Pointer], so they cannot be written to the
+// fields of other pointers.
+//
+//	SetWidth func(Surface, int32) `std:"SDL_Surface.w+16 int"`
+func Setters[T any]() T {
+	var setters T
+	accessors(reflect.ValueOf(&setters).Elem(), "std.Setters", true)
+	return setters
+}
+
+// accessors implements the func fields of rvalue, either as getters or
+// as setters.
+func accessors(rvalue reflect.Value, name string, set bool) {
+	rtype := rvalue.Type()
+	if rtype.Kind() != reflect.Struct {
+		panic(errorString(name + ": " + rtype.String() + " is not a struct"))
+	}
+	for i := 0; i < rtype.NumField(); i++ {
+		sfield := rtype.Field(i)
+		if sfield.Type.Kind() != reflect.Func {
+			continue
+		}
+		tag, ok := sfield.Tag.Lookup("std")
+		if !ok {
+			continue
+		}
+		fn, err := accessor(sfield.Type, tag, set)
+		if err != nil {
+			panic(errorString(name + ": " + sfield.Name + ": " + err.Error()))
+		}
+		reflect.NewAt(sfield.Type, unsafe.Add(rvalue.Addr().UnsafePointer(), sfield.Offset)).Elem().Set(fn)
+	}
+}
+
+// accessor returns a getter (or a setter) of the func type, for the
+// given std tag.
+func accessor(ftype reflect.Type, tag string, set bool) (reflect.Value, error) {
+	var params = 1
+	if set {
+		params = 2
+	}
+	if ftype.NumIn() != params || ftype.NumOut() != 2-params || ftype.IsVariadic() {
+		if set {
+			return reflect.Value{}, errorString("setters must be a func(ptr, value)")
+		}
+		return reflect.Value{}, errorString("getters must be a func(ptr) value")
+	}
+	vtype := ftype.In(params - 1)
+	if !set {
+		vtype = ftype.Out(0)
+	}
+	base, err := basePointer(ftype.In(0))
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	field, err := fieldOf(ftype.In(0), vtype, tag)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if set {
+		return reflect.MakeFunc(ftype, func(args []reflect.Value) []reflect.Value {
+			m, ptr := base(args[0])
+			val := reflect.New(vtype)
+			val.Elem().Set(args[1])
+			field.set(ptr, val.UnsafePointer(), m, 0)
+			return nil
+		}), nil
+	}
+	return reflect.MakeFunc(ftype, func(args []reflect.Value) []reflect.Value {
+		_, ptr := base(args[0])
+		val := reflect.New(vtype)
+		field.get(val.UnsafePointer(), ptr)
+		return []reflect.Value{val.Elem()}
+	}), nil
+}
+
+// fieldOf returns the field that is named by the tag, within the C struct
+// pointed to by ptype, for Go values of type vtype.
+func fieldOf(ptype, vtype reflect.Type, tag string) (*field, error) {
+	sym, ctype, _ := strings.Cut(strings.TrimSpace(tag), " ")
+	_, name, ok := strings.Cut(sym, ".")
+	if !ok || name == "" {
+		return nil, errorString("std tag " + tag + " must name a struct field, as struct.field")
+	}
+	name, offset, explicit := strings.Cut(name, "+")
+	if explicit {
+		n, err := strconv.ParseUint(offset, 0, 64)
+		if err != nil {
+			return nil, errorString("invalid offset " + offset)
+		}
+		if ctype == "" && (vtype.Kind() != reflect.Struct || cconv.IsMemory(vtype) || vtype.Implements(reflect.TypeFor[IsPointer]())) {
+			return nil, errorString("the C type of the field is missing")
+		}
+		field, err := newField(vtype, ctype)
+		field.offset = uintptr(n)
+		return &field, err
+	}
+	elem, ok := pointee(ptype)
+	if !ok || elem.Kind() != reflect.Struct {
+		return nil, errorString("the offset of " + name + " is unknown, as " + ptype.String() + " does not point to a Go struct")
+	}
+	for _, field := range layoutOf(elem).fields {
+		if field.cname != name && field.name != name {
+			continue
+		}
+		if ctype == "" {
+			field, err := field.as(vtype)
+			return &field, err
+		}
+		retyped, err := newField(vtype, ctype)
+		retyped.offset, retyped.shift = field.offset, field.shift
+		return &retyped, err
+	}
+	return nil, errorString(elem.String() + " has no field " + name)
+}
+
+// pointee returns T for a type defined by a [Pointer], [Struct],
+// [Memory] or [Handle] of T.
+func pointee(ptype reflect.Type) (reflect.Type, bool) {
+	if ptype.Kind() != reflect.Struct || ptype.NumField() == 0 {
+		return nil, false
+	}
+	marker := ptype.Field(0).Type
+	if marker.Kind() != reflect.Array || marker.Len() != 0 || marker.Elem().Kind() != reflect.Pointer {
+		return nil, false
+	}
+	return marker.Elem().Elem(), true
+}
+
+// basePointer returns a func that returns the address of the C struct
+// pointed to by a value of the given type, along with its memory (if
+// it has any).
+func basePointer(ptype reflect.Type) (func(reflect.Value) (*memory, unsafe.Pointer), error) {
+	switch {
+	case ptype.Kind() == reflect.UnsafePointer:
+		return func(v reflect.Value) (*memory, unsafe.Pointer) {
+			return nil, v.UnsafePointer()
+		}, nil
+	case cconv.IsMemory(ptype):
+		return func(v reflect.Value) (*memory, unsafe.Pointer) {
+			m := memoryOf(v)
+			return m, m.unsafePointer()
+		}, nil
+	case ptype.Implements(reflect.TypeFor[IsPointer]()):
+		return func(v reflect.Value) (*memory, unsafe.Pointer) {
+			addr := v.Interface().(IsPointer).Pointer()
+			return nil, *(*unsafe.Pointer)(unsafe.Pointer(&addr))
+		}, nil
+	}
+	return nil, errorString("unsupported pointer type " + ptype.String())
+}
+
+// memoryOf returns the memory of a [Memory], [Pointer] or [Struct].
+func memoryOf(v reflect.Value) *memory {
+	offset, _ := cconv.MemoryOffset(v.Type())
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return *(**memory)(unsafe.Add(ptr.UnsafePointer(), offset))
+}
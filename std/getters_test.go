@@ -0,0 +1,148 @@
+package std_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"runtime.link/std"
+)
+
+type account std.Struct[struct {
+	ID      int32  `std:"id int"`
+	Owner   string `std:"owner &char"`
+	Balance float64
+}]
+
+func TestGetters(t *testing.T) {
+	a, alloc := newAllocator()
+	s := std.NewStruct[struct {
+		ID      int32  `std:"id int"`
+		Owner   string `std:"owner &char"`
+		Balance float64
+	}](alloc)
+	acc := account(s)
+	get := std.Getters[struct {
+		ID      func(account) int     `std:"account.id"`
+		Owner   func(account) string  `std:"account.owner"`
+		Balance func(account) float64 `std:"account.Balance"`
+		Open    func(account) bool    `std:"account.id int"`
+	}]()
+	set := std.Setters[struct {
+		ID      func(account, int)     `std:"account.id"`
+		Owner   func(account, string)  `std:"account.owner"`
+		Balance func(account, float64) `std:"account.Balance"`
+	}]()
+	set.ID(acc, 7)
+	set.Owner(acc, "Alice")
+	set.Balance(acc, 1.5)
+	if get.ID(acc) != 7 || get.Owner(acc) != "Alice" || get.Balance(acc) != 1.5 || !get.Open(acc) {
+		t.Fatal("unexpected fields", get.ID(acc), get.Owner(acc), get.Balance(acc))
+	}
+	if val := s.Get(); val.ID != 7 || val.Owner != "Alice" || val.Balance != 1.5 {
+		t.Fatal("expected the setters to write the C struct", val)
+	}
+	set.Owner(acc, "Bob")
+	if len(a.live) != 2 {
+		t.Fatal("expected the old owner to be freed", len(a.live))
+	}
+	s.Free()
+	if len(a.live) != 0 {
+		t.Fatal("expected the struct and its owner to be freed", len(a.live))
+	}
+	expectPanic(t, std.ErrUseAfterFree, func() { get.ID(acc) })
+}
+
+type surface std.Handle[surface]
+
+type sdlSurface std.Handle[struct {
+	Flags  uint32         `std:"flags uint32_t"`
+	Format unsafe.Pointer `std:"format &void"`
+	W      int32          `std:"w int"`
+	H      int32          `std:"h int"`
+	Pitch  int32          `std:"pitch int"`
+	Pixels unsafe.Pointer `std:"pixels &void"`
+}]
+
+func TestGettersHandle(t *testing.T) {
+	var pixels [4]byte
+	c := struct {
+		flags  uint32
+		format unsafe.Pointer
+		w, h   int32
+		pitch  int32
+		pixels unsafe.Pointer
+	}{w: 640, h: 480, pitch: 2560, pixels: unsafe.Pointer(&pixels)}
+	get := std.Getters[struct {
+		Width  func(sdlSurface) int32          `std:"SDL_Surface.w"`
+		Height func(sdlSurface) int32          `std:"SDL_Surface.h"`
+		Pitch  func(sdlSurface) int            `std:"SDL_Surface.pitch"`
+		Pixels func(sdlSurface) unsafe.Pointer `std:"SDL_Surface.pixels"`
+	}]()
+	var s sdlSurface
+	s.SetPointer(unsafe.Pointer(&c))
+	if get.Width(s) != 640 || get.Height(s) != 480 || get.Pitch(s) != 2560 || get.Pixels(s) != unsafe.Pointer(&pixels) {
+		t.Fatal("unexpected fields", get.Width(s), get.Height(s), get.Pitch(s), get.Pixels(s))
+	}
+}
+
+func TestGettersOffsets(t *testing.T) {
+	var pixels [4]byte
+	c := struct {
+		flags  uint32
+		format unsafe.Pointer
+		w, h   int32
+		pitch  int32
+		pixels unsafe.Pointer
+	}{w: 640, h: 480, pitch: 2560, pixels: unsafe.Pointer(&pixels)}
+	ptr := unsafe.Pointer(&c)
+	get := std.Getters[struct {
+		Width  func(unsafe.Pointer) int32          `std:"SDL_Surface.w+16 int"`
+		Height func(unsafe.Pointer) int64          `std:"SDL_Surface.h+0x14 int"`
+		Pixels func(unsafe.Pointer) unsafe.Pointer `std:"SDL_Surface.pixels+32 &void"`
+		Handle func(unsafe.Pointer) surface        `std:"SDL_Surface.pixels+32 &void"`
+	}]()
+	set := std.Setters[struct {
+		Pitch func(unsafe.Pointer, int16) `std:"SDL_Surface.pitch+24 int"`
+	}]()
+	if get.Width(ptr) != 640 || get.Height(ptr) != 480 || get.Pixels(ptr) != unsafe.Pointer(&pixels) {
+		t.Fatal("unexpected fields", get.Width(ptr), get.Height(ptr), get.Pixels(ptr))
+	}
+	if get.Handle(ptr).Pointer() != uintptr(unsafe.Pointer(&pixels)) {
+		t.Fatal("expected a handle to the pixels")
+	}
+	set.Pitch(ptr, -1)
+	if c.pitch != -1 {
+		t.Fatal("expected the setter to write the field", c.pitch)
+	}
+	for _, fn := range []func(){
+		func() {
+			std.Getters[struct {
+				Width func(unsafe.Pointer) int32 `std:"SDL_Surface.w"`
+			}]()
+		},
+		func() {
+			std.Getters[struct {
+				Width func(unsafe.Pointer) int32 `std:"SDL_Surface.w+16"`
+			}]()
+		},
+		func() {
+			std.Getters[struct {
+				Width func(unsafe.Pointer) string `std:"SDL_Surface.w+16 int"`
+			}]()
+		},
+		func() {
+			std.Setters[struct {
+				Width func(unsafe.Pointer) int32 `std:"SDL_Surface.w+16 int"`
+			}]()
+		},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected an invalid accessor to panic")
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -0,0 +1,277 @@
+// Package header generates C headers from runtime.link library structs.
+//
+// Each function of a library struct is declared with the C prototype that
+// its [std.Tag] describes, along with a comment that records the ownership
+// and safety assertions of the tag. Such a header is a reviewable artifact
+// for C programmers, and when it is compiled after the headers of the
+// library itself, the C compiler checks each tag against the real
+// prototype, as conflicting declarations are an error:
+//
+//	#include <stdio.h>
+//	#include "libc.h" // generated.
+package header
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"runtime.link/std"
+)
+
+// Write a C header to w, that declares the functions of the library struct
+// pointed to by library (including those of nested exported structs). The
+// name of the header is used for its include guard. Nothing is written if
+// any of the tags are invalid, instead an error is returned for each one.
+func Write(w io.Writer, name string, library any) error {
+	rtype := reflect.TypeOf(library)
+	if rtype == nil || rtype.Kind() != reflect.Pointer || rtype.Elem().Kind() != reflect.Struct {
+		return errors.New("header.Write: library must be a pointer to a struct")
+	}
+	guard := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name) + "_H"
+	var (
+		b    bytes.Buffer
+		errs []error
+	)
+	fmt.Fprintf(&b, "/* %s declares the functions of the %s library struct. */\n", name, rtype.Elem().String())
+	fmt.Fprintf(&b, "#ifndef %s\n#define %s\n\n", guard, guard)
+	b.WriteString("#include <stdbool.h>\n#include <stddef.h>\n#include <stdint.h>\n")
+	declare(&b, rtype.Elem(), "", &errs)
+	fmt.Fprintf(&b, "\n#endif /* %s */\n", guard)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func declare(b *bytes.Buffer, rtype reflect.Type, prefix string, errs *[]error) {
+	for i := 0; i < rtype.NumField(); i++ {
+		field := rtype.Field(i)
+		if field.IsExported() && field.Type.Kind() == reflect.Struct {
+			declare(b, field.Type, prefix+field.Name+".", errs)
+		}
+		tag, ok := field.Tag.Lookup("std")
+		if !ok || field.Type.Kind() != reflect.Func {
+			continue
+		}
+		symbols, ctype, err := std.Tag(tag).Parse()
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s%s: %w", prefix, field.Name, err))
+			continue
+		}
+		b.WriteByte('\n')
+		comment(b, prefix+field.Name, std.Tag(tag), symbols, ctype)
+		for _, symbol := range symbols {
+			symbol, _, _ = strings.Cut(symbol, "@")
+			if ctype.Name != "func" || ctype.Func == nil {
+				fmt.Fprintf(b, "extern %s;\n", declaration(ctype, symbol))
+				continue
+			}
+			fmt.Fprintf(b, "%s;\n", declaration(*ctype.Func, symbol+parameters(ctype.Args)))
+		}
+	}
+}
+
+// names of the C types that are abbreviated in tags.
+var names = map[string]string{
+	"schar":   "signed char",
+	"uchar":   "unsigned char",
+	"ushort":  "unsigned short",
+	"uint":    "unsigned int",
+	"ulong":   "unsigned long",
+	"ptrdiff": "ptrdiff_t",
+}
+
+// declaration returns the C declaration of the declarator with type t.
+func declaration(t std.Type, declarator string) string {
+	if t.Name == "func" {
+		// callbacks are function pointers.
+		if t.Func == nil {
+			return declaration(std.Type{Name: "void"}, "(*"+declarator+")()")
+		}
+		return declaration(*t.Func, "(*"+declarator+")"+parameters(t.Args))
+	}
+	name := t.Name
+	if full, ok := names[name]; ok {
+		name = full
+	}
+	switch t.Free {
+	case '$', '&', '+', '*':
+		if t.Hash {
+			name = "const " + name
+		}
+		declarator = "*" + declarator
+	}
+	if declarator == "" {
+		return name
+	}
+	return name + " " + declarator
+}
+
+func parameters(args []std.Type) string {
+	if len(args) == 0 {
+		return "(void)"
+	}
+	var params []string
+	for _, arg := range args {
+		if arg.More {
+			params = append(params, "...")
+			continue
+		}
+		params = append(params, declaration(arg, ""))
+	}
+	return "(" + strings.Join(params, ", ") + ")"
+}
+
+// comment writes a comment that names the field, and records the
+// assertions of the tag that are not apparent from the prototype.
+func comment(b *bytes.Buffer, field string, tag std.Tag, symbols []string, ctype std.Type) {
+	var lines []string
+	if tag.Optional() {
+		lines = append(lines, "optional, the library may not define it.")
+	}
+	for _, symbol := range symbols {
+		if name, version, ok := strings.Cut(symbol, "@"); ok {
+			lines = append(lines, name+" is bound to version "+strings.TrimPrefix(version, "@")+".")
+		}
+	}
+	for i, arg := range ctype.Args {
+		if notes := describe(arg, false); len(notes) > 0 {
+			lines = append(lines, "argument "+strconv.Itoa(i+1)+": "+strings.Join(notes, ", ")+".")
+		}
+	}
+	if ctype.Func != nil {
+		if notes := describe(*ctype.Func, true); len(notes) > 0 {
+			lines = append(lines, "result: "+strings.Join(notes, ", ")+".")
+		}
+	}
+	if ctype.Call.Name != "" {
+		lines = append(lines, "on failure, the reason is given by "+call(ctype.Call)+".")
+	}
+	if len(lines) == 0 {
+		fmt.Fprintf(b, "/* %s */\n", field)
+		return
+	}
+	fmt.Fprintf(b, "/*\n * %s\n *\n", field)
+	for _, line := range lines {
+		fmt.Fprintf(b, " * %s\n", line)
+	}
+	b.WriteString(" */\n")
+}
+
+// describe the ownership and safety assertions of the type.
+func describe(t std.Type, result bool) []string {
+	var notes []string
+	switch {
+	case t.Free == '$' && t.Drop.Name != "" && result:
+		notes = append(notes, "owned by the caller, who must free it with "+t.Drop.Name)
+	case t.Free == '$' && result:
+		notes = append(notes, "owned by the caller, who must free it")
+	case t.Free == '$':
+		notes = append(notes, "ownership is transferred to the function")
+	case t.Drop.Name != "":
+		notes = append(notes, "borrowed until "+call(t.Drop)+" is called")
+	case t.Free == '&' && result:
+		notes = append(notes, "borrowed, the caller must copy it")
+	case t.Free == '&':
+		notes = append(notes, "borrowed for the duration of the call")
+	case t.Free == '+':
+		notes = append(notes, "borrowed to be initialized, any existing value is overwritten")
+	}
+	if t.Name == "func" && t.Free == '$' {
+		notes = append(notes, "the callback remains valid until it is freed")
+	}
+	test := t.Test
+	switch {
+	case test.OfFormat.Check:
+		notes = append(notes, "printf-style arguments for the format in "+argument(test.OfFormat))
+	case test.Overlaps.Check && test.Inverted:
+		notes = append(notes, "must not overlap with "+argument(test.Overlaps))
+	case test.Overlaps.Check:
+		notes = append(notes, "must overlap with "+argument(test.Overlaps))
+	case test.Lifetime.Check:
+		notes = append(notes, "points within "+argument(test.Lifetime)+", so it lives as long as it")
+	case test.SameType.Check:
+		notes = append(notes, "points to the same type as "+argument(test.SameType))
+	default:
+		op, arg := comparison(test)
+		if test.Indirect > 0 {
+			arg = "the size of the value pointed to by " + arg
+		}
+		switch {
+		case op == "":
+		case result:
+			notes = append(notes, "an error when "+op+" "+arg)
+		case test.Capacity:
+			notes = append(notes, "capacity must be "+op+" "+arg)
+		default:
+			notes = append(notes, "must be "+op+" "+arg)
+		}
+	}
+	return notes
+}
+
+// comparison returns the operator and operand of the comparison
+// assertion, if any.
+func comparison(test std.Assertions) (string, string) {
+	var (
+		op  string
+		arg std.Argument
+	)
+	switch {
+	case test.MoreThan.Check:
+		op, arg = ">", test.MoreThan
+	case test.LessThan.Check:
+		op, arg = "<", test.LessThan
+	}
+	if test.Equality.Check {
+		op, arg = op+"=", test.Equality
+	}
+	if op == "" {
+		return "", ""
+	}
+	if test.Inverted {
+		op = "!" + op
+	}
+	return op, argument(arg)
+}
+
+// call returns the C representation of a call.
+func call(call std.Call) string {
+	if call.Name == "errno" && len(call.Args) == 0 {
+		return call.Name
+	}
+	var args []string
+	for _, arg := range call.Args {
+		args = append(args, argument(arg))
+	}
+	return call.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// argument returns the C representation of an assertion argument.
+func argument(arg std.Argument) string {
+	switch {
+	case arg.Maps > 0:
+		return "the argument of Go parameter " + strconv.Itoa(arg.Maps)
+	case arg.Index > 0:
+		return "argument " + strconv.Itoa(int(arg.Index))
+	case arg.Const != "":
+		return arg.Const
+	default:
+		return strconv.FormatInt(arg.Value, 10)
+	}
+}
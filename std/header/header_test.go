@@ -0,0 +1,119 @@
+package header_test
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"runtime.link/std/header"
+)
+
+type libc struct {
+	IO struct {
+		Puts     func(string) int32                                                      `std:"puts func(&#char)int"`
+		Read     func([]byte, uintptr, uintptr, uintptr) uintptr                         `std:"fread func(&void[=@3],size_t*=@1,size_t,&FILE)size_t=@3; ferror(@4)"`
+		Snprintf func([]byte, uintptr, string, ...any) int32                             `std:"snprintf func(&char[>=@2],size_t,&#char,void...?@3)int<0"`
+		Strdup   func(string) unsafe.Pointer                                             `std:"strdup func(&#char)$char"`
+		Fopen    func(string, string) unsafe.Pointer                                     `std:"fopen func(&#char,&#char)$FILE@fclose=NULL; errno"`
+		Memcpy   func(unsafe.Pointer, unsafe.Pointer, uintptr)                           `std:"memcpy func(&void[>=@3],&#void~@1,size_t)&void^@1"`
+		Sort     func(unsafe.Pointer, uintptr, uintptr, func(a, b unsafe.Pointer) int32) `std:"qsort func(&void,size_t,size_t,&func(&#void,&#void)int)void"`
+	}
+	Abs     func(int32) int32     `std:"abs func(int)int"`
+	Sqrt    func(float64) float64 `std:"sqrt func(double)double; errno"`
+	Missing func()                `std:"no_such_function? func()void"`
+	Getpid  func() int32          `std:"getpid@GLIBC_2.2.5 func()int"`
+
+	Untagged func()
+}
+
+func TestWrite(t *testing.T) {
+	var b bytes.Buffer
+	if err := header.Write(&b, "libc.h", new(libc)); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, expected := range []string{
+		"#ifndef LIBC_H_H",
+		"int puts(const char *);",
+		"size_t fread(void *, size_t, size_t, FILE *);",
+		"int snprintf(char *, size_t, const char *, ...);",
+		"char *strdup(const char *);",
+		"void *memcpy(void *, const void *, size_t);",
+		"void qsort(void *, size_t, size_t, int (*)(const void *, const void *));",
+		"int abs(int);",
+		"int getpid(void);",
+		" * IO.Read\n",
+		"argument 1: borrowed for the duration of the call, capacity must be = argument 3.",
+		"argument 2: must be = the size of the value pointed to by argument 1.",
+		"result: an error when = argument 3.",
+		"on failure, the reason is given by ferror(argument 4).",
+		"argument 4: printf-style arguments for the format in argument 3.",
+		"result: an error when < 0.",
+		"result: owned by the caller, who must free it.",
+		"result: owned by the caller, who must free it with fclose, an error when = NULL.",
+		"argument 2: borrowed for the duration of the call, must not overlap with argument 1.",
+		"result: borrowed, the caller must copy it, points within argument 1, so it lives as long as it.",
+		"on failure, the reason is given by errno.",
+		"optional, the library may not define it.",
+		"getpid is bound to version GLIBC_2.2.5.",
+		"/* Abs */",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "Untagged") {
+		t.Error("unexpected untagged field in\n", out)
+	}
+	if err := compile(t, out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteConflict(t *testing.T) {
+	var b bytes.Buffer
+	if err := header.Write(&b, "libc.h", &struct {
+		Abs func(float64) int32 `std:"abs func(double)int"`
+	}{}); err != nil {
+		t.Fatal(err)
+	}
+	err := compile(t, b.String())
+	if err == nil || !strings.Contains(err.Error(), "conflicting types") {
+		t.Fatal("expected conflicting types, got", err)
+	}
+}
+
+func TestWriteInvalid(t *testing.T) {
+	var b bytes.Buffer
+	err := header.Write(&b, "libc.h", &struct {
+		Bad struct {
+			Func func() `std:"func"`
+		}
+	}{})
+	if err == nil || !strings.HasPrefix(err.Error(), "Bad.Func: ") {
+		t.Fatal("expected an error for Bad.Func, got", err)
+	}
+	if b.Len() > 0 {
+		t.Fatal("expected nothing to be written")
+	}
+}
+
+// compile the header after the headers of libc, so that the C compiler
+// checks it against the real prototypes.
+func compile(t *testing.T, src string) error {
+	t.Helper()
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("no C compiler available")
+	}
+	cmd := exec.Command(cc, "-fsyntax-only", "-x", "c", "-")
+	cmd.Stdin = strings.NewReader("#include <stdio.h>\n#include <stdlib.h>\n#include <string.h>\n#include <math.h>\n#include <unistd.h>\n" + src)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.New(string(out))
+	}
+	return nil
+}
@@ -0,0 +1,523 @@
+package std
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"runtime.link/internal/cconv"
+)
+
+// Layout of a C struct for the C ABI of the current platform, as computed
+// by [LayoutOf] from the fields of a Go struct.
+type Layout struct {
+	Size   uintptr
+	Align  uintptr
+	Packed bool
+	Fields []FieldLayout
+
+	layout *layout
+}
+
+// FieldLayout is the location of a field within a C struct.
+type FieldLayout struct {
+	Name   string  // of the Go field.
+	Type   string  // C type of the field, empty if it is the Go type.
+	Offset uintptr // in bytes, from the start of the struct.
+	Size   uintptr // of the C type, in bytes.
+	Align  uintptr // of the C type, in bytes.
+	Bits   int     // width of a bitfield, zero for other fields.
+	Shift  int     // of a bitfield, in bits from the start of the byte at Offset.
+}
+
+// LayoutOf returns the C layout of the struct T, as described by the std
+// tags of its fields. Each tag names the C field, followed by its C type,
+// with the width of a bitfield following a ':'.
+//
+//	Flags uint8 `std:"flags uint:3"`
+//
+// Fields without a C type, or with a C type that is not a standard one,
+// are laid out as their Go type. A blank field tagged as packed removes
+// the padding between fields, as if the C struct were packed.
+//
+//	_ struct{} `std:"packed"`
+//
+// A [Struct] of T is converted to and from this layout, such that it does
+// not matter whether it is the same as the Go layout of T. Otherwise, see
+// [Layout.Mismatch].
+func LayoutOf[T any]() (Layout, error) {
+	rtype := reflect.TypeFor[T]()
+	if rtype.Kind() != reflect.Struct {
+		return Layout{}, errorString("std.LayoutOf: " + rtype.String() + " is not a struct")
+	}
+	l, err := compileLayout(rtype)
+	if err != nil {
+		return Layout{}, err
+	}
+	layout := Layout{Size: l.size, Align: l.align, Packed: l.packed, layout: l}
+	for _, f := range l.fields {
+		layout.Fields = append(layout.Fields, FieldLayout{
+			Name:   f.name,
+			Type:   f.ctype,
+			Offset: f.offset,
+			Size:   f.layout.size,
+			Align:  f.layout.align,
+			Bits:   int(f.bits),
+			Shift:  int(f.shift),
+		})
+	}
+	return layout, nil
+}
+
+func init() {
+	cconv.Layout = func(rtype reflect.Type) (size, align uintptr, fields []cconv.Field, err error) {
+		if rtype.Kind() != reflect.Struct {
+			return 0, 0, nil, errorString("std: " + rtype.String() + " is not a struct")
+		}
+		l, err := compileLayout(rtype)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		for _, f := range l.fields {
+			fields = append(fields, cconv.Field{GoOffset: f.goffset, Offset: f.offset, Size: f.layout.size, Bits: int(f.bits)})
+		}
+		return l.size, l.align, fields, nil
+	}
+}
+
+// Mismatch returns an error describing the first difference between the
+// C layout and the Go layout, or nil if values can be passed between Go
+// and C as they are.
+func (l Layout) Mismatch() error {
+	if l.layout == nil {
+		return nil
+	}
+	return l.layout.mismatch(l.layout.rtype.String())
+}
+
+// mismatch returns an error describing the first difference between the
+// C and Go layouts of the value with the given name.
+func (l *layout) mismatch(name string) error {
+	if l.plain {
+		return nil
+	}
+	switch l.rtype.Kind() {
+	case reflect.String:
+		return errorString(name + " is a C char *, but a Go string")
+	case reflect.Array:
+		return l.elem.mismatch(name + "[0]")
+	case reflect.Struct:
+		for _, f := range l.fields {
+			fname := name + "." + f.name
+			switch {
+			case f.bitfield:
+				return errorString(fname + " is a bitfield in C")
+			case !f.direct:
+				return errorString(fname + " is a C " + f.ctype + ", but a Go " + f.rtype.String())
+			case f.offset != f.goffset:
+				return errorString(fname + " is at offset " + strconv.Itoa(int(f.offset)) + " in C, but " +
+					strconv.Itoa(int(f.goffset)) + " in Go")
+			}
+			if err := f.layout.mismatch(fname); err != nil {
+				return err
+			}
+		}
+		return errorString(name + " is " + strconv.Itoa(int(l.size)) + " bytes in C, but " +
+			strconv.Itoa(int(l.rtype.Size())) + " in Go")
+	}
+	return errorString(name + " has a different layout in C")
+}
+
+// msvc is true when bitfields are allocated as they are by the Microsoft
+// C compiler, rather than as they are by the System V ABI.
+const msvc = runtime.GOOS == "windows"
+
+// long is the representation of a C long, which is only 64 bits on 64-bit
+// platforms other than Windows.
+func long(signed bool) reflect.Type {
+	switch {
+	case msvc || unsafe.Sizeof(uintptr(0)) == 4:
+		if signed {
+			return reflect.TypeFor[int32]()
+		}
+		return reflect.TypeFor[uint32]()
+	case signed:
+		return reflect.TypeFor[int64]()
+	default:
+		return reflect.TypeFor[uint64]()
+	}
+}
+
+// scalars are the Go representations of the standard C types.
+var scalars = map[string]reflect.Type{
+	"bool": reflect.TypeFor[bool](), "char": reflect.TypeFor[int8](),
+	"schar": reflect.TypeFor[int8](), "uchar": reflect.TypeFor[uint8](),
+	"short": reflect.TypeFor[int16](), "ushort": reflect.TypeFor[uint16](),
+	"int": reflect.TypeFor[int32](), "uint": reflect.TypeFor[uint32](),
+	"long": long(true), "ulong": long(false),
+	"int8_t": reflect.TypeFor[int8](), "int16_t": reflect.TypeFor[int16](),
+	"int32_t": reflect.TypeFor[int32](), "int64_t": reflect.TypeFor[int64](),
+	"uint8_t": reflect.TypeFor[uint8](), "uint16_t": reflect.TypeFor[uint16](),
+	"uint32_t": reflect.TypeFor[uint32](), "uint64_t": reflect.TypeFor[uint64](),
+	"size_t": reflect.TypeFor[uintptr](), "ptrdiff": reflect.TypeFor[int](),
+	"ptrdiff_t": reflect.TypeFor[int](), "intptr_t": reflect.TypeFor[int](),
+	"uintptr_t": reflect.TypeFor[uintptr](),
+	"float":     reflect.TypeFor[float32](),
+	"double":    reflect.TypeFor[float64](),
+}
+
+// representation returns the Go representation of the C type, pointers
+// to char are C strings.
+func representation(t Type) (reflect.Type, bool) {
+	if t.Free != 0 || t.Name == "func" {
+		if t.Name == "char" {
+			return reflect.TypeFor[string](), true
+		}
+		return reflect.TypeFor[unsafe.Pointer](), true
+	}
+	rtype, ok := scalars[t.Name]
+	return rtype, ok
+}
+
+// sameRepresentation reports whether values of the Go type can be copied
+// to and from the C representation as they are.
+func sameRepresentation(repr, rtype reflect.Type) bool {
+	switch {
+	case repr == rtype:
+		return true
+	case repr.Kind() == reflect.UnsafePointer:
+		_, handle := cconv.HandleOffset(rtype)
+		return rtype.Kind() == reflect.UnsafePointer || rtype.Kind() == reflect.Pointer ||
+			rtype.Kind() == reflect.Uintptr || handle && rtype.Size() == repr.Size()
+	case repr.Kind() == reflect.String, repr.Kind() == reflect.Struct, repr.Kind() == reflect.Array:
+		return false
+	}
+	return repr.Kind() == rtype.Kind()
+}
+
+// layout of a Go type in C memory.
+type layout struct {
+	rtype  reflect.Type
+	size   uintptr
+	align  uintptr
+	plain  bool    // the same layout in Go and C.
+	packed bool    // of a struct, without padding.
+	fields []field // of a struct.
+	elem   *layout // of an array.
+}
+
+// field of a C struct, for Go values of type rtype.
+type field struct {
+	name     string
+	cname    string  // from the std tag (if any).
+	ctype    string  // from the std tag (if any).
+	goffset  uintptr // of the Go field.
+	offset   uintptr // in C memory.
+	rtype    reflect.Type
+	layout   *layout // of the C representation.
+	direct   bool    // the C and Go representations are the same.
+	bitfield bool
+	bits     uintptr // width of the bitfield.
+	shift    uintptr // of the bitfield, within the byte at offset.
+}
+
+var layouts sync.Map // map[reflect.Type]*layout
+
+// layoutOf returns the C layout of the Go type, it panics if the type
+// cannot be represented in C.
+func layoutOf(rtype reflect.Type) *layout {
+	l, err := compileLayout(rtype)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// compileLayout computes the C layout of the Go type.
+func compileLayout(rtype reflect.Type) (*layout, error) {
+	if cached, ok := layouts.Load(rtype); ok {
+		return cached.(*layout), nil
+	}
+	l := &layout{rtype: rtype, size: rtype.Size(), align: uintptr(rtype.Align()), plain: true}
+	switch rtype.Kind() {
+	case reflect.Int64, reflect.Uint64, reflect.Float64, reflect.Complex128:
+		if runtime.GOARCH == "arm" {
+			l.align = 8 // unlike Go, the ARM EABI aligns 64-bit values.
+		}
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uintptr,
+		reflect.Float32, reflect.Complex64, reflect.Pointer, reflect.UnsafePointer:
+	case reflect.String:
+		l.size, l.align, l.plain = unsafe.Sizeof(uintptr(0)), unsafe.Alignof(uintptr(0)), false
+	case reflect.Array:
+		elem, err := compileLayout(rtype.Elem())
+		if err != nil {
+			return nil, err
+		}
+		l.elem = elem
+		l.size, l.align, l.plain = elem.size*uintptr(rtype.Len()), elem.align, elem.plain && elem.size == rtype.Elem().Size()
+	case reflect.Struct:
+		if err := l.compileStruct(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errorString("std: " + rtype.String() + " cannot be represented in C")
+	}
+	cached, _ := layouts.LoadOrStore(rtype, l)
+	return cached.(*layout), nil
+}
+
+// compileStruct lays out the fields of the struct, bitfields are allocated
+// from the least significant bit of each byte.
+func (l *layout) compileStruct() error {
+	rtype := l.rtype
+	l.size, l.align = 0, 1
+	for i := 0; i < rtype.NumField(); i++ {
+		if sfield := rtype.Field(i); sfield.Type.Size() == 0 && sfield.Tag.Get("std") == "packed" {
+			l.packed = true
+		}
+	}
+	var (
+		pos  uintptr // in bits.
+		unit uintptr // end of the current bitfield allocation unit (msvc), in bits.
+		size uintptr // of the current allocation unit (msvc), in bits.
+	)
+	for i := 0; i < rtype.NumField(); i++ {
+		sfield := rtype.Field(i)
+		if sfield.Type.Size() == 0 {
+			continue
+		}
+		cname, ctype, _ := strings.Cut(sfield.Tag.Get("std"), " ")
+		f, err := newField(sfield.Type, ctype)
+		if err != nil {
+			return errorString(rtype.String() + "." + sfield.Name + ": " + err.Error())
+		}
+		f.name, f.cname, f.goffset = sfield.Name, cname, sfield.Offset
+		align := f.layout.align
+		if l.packed {
+			align = 1
+		}
+		switch {
+		case !f.bitfield:
+			pos, unit = alignUp(max(pos, unit), align*8), 0
+			f.offset = pos / 8
+			pos += f.layout.size * 8
+		case msvc:
+			if f.bits == 0 {
+				pos, unit = max(pos, unit), 0
+				continue
+			}
+			if unit == 0 || size != f.layout.size*8 || pos+f.bits > unit {
+				pos = alignUp(max(pos, unit), align*8)
+				unit, size = pos+f.layout.size*8, f.layout.size*8
+			}
+		default:
+			if f.bits == 0 {
+				pos = alignUp(pos, align*8)
+				continue
+			}
+			if n := f.layout.size * 8; !l.packed && pos/n != (pos+f.bits-1)/n {
+				pos = alignUp(pos, n) // bitfields do not straddle their type.
+			}
+		}
+		if f.bitfield {
+			f.offset, f.shift = pos/8, pos%8
+			pos += f.bits
+		}
+		l.align = max(l.align, align)
+		l.plain = l.plain && f.direct && !f.bitfield && f.layout.plain && f.offset == sfield.Offset
+		l.fields = append(l.fields, f)
+	}
+	l.size = alignUp((max(pos, unit)+7)/8, l.align)
+	l.plain = l.plain && l.size == rtype.Size()
+	return nil
+}
+
+// alignUp rounds n up to a multiple of align.
+func alignUp(n, align uintptr) uintptr {
+	return (n + align - 1) &^ (align - 1)
+}
+
+// newField returns a field for Go values of type rtype, with the given C type
+// (and bitfield width), or with the C layout of rtype if there is no C type.
+func newField(rtype reflect.Type, ctype string) (field, error) {
+	ctype, width, bitfield := strings.Cut(ctype, ":")
+	f := field{ctype: ctype, bitfield: bitfield}
+	if ctype != "" {
+		_, stype, err := Tag("field " + ctype).Parse()
+		if err != nil {
+			return f, err
+		}
+		if repr, ok := representation(stype); ok {
+			if f.layout, err = compileLayout(repr); err != nil {
+				return f, err
+			}
+		}
+	}
+	if f.layout == nil {
+		var err error
+		if f.layout, err = compileLayout(rtype); err != nil {
+			return f, err
+		}
+	}
+	if bitfield {
+		bits, err := strconv.ParseUint(width, 10, 8)
+		switch f.layout.rtype.Kind() {
+		case reflect.Bool, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int,
+			reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint, reflect.Uintptr:
+		default:
+			err = errorString("bitfields must be integers")
+		}
+		if err != nil || uintptr(bits) > f.layout.size*8 {
+			return f, errorString("invalid bitfield " + ctype + ":" + width)
+		}
+		f.bits = uintptr(bits)
+	}
+	return f.as(rtype)
+}
+
+// as returns the field for Go values of type rtype.
+func (f field) as(rtype reflect.Type) (field, error) {
+	if !cconv.Convertible(f.layout.rtype, rtype) {
+		return f, errorString("cannot convert between " + rtype.String() + " and the C type " + f.layout.rtype.String())
+	}
+	f.rtype, f.direct = rtype, sameRepresentation(f.layout.rtype, rtype)
+	return f, nil
+}
+
+// get copies the field of the C struct at src into the Go value at dst.
+func (f *field) get(dst, src unsafe.Pointer) {
+	src = unsafe.Add(src, f.offset)
+	switch {
+	case f.bitfield:
+		var bits uint64
+		for i := uintptr(0); i < f.bits; i++ {
+			at := f.shift + i
+			bits |= uint64(*(*byte)(unsafe.Add(src, at/8))>>(at%8)&1) << i
+		}
+		val := reflect.New(f.layout.rtype).Elem()
+		switch {
+		case val.Kind() == reflect.Bool:
+			val.SetBool(bits != 0)
+		case val.CanInt():
+			if f.bits < 64 && bits>>(f.bits-1)&1 != 0 {
+				bits |= ^uint64(0) << f.bits
+			}
+			val.SetInt(int64(bits))
+		default:
+			val.SetUint(bits)
+		}
+		cconv.Convert(reflect.NewAt(f.rtype, dst).Elem(), val)
+	case f.direct:
+		f.layout.toGo(dst, src)
+	default:
+		val := reflect.New(f.layout.rtype)
+		f.layout.toGo(val.UnsafePointer(), src)
+		cconv.Convert(reflect.NewAt(f.rtype, dst).Elem(), val.Elem())
+	}
+}
+
+// set copies the Go value at src into the field of the C struct at dst,
+// which is at the given offset within m.
+func (f *field) set(dst, src unsafe.Pointer, m *memory, offset uintptr) {
+	dst, offset = unsafe.Add(dst, f.offset), offset+f.offset
+	switch {
+	case f.bitfield:
+		val := reflect.New(f.layout.rtype).Elem()
+		cconv.Convert(val, reflect.NewAt(f.rtype, src).Elem())
+		var bits uint64
+		switch {
+		case val.Kind() == reflect.Bool:
+			if val.Bool() {
+				bits = 1
+			}
+		case val.CanInt():
+			bits = uint64(val.Int())
+		default:
+			bits = val.Uint()
+		}
+		for i := uintptr(0); i < f.bits; i++ {
+			at := f.shift + i
+			b := (*byte)(unsafe.Add(dst, at/8))
+			*b = *b&^(1<<(at%8)) | byte(bits>>i&1)<<(at%8)
+		}
+	case f.direct:
+		f.layout.toC(dst, src, m, offset)
+	default:
+		val := reflect.New(f.layout.rtype)
+		cconv.Convert(val.Elem(), reflect.NewAt(f.rtype, src).Elem())
+		f.layout.toC(dst, val.UnsafePointer(), m, offset)
+	}
+}
+
+// toGo copies the C value at src into the Go value at dst.
+func (l *layout) toGo(dst, src unsafe.Pointer) {
+	switch {
+	case l.plain:
+		copy(unsafe.Slice((*byte)(dst), l.size), unsafe.Slice((*byte)(src), l.size))
+	case l.rtype.Kind() == reflect.String:
+		*(*string)(dst) = goString(*(*unsafe.Pointer)(src))
+	case l.rtype.Kind() == reflect.Array:
+		for i := 0; i < l.rtype.Len(); i++ {
+			l.elem.toGo(unsafe.Add(dst, uintptr(i)*l.rtype.Elem().Size()), unsafe.Add(src, uintptr(i)*l.elem.size))
+		}
+	default:
+		for i := range l.fields {
+			f := &l.fields[i]
+			f.get(unsafe.Add(dst, f.goffset), src)
+		}
+	}
+}
+
+// toC copies the Go value at src into the C value at dst, which is at
+// the given offset within m. Strings are allocated with the allocator
+// of m, such that they are freed along with m.
+func (l *layout) toC(dst, src unsafe.Pointer, m *memory, offset uintptr) {
+	switch {
+	case l.plain:
+		copy(unsafe.Slice((*byte)(dst), l.size), unsafe.Slice((*byte)(src), l.size))
+	case l.rtype.Kind() == reflect.String:
+		if m == nil || m.alloc == nil {
+			panic(ErrNoAllocator)
+		}
+		if old, ok := m.strings[offset]; ok {
+			m.alloc.Free(old)
+		}
+		s := *(*string)(src)
+		ptr := m.alloc.Allocate(1, uintptr(len(s))+1)
+		if ptr == nil {
+			panic(ErrOutOfMemory)
+		}
+		copy(unsafe.Slice((*byte)(ptr), len(s)), s)
+		if m.strings == nil {
+			m.strings = make(map[uintptr]unsafe.Pointer)
+		}
+		m.strings[offset] = ptr
+		*(*unsafe.Pointer)(dst) = ptr
+	case l.rtype.Kind() == reflect.Array:
+		for i := 0; i < l.rtype.Len(); i++ {
+			at := uintptr(i) * l.elem.size
+			l.elem.toC(unsafe.Add(dst, at), unsafe.Add(src, uintptr(i)*l.rtype.Elem().Size()), m, offset+at)
+		}
+	default:
+		for i := range l.fields {
+			f := &l.fields[i]
+			f.set(dst, unsafe.Add(src, f.goffset), m, offset)
+		}
+	}
+}
+
+// goString copies the null-terminated C string at ptr.
+func goString(ptr unsafe.Pointer) string {
+	if ptr == nil {
+		return ""
+	}
+	var n int
+	for *(*byte)(unsafe.Add(ptr, n)) != 0 {
+		n++
+	}
+	return string(unsafe.Slice((*byte)(ptr), n))
+}
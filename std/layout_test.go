@@ -0,0 +1,118 @@
+package std_test
+
+import (
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"runtime.link/std"
+)
+
+type flags struct {
+	A uint8  `std:"a uint:3"`
+	B int    `std:"b int:4"`
+	C bool   `std:"c bool:1"`
+	D uint16 `std:"d ushort"`
+}
+
+type packed struct {
+	_ struct{} `std:"packed"`
+	A uint8
+	B uint32
+	C uint16
+}
+
+type straddle struct {
+	A int8  `std:"a char"`
+	B int64 `std:"b int64_t:40"`
+	C int32 `std:"c int:30"`
+}
+
+func TestLayout(t *testing.T) {
+	if runtime.GOOS == "windows" || unsafe.Sizeof(uintptr(0)) != 8 {
+		t.Skip("expected layouts are for 64-bit System V platforms")
+	}
+	check := func(name string, layout std.Layout, err error, size, align uintptr, offsets ...uintptr) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if layout.Size != size || layout.Align != align || len(layout.Fields) != len(offsets) {
+			t.Fatal(name, "unexpected layout", layout.Size, layout.Align, len(layout.Fields))
+		}
+		for i, field := range layout.Fields {
+			if field.Offset != offsets[i] {
+				t.Fatal(name, "unexpected offset of", field.Name, field.Offset)
+			}
+		}
+	}
+	layout, err := std.LayoutOf[flags]()
+	check("flags", layout, err, 4, 4, 0, 0, 0, 2)
+	if b := layout.Fields[1]; b.Type != "int" || b.Bits != 4 || b.Shift != 3 || layout.Fields[2].Shift != 7 {
+		t.Fatal("unexpected bitfield", b)
+	}
+	if layout.Mismatch() == nil {
+		t.Fatal("expected bitfields to mismatch")
+	}
+	layout, err = std.LayoutOf[packed]()
+	check("packed", layout, err, 7, 1, 0, 1, 5)
+	if !layout.Packed || layout.Mismatch() == nil {
+		t.Fatal("expected a packed layout, that mismatches")
+	}
+	layout, err = std.LayoutOf[straddle]()
+	check("straddle", layout, err, 16, 8, 0, 1, 8)
+	if c := layout.Fields[2]; c.Shift != 0 || c.Bits != 30 {
+		t.Fatal("expected c to start a new int, as it would straddle one", c)
+	}
+	layout, err = std.LayoutOf[point]()
+	check("point", layout, err, 8, 4, 0, 4)
+	if err := layout.Mismatch(); err != nil {
+		t.Fatal(err)
+	}
+	layout, err = std.LayoutOf[person]()
+	check("person", layout, err, 40, 8, 0, 8, 16, 32)
+	if err := layout.Mismatch(); err == nil || err.Error() != "std_test.person.Name is a C char *, but a Go string" {
+		t.Fatal("unexpected mismatch", err)
+	}
+	if _, err := std.LayoutOf[struct {
+		A float32 `std:"a float:3"`
+	}](); err == nil {
+		t.Fatal("expected a float bitfield to be invalid")
+	}
+	if _, err := std.LayoutOf[struct {
+		A uint8 `std:"a uint8_t:9"`
+	}](); err == nil {
+		t.Fatal("expected a bitfield wider than its type to be invalid")
+	}
+}
+
+func TestLayoutStruct(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("expected bytes are for System V bitfields")
+	}
+	_, alloc := newAllocator()
+	s := std.NewStruct[flags](alloc)
+	defer s.Free()
+	val := flags{A: 5, B: -3, C: true, D: 0xBEEF}
+	s.Set(val)
+	if s.Get() != val {
+		t.Fatal("unexpected struct", s.Get())
+	}
+	if c := *(*[4]byte)(s.UnsafePointer()); c != [4]byte{0xed, 0x00, 0xef, 0xbe} {
+		t.Fatal("unexpected C struct", c)
+	}
+	b := std.FieldOf[int](s, "B")
+	b.Set(7)
+	if b.Get() != 7 || s.Get().A != 5 || !s.Get().C {
+		t.Fatal("expected the bitfield to be written without its neighbours", s.Get())
+	}
+	p := std.NewStruct[packed](alloc)
+	defer p.Free()
+	p.Set(packed{A: 1, B: 0x02030405, C: 0x0607})
+	if c := *(*[7]byte)(p.UnsafePointer()); c != [7]byte{1, 5, 4, 3, 2, 7, 6} {
+		t.Fatal("unexpected packed C struct", c)
+	}
+	if p.Get() != (packed{A: 1, B: 0x02030405, C: 0x0607}) {
+		t.Fatal("unexpected packed struct", p.Get())
+	}
+}
@@ -72,13 +72,13 @@ type LibraryMath struct {
 	Cos   func(x float64) float64             `std:"double cos(double)"`
 	Tan   func(x float64) float64             `std:"double tan(double)"`
 	Asin  func(x float64) float64             `std:"double asin(double)"`
-	Atan2 func(x, y float64) float64          `std:"double atan2(double)"`
-	Sinh  func(x float64) float64             `std:"double sinh(double,double)"`
+	Atan2 func(x, y float64) float64          `std:"double atan2(double,double)"`
+	Sinh  func(x float64) float64             `std:"double sinh(double)"`
 	Cosh  func(x float64) float64             `std:"double cosh(double)"`
 	Tanh  func(x float64) float64             `std:"double tanh(double)"`
 	Exp   func(x float64) float64             `std:"double exp(double)"`
 	Log   func(x float64) float64             `std:"double log(double)"`
-	Log10 func(x float64) float64             `std:"double log10(double,double)"`
+	Log10 func(x float64) float64             `std:"double log10(double)"`
 	Pow   func(x, y float64) float64          `std:"double pow(double,double)"`
 	Sqrt  func(x float64) float64             `std:"double sqrt(double)"`
 	Ceil  func(x float64) float64             `std:"double ceil(double)"`
@@ -88,16 +88,16 @@ type LibraryMath struct {
 	Frexp func(x float64, exp *int32) float64 `std:"double frexp(double,&int)"`
 	Modf  func(x float64, y *float64) float64 `std:"double modf(double,&double)"`
 
-	Rand     func() int32            `std:"int rand"`
-	SeedRand func(seed uint32) int32 `std:"int srand(unsigned_int)"`
+	Rand     func() int32      `std:"int rand(void)"`
+	SeedRand func(seed uint32) `std:"void srand(uint)"`
 }
 
 // LibraryJumps provides the functions from <setjmp.h>.
 type LibraryJumps struct {
 	location
 
-	Set  func(env JumpBuffer) error            `std:"int setjmp(&void)"`
-	Long func(env JumpBuffer, err error) error `std:"int longjmp(&void,int)"`
+	Set  func(env JumpBuffer) error      `std:"int setjmp(&void)"`
+	Long func(env JumpBuffer, val int32) `std:"void longjmp(&void,int)"`
 }
 
 type Signal int
@@ -106,13 +106,16 @@ type Signal int
 type LibrarySignals struct {
 	location
 
-	Handle func(sig Signal, handler func(Signal)) `std:"-void signal(int,void)"`
+	Handle func(sig Signal, handler func(Signal)) `std:"-void signal(int,$func(int)void)"`
 	Raise  func(sig Signal) error                 `std:"int raise(int)"`
 }
 
 type JumpBuffer unsafe.Pointer
+
+// Date points to a C struct tm.
+type Date unsafe.Pointer
 type (
-	File         Handle[File]
+	File         Memory[File]
 	FilePosition unsafe.Pointer
 )
 
@@ -126,117 +129,126 @@ type BufferMode int
 type LibraryFiles struct {
 	location
 
-	Open     func(filename string, mode string) *File               `std:"$void fopen(&char,&char)"`
-	Reopen   func(filename string, mode string, stream *File) *File `std:"$void freopen(&char,&char,#void)"`
-	Flush    func(stream *File) error                               `std:"int fflush(&void)"`
-	Close    func(stream *File) error                               `std:"int fclose(#void)"`
-	Remove   func(filename string) error                            `std:"int remove(&char)"`
-	Rename   func(oldname, newname string) error                    `std:"int rename(&char,&char)"`
-	Temp     func() *File                                           `std:"$void tmpfile"`
-	TempName func(*[TempNameLength]byte) string                     `std:"char tmpnam(&char)"`
-
-	SetBufferMode func(stream *File, buf *[BufferSize]byte, mode BufferMode) error `std:"int setvbuf(&void,free@fclose{%v},int,size_t%[2]v)"`
-	SetBuffer     func(stream *File, buf *[BufferSize]byte) error                  `std:"int setbuf(&void,free@fclose{%v})"`
-
-	Printf    func(stream *File, format string, args ...any) (int, error) `std:"int<0 fprintf(&void,&char,&varg%[1]v...)"`
-	Scanf     func(stream *File, format string, args ...any) (int, error) `std:"int<0 fscanf(&void,&char,&varg%[1]v...)"`
-	GetChar   func(stream *File) rune                                     `std:"int fgetc(&void)"`
-	GetString func(s []byte, stream *File) string                         `std:"char fgets(&char,int,&void)"`
-	PutChar   func(c rune, stream *File) rune                             `std:"int fputc(int,&void)"`
-	Unget     func(c rune, stream *File) rune                             `std:"int ungetc(int,&void)"`
-
-	Read  func(ptr []byte, stream *File) int `std:"int fread(&void,size_t=1,size_t%[1]v,&void)"`
-	Write func(ptr []byte, stream *File) int `std:"int fwrite(&void,size_t=1,size_t%[1]v,&void)"`
-
-	Seek func(stream *File, offset int, origin SeekMode) error `std:"int fseek(&void,long,int)"`
-	Tell func(stream *File) int                                `std:"long ftell(&void)"`
-
-	Rewind func(stream *File) error                    `std:"int rewind(&void)"`
-	GetPos func(stream *File, ptr *FilePosition) error `std:"int fgetpos(&void,&void)"`
-	SetPos func(stream *File, ptr *FilePosition) error `std:"int fsetpos(&void,&void)"`
-
-	ClearError func(stream *File)      `std:"clearerr(&void)"`
-	IsEOF      func(stream *File) bool `std:"int feof(&void)"`
-	Error      func(stream *File) bool `std:"int ferror(&void)"`
+	Open     func(filename string, mode string) File              `std:"$FILE@fclose fopen(&char,&char)"`
+	Reopen   func(filename string, mode string, stream File) File `std:"$FILE@fclose freopen(&char,&char,$FILE)"`
+	Flush    func(stream File) error                              `std:"int fflush(&void)"`
+	Close    func(stream File) error                              `std:"int fclose($FILE)"`
+	Remove   func(filename string) error                          `std:"int remove(&char)"`
+	Rename   func(oldname, newname string) error                  `std:"int rename(&char,&char)"`
+	Temp     func() File                                          `std:"$FILE@fclose tmpfile(void)"`
+	TempName func(*[TempNameLength]byte) string                   `std:"&char tmpnam(&char)"`
+
+	SetBufferMode func(stream File, buf *[BufferSize]byte, mode BufferMode, size int) error `std:"int setvbuf(&void,free@fclose{%[1]v}void,int,size_t)"`
+	SetBuffer     func(stream File, buf *[BufferSize]byte)                                  `std:"void setbuf(&void,free@fclose{%[1]v}void)"`
+
+	Printf    func(stream File, format string, args ...any) (int, error) `std:"int<0 fprintf(&void,&char,void...)"`
+	Scanf     func(stream File, format string, args ...any) (int, error) `std:"int<0 fscanf(&void,&char,void...)"`
+	GetChar   func(stream File) rune                                     `std:"int fgetc(&void)"`
+	GetString func(s []byte, n int32, stream File) string                `std:"&char fgets(&char[>=@2],int,&void)"`
+	PutChar   func(c rune, stream File) rune                             `std:"int fputc(int,&void)"`
+	Unget     func(c rune, stream File) rune                             `std:"int ungetc(int,&void)"`
+
+	Read  func(ptr []byte, size, n int, stream File) int `std:"size_t fread(&void,size_t,size_t,&void)"`
+	Write func(ptr []byte, size, n int, stream File) int `std:"size_t fwrite(&void,size_t,size_t,&void)"`
+
+	Seek func(stream File, offset int, origin SeekMode) error `std:"int fseek(&void,long,int)"`
+	Tell func(stream File) int                                `std:"long ftell(&void)"`
+
+	Rewind func(stream File) error                   `std:"void rewind(&void)"`
+	GetPos func(stream File, ptr FilePosition) error `std:"int fgetpos(&void,&void)"`
+	SetPos func(stream File, ptr FilePosition) error `std:"int fsetpos(&void,&void)"`
+
+	ClearError func(stream File)      `std:"void clearerr(&void)"`
+	IsEOF      func(stream File) bool `std:"int feof(&void)"`
+	Error      func(stream File) bool `std:"int ferror(&void)"`
 }
 
 // LibraryIO provides stdin/stdout functions from <stdio.h>.
 type LibraryIO struct {
 	location
 
-	Printf    func(format string, args ...any) (int, error) `std:"int<0 printf(&char,&varg%v...)"`
-	Scanf     func(format string, args ...any) (int, error) `std:"int<0 scanf(&char,&varg%v...)"`
-	GetChar   func() rune                                   `std:"int getchar"`
-	GetString func(s unsafe.Pointer) string                 `std:"char gets"`
+	Printf    func(format string, args ...any) (int, error) `std:"int<0 printf(&char,void...)"`
+	Scanf     func(format string, args ...any) (int, error) `std:"int<0 scanf(&char,void...)"`
+	GetChar   func() rune                                   `std:"int getchar(void)"`
+	GetString func(s unsafe.Pointer) string                 `std:"&char gets(&char)"`
 	PutChar   func(c rune) error                            `std:"int<0 putchar(int)"`
 	PutString func(s string) error                          `std:"int<0 puts(&char)"`
 
-	Error func(s string) `sym:"perror(&char)"`
+	Error func(s string) `std:"void perror(&char)"`
 }
 
 // LibraryStrings provides string-related functions from <string.h>, <stdio.h> and <stdlib.h>.
 type LibraryStrings struct {
 	location
 
-	Printf func(s unsafe.Pointer, fmt string, args ...any) (int, error) `std:"int<0 sprintf(&char,&char,&varg%v...)"`
-	Scanf  func(s, fmt string, args ...any) (int, error)                `std:"int<0 sscanf(&char,&char,&varg%v...)"`
-
-	ToFloat64    func(s string) float64                 `std:"double atof(&char)"`
-	ToInt32      func(s string) int32                   `std:"int atoi(&char)"`
-	ToInt64      func(s string) int64                   `std:"long atol(&char)"`
-	ParseFloat64 func(s string) (float64, int)          `std:"double atof(&char,+ptrdiff%v)"`
-	ParseInt64   func(s string, base int) (int64, int)  `std:"long strtol(&char,+ptrdiff(%v),int)"`
-	ParseUint64  func(s string, base int) (uint64, int) `std:"unsigned_long strtoul(&char,+ptrdiff(%v),int)"`
-
-	Copy           func([]byte, string) string    `std:"char strcpy(&char|%[1]v,&char)"`
-	CopyLimited    func([]byte, string) string    `std:"char strncpy(&char|%[1]v,&char)"`
-	Cat            func([]byte, string) string    `std:"char strcat(&char||%[1]v,&char)"`
-	CatLimited     func([]byte, string) string    `std:"char strncat(&char||%[1]v,&char,size_t%[2]v)"`
-	Compare        func(cs, ct string) int        `std:"int strcmp(&char,&char)"`
-	CompareLimited func(cs, ct string, n int) int `std:"int strncmp(&char,&char,int)"`
-
-	Index             func(cs string, c rune) int `std:"ptrdiff%v strchr(&char,int)"`
-	IndexLast         func(cs string, c rune) int `std:"ptrdiff%v strrchr(&char,int)"`
+	Printf func(s unsafe.Pointer, fmt string, args ...any) (int, error) `std:"int<0 sprintf(&char,&char,void...)"`
+	Scanf  func(s, fmt string, args ...any) (int, error)                `std:"int<0 sscanf(&char,&char,void...)"`
+
+	ToFloat64    func(s string) float64                               `std:"double atof(&char)"`
+	ToInt32      func(s string) int32                                 `std:"int atoi(&char)"`
+	ToInt64      func(s string) int64                                 `std:"long atol(&char)"`
+	ParseFloat64 func(s string, end *unsafe.Pointer) float64          `std:"double strtod(&char,+void)"`
+	ParseInt64   func(s string, end *unsafe.Pointer, base int) int64  `std:"long strtol(&char,+void,int)"`
+	ParseUint64  func(s string, end *unsafe.Pointer, base int) uint64 `std:"ulong strtoul(&char,+void,int)"`
+
+	Copy           func(dst []byte, src string) string            `std:"&char strcpy(&char,&char)"`
+	CopyLimited    func(dst []byte, src string, n uintptr)        `std:"void strncpy(&char[>=@3],&char,size_t)"`
+	Cat            func(dst []byte, src string) string            `std:"&char strcat(&char,&char)"`
+	CatLimited     func(dst []byte, src string, n uintptr) string `std:"&char strncat(&char,&char,size_t)"`
+	Compare        func(cs, ct string) int                        `std:"int strcmp(&char,&char)"`
+	CompareLimited func(cs, ct string, n int) int                 `std:"int strncmp(&char,&char,int)"`
+
+	Index             func(cs string, c rune) int `std:"ptrdiff strchr(&char,int)"`
+	IndexLast         func(cs string, c rune) int `std:"ptrdiff strrchr(&char,int)"`
 	Span              func(cs, ct string) int     `std:"size_t strspn(&char,&char)"`
 	ComplimentarySpan func(cs, ct string) int     `std:"size_t strcspn(&char,&char)"`
-	PointerBreak      func(cs, ct string) int     `std:"ptrdiff%v strpbrk(&char,&char)"`
+	PointerBreak      func(cs, ct string) int     `std:"ptrdiff strpbrk(&char,&char)"`
 
-	Search func(cs, ct string) int `std:"ptrdiff%v strstr(&char,&char)"`
+	Search func(cs, ct string) int `std:"ptrdiff strstr(&char,&char)"`
 	Length func(cs string) int     `std:"size_t strlen(&char)"`
 
-	Error  func(n error) string                `std:"$char strerror(int)"`
-	Tokens func(s []byte, delim string) string `std:"char strtok(&char,&char)"`
+	Error  func(errnum int32) string           `std:"&char strerror(int)"`
+	Tokens func(s []byte, delim string) string `std:"&char strtok(&char,&char)"`
+}
+
+// Allocator of C memory, for [New] and [NewStruct], it needs
+// to be imported (from the C standard library) before use.
+type Allocator struct {
+	location
+
+	Allocate func(n, size uintptr) unsafe.Pointer `std:"$void calloc(size_t,size_t)"`
+	Free     func(ptr unsafe.Pointer)             `std:"void free($void)"`
 }
 
 // LibraryMemory provides memory-related functions from <stdlib.h>.
 type LibraryMemory struct {
 	location
 
-	AllocateZeros func(int) []byte         `std:"$void|%v calloc(size_t=1,size_t)"`
-	Allocate      func(int) []byte         `std:"$void|%v malloc(size_t)"`
-	Reallocate    func([]byte, int) []byte `std:"$void|%[2]v realloc(#void,size_t)"`
-	Free          func([]byte)             `std:"free(#void)"`
+	AllocateZeros func(n, size uintptr) Memory[byte]                `std:"$void@free calloc(size_t,size_t)"`
+	Allocate      func(size uintptr) Memory[byte]                   `std:"$void@free malloc(size_t)"`
+	Reallocate    func(ptr Memory[byte], size uintptr) Memory[byte] `std:"$void@free realloc($void,size_t)"`
+	Free          func(ptr Memory[byte])                            `std:"void free($void)"`
 
-	Sort   func(base unsafe.Pointer, n, size int, cmp func(a, b unsafe.Pointer) int)                              `std:"qsort(&void,size_t,size_t,&func(&void,&void)int)"`
-	Search func(key, base unsafe.Pointer, n, size int, cmp func(keyval, datum unsafe.Pointer) int) unsafe.Pointer `std:"void bsearch(&void,&void,size_t,size_t,&func(&void,&void)int)"`
+	Sort   func(base unsafe.Pointer, n, size int, cmp func(a, b unsafe.Pointer) int)                              `std:"void qsort(&void,size_t,size_t,&func(&void,&void)int)"`
+	Search func(key, base unsafe.Pointer, n, size int, cmp func(keyval, datum unsafe.Pointer) int) unsafe.Pointer `std:"&void bsearch(&void,&void,size_t,size_t,&func(&void,&void)int)"`
 
-	Copy    func(dst, src []byte) []byte `std:"void|%v memcpy(&void|%[2]v,&void,size_t%[2]v)"`
-	Move    func(dst, src []byte) []byte `std:"void|%v memmove(&void|%[2]v,&void,size_t%[2]v)"`
-	Compare func(cs, ct []byte) int      `std:"int memcmp(&void|%[2]v,&void,size_t%[2]v)"`
+	Copy    func(dst, src []byte, n uintptr)     `std:"void memcpy(&void[>=@3],&void[>=@3],size_t)"`
+	Move    func(dst, src []byte, n uintptr)     `std:"void memmove(&void[>=@3],&void[>=@3],size_t)"`
+	Compare func(cs, ct []byte, n uintptr) int32 `std:"int memcmp(&void[>=@3],&void[>=@3],size_t)"`
 
-	Index func([]byte, byte) int `std:"ptrdiff%v memchr(&void,int,size_t%[2]v)"`
+	Index func(s []byte, c byte, n uintptr) unsafe.Pointer `std:"&void memchr(&void[>=@3],int,size_t)"`
 
-	Set func([]byte, byte) []byte `std:"void|%v memset(&void,int,size_t%[2]v)"`
+	Set func(s []byte, c byte, n uintptr) `std:"void memset(&void[>=@3],int,size_t)"`
 }
 
 // LibraryProgram provides program-related functions from <stdlib.h>.
 type LibraryProgram struct {
 	location
 
-	Abort  func()                   `std:"void abort"`
+	Abort  func()                   `std:"void abort(void)"`
 	Exit   func(status int)         `std:"void exit(int)"`
-	OnExit func(func())             `std:"void atexit($func)" sym:"atexit,__cxa_atexit"`
-	Getenv func(name string) string `std:"char getenv(&char)"`
+	OnExit func(func())             `std:"int atexit,__cxa_atexit($func)"`
+	Getenv func(name string) string `std:"&char getenv(&char)"`
 }
 
 // LibrarySystem provides system-related functions from <stdlib.h>.
@@ -245,8 +257,8 @@ type LibrarySystem struct {
 
 	Command func(command string) int `std:"int system(&char)"`
 
-	Clock func() time.Duration        `std:"clock_t clock"`
-	Time  func(t time.Time) time.Time `std:"time_t time(&time_t)"`
+	Clock func() time.Duration `std:"clock_t clock(void)"`
+	Time  func(t *int64) int64 `std:"time_t time(&time_t)"`
 }
 
 // LibraryDivision provides division-related functions from <stdlib.h>.
@@ -261,18 +273,18 @@ type LibraryDivision struct {
 type LibraryTime struct {
 	location
 
-	Sub    func(t1, t2 time.Time) time.Time `std:"time_t difftime(time_t,time_t)"`
-	String func(t time.Time) string         `std:"$char ctime(time_t)"`
+	Sub    func(t1, t0 int64) float64 `std:"double difftime(time_t,time_t)"`
+	String func(t *int64) string      `std:"&char ctime(&#time_t)"`
 
-	UTC   func(t time.Time) time.Time `std:"$void timegm(time_t)"`
-	Local func(t time.Time) time.Time `std:"$void localtime(time_t)"`
+	UTC   func(t *int64) Date `std:"&tm gmtime(&#time_t)"`
+	Local func(t *int64) Date `std:"&tm localtime(&#time_t)"`
 }
 
 // LibraryDates provides date-related functions from <time.h>.
 type LibraryDates struct {
 	location
 
-	Time   func(t time.Time) time.Time                     `std:"time_t mktime(&void)"`
-	String func(t time.Time) string                        `std:"$char asctime(&void)"`
-	Format func(s []byte, format string, tp time.Time) int `std:"size_t strftime(&char,size_t%v,&char,&void)"`
+	Time   func(t Date) int64                                       `std:"time_t mktime(&tm)"`
+	String func(t Date) string                                      `std:"&char asctime(&#tm)"`
+	Format func(s []byte, n uintptr, format string, t Date) uintptr `std:"size_t strftime(&char[>=@2],size_t,&char,&#tm)"`
 }
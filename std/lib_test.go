@@ -1,36 +1,40 @@
 package std_test
 
-/*var libc = dll.Import[std.Library]()
-
-func TestMain(m *testing.M) {
-	libc.Program.Exit(std.Int(m.Run()))
-}
-
-func TestLibc(t *testing.T) {
-	fmt.Println(libc.Char.IsAlphaNumeric('a'))
-
-	fmt.Println(libc.Math.Sqrt(2))
-
-	var i std.Int
-	var d = libc.Math.Frexp(2.2, &i)
-	fmt.Println(d, i)
-
-	fmt.Println(libc.System.Time(nil))
-
-	libc.Program.OnExit(func() {
-		fmt.Println("exiting...")
-	})
-}
-
-func BenchmarkGo(b *testing.B) {
-	for i := 0; i < b.N; i++ {
-		math.Sqrt(2)
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"runtime.link/dll"
+	"runtime.link/std"
+)
+
+func TestLibrary(t *testing.T) {
+	libc, err := dll.Load[std.Library]()
+	var errs dll.LoadError
+	if errors.As(err, &errs) {
+		for _, err := range errs {
+			// without cgo, C cannot call back into Go.
+			if !strings.HasSuffix(err.Error(), "callback requires cgo") {
+				t.Error(err)
+			}
+		}
+	} else if err != nil {
+		t.Fatal(err)
 	}
-}
-
-func BenchmarkC(b *testing.B) {
-	for i := 0; i < b.N; i++ {
-		libc.Math.Sqrt(2)
+	if n := libc.Strings.Length("hello"); n != 5 {
+		t.Fatal("unexpected strlen result", n)
+	}
+	buf := make([]byte, 8)
+	if s := libc.Strings.Copy(buf, "hello"); s != "hello" {
+		t.Fatal("unexpected strcpy result", s)
+	}
+	if n := libc.Memory.Compare(buf, []byte("help"), 4); n >= 0 {
+		t.Fatal("unexpected memcmp result", n)
+	}
+	mem := libc.Memory.AllocateZeros(4, 4)
+	if mem.Pointer() == 0 {
+		t.Fatal("calloc returned NULL")
 	}
+	mem.Free()
 }
-*/
@@ -0,0 +1,260 @@
+package std
+
+import (
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+
+	"runtime.link/internal/cconv"
+)
+
+// Memory errors, these are panicked by the [Memory], [Pointer] and
+// [Struct] types when they are misused.
+const (
+	ErrUseAfterFree errorString = "use after free"
+	ErrDoubleFree   errorString = "double free"
+	ErrNoAllocator  errorString = "no allocator for C memory"
+	ErrOutOfMemory  errorString = "out of memory"
+)
+
+// Memory is like [Handle] except it can be freed.
+// Copies of a Memory value share the same pointer,
+// such that it is freed (at most) once. Memory that
+// is never freed is freed when it becomes unreachable.
+//
+// Results of C functions are returned as Memory when
+// their tag names a destructor, such as fclose in
+//
+//	fopen func(&char,&char)$FILE@fclose
+type Memory[T any] struct {
+	_ [0]*T
+	*memory
+}
+
+// MemoryOf returns Memory for the C pointer, such that free is called
+// to release it. A nil free func means that the memory is not owned
+// by Go, so that freeing it only forgets the pointer.
+func MemoryOf[T any](ptr uintptr, free func()) Memory[T] {
+	if ptr == 0 {
+		return Memory[T]{}
+	}
+	m := new(memory)
+	m.init(ptr, free)
+	return Memory[T]{memory: m}
+}
+
+// memory is shared by the copies of a [Memory], [Pointer] or [Struct],
+// so that it is freed once and cannot be used after it has been freed.
+// The pointer and struct types embed memory and nothing else, such that
+// they have the same layout.
+type memory struct {
+	ptr   uintptr
+	free  func() // nil if the memory is borrowed.
+	freed atomic.Bool
+
+	alloc   *Allocator                 // for the strings of a Struct.
+	strings map[uintptr]unsafe.Pointer // owned by a Struct, by offset.
+}
+
+func init() {
+	cconv.Memory = reflect.TypeFor[memory]()
+	cconv.Borrow = func(ptr unsafe.Pointer) unsafe.Pointer {
+		m := new(memory)
+		m.init(uintptr(ptr), nil)
+		return unsafe.Pointer(m)
+	}
+}
+
+func (m *memory) init(ptr uintptr, free func()) {
+	m.ptr, m.free = ptr, free
+	if free != nil {
+		runtime.SetFinalizer(m, (*memory).Close)
+	}
+}
+
+// Pointer returns the address of the memory, it panics with
+// [ErrUseAfterFree] if the memory has been freed.
+func (m *memory) Pointer() uintptr {
+	if m == nil {
+		return 0
+	}
+	if m.freed.Load() {
+		panic(ErrUseAfterFree)
+	}
+	return m.ptr
+}
+
+// unsafePointer is like Pointer, for memory that is read or written
+// by Go.
+func (m *memory) unsafePointer() unsafe.Pointer {
+	ptr := m.Pointer()
+	return *(*unsafe.Pointer)(unsafe.Pointer(&ptr))
+}
+
+// Free the memory, it panics with [ErrDoubleFree] if
+// it has already been freed (or released).
+func (m *memory) Free() {
+	if err := m.Close(); err != nil {
+		panic(err)
+	}
+}
+
+// Close is like Free, except that it returns [ErrDoubleFree]
+// instead of panicking, so that Memory is an [io.Closer].
+func (m *memory) Close() error {
+	if m == nil {
+		return nil
+	}
+	if m.freed.Swap(true) {
+		return ErrDoubleFree
+	}
+	runtime.SetFinalizer(m, nil)
+	for _, s := range m.strings {
+		m.alloc.Free(s)
+	}
+	if m.free != nil {
+		m.free()
+	}
+	return nil
+}
+
+// Release ownership of the memory without freeing it, the caller
+// becomes responsible for freeing the returned address. Any further
+// use of the memory panics.
+func (m *memory) Release() uintptr {
+	if m == nil {
+		return 0
+	}
+	if m.freed.Swap(true) {
+		panic(ErrUseAfterFree)
+	}
+	runtime.SetFinalizer(m, nil)
+	return m.ptr
+}
+
+// allocate size bytes of zeroed memory, that are freed by the allocator.
+func (alloc *Allocator) allocate(m *memory, size uintptr) {
+	if alloc == nil || alloc.Allocate == nil || alloc.Free == nil {
+		panic(ErrNoAllocator)
+	}
+	ptr := alloc.Allocate(1, max(size, 1))
+	if ptr == nil {
+		panic(ErrOutOfMemory)
+	}
+	m.init(uintptr(ptr), func() { alloc.Free(ptr) })
+	m.alloc = alloc
+}
+
+// Pointer is a typed pointer to C memory that can
+// be freed, dereferenced, and passed to C functions.
+// T is read and written as is, so it must have the
+// same layout in Go and C, see [Struct] for types
+// that do not.
+type Pointer[T any] struct {
+	_ [0]*T
+	*pointer[T]
+}
+
+type pointer[T any] struct {
+	memory
+}
+
+// New allocates a zero T in C memory, which should be freed.
+// It panics if T does not have the same layout in Go and C.
+func New[T any](alloc *Allocator) Pointer[T] {
+	rtype := reflect.TypeFor[T]()
+	if !layoutOf(rtype).plain {
+		panic(errorString("std.New: " + rtype.String() + " has a different layout in C"))
+	}
+	p := new(pointer[T])
+	alloc.allocate(&p.memory, rtype.Size())
+	return Pointer[T]{pointer: p}
+}
+
+// Get returns a copy of the value pointed to.
+func (p *pointer[T]) Get() T {
+	return *(*T)(p.unsafePointer())
+}
+
+// Set the value pointed to.
+func (p *pointer[T]) Set(val T) {
+	*(*T)(p.unsafePointer()) = val
+}
+
+func (p *pointer[T]) UnsafePointer() unsafe.Pointer {
+	return p.unsafePointer()
+}
+
+// Struct is a C struct in C memory, with the fields of T laid
+// out as a C compiler would (see [LayoutOf]). It is copied field
+// by field when it is read or written, with Go strings being
+// copied to and from null-terminated C strings (char *), that
+// are freed along with the Struct.
+type Struct[T any] struct {
+	_ [0]*T
+	*structure[T]
+}
+
+type structure[T any] struct {
+	memory
+}
+
+// NewStruct allocates a zero C struct for T, which should be freed.
+func NewStruct[T any](alloc *Allocator) Struct[T] {
+	s := new(structure[T])
+	alloc.allocate(&s.memory, layoutOf(reflect.TypeFor[T]()).size)
+	return Struct[T]{structure: s}
+}
+
+// Get returns a copy of the C struct.
+func (s *structure[T]) Get() T {
+	var val T
+	layoutOf(reflect.TypeFor[T]()).toGo(unsafe.Pointer(&val), s.unsafePointer())
+	return val
+}
+
+// Set the C struct to a copy of val.
+func (s *structure[T]) Set(val T) {
+	layoutOf(reflect.TypeFor[T]()).toC(s.unsafePointer(), unsafe.Pointer(&val), &s.memory, 0)
+}
+
+func (s *structure[T]) UnsafePointer() unsafe.Pointer {
+	return s.unsafePointer()
+}
+
+// Field of a [Struct] in C memory, that can be read and written without
+// copying the rest of the struct.
+type Field[T any] struct {
+	memory *memory
+	field  *field
+}
+
+// FieldOf returns the Go field of the struct with the given name, it
+// panics if there is no such field, or if the field is not of type V.
+func FieldOf[V, T any](s Struct[T], name string) Field[V] {
+	fields := layoutOf(reflect.TypeFor[T]()).fields
+	for i := range fields {
+		field := &fields[i]
+		if field.name != name {
+			continue
+		}
+		if field.rtype != reflect.TypeFor[V]() {
+			panic(errorString("std.FieldOf: field " + name + " is a " + field.rtype.String()))
+		}
+		return Field[V]{memory: &s.memory, field: field}
+	}
+	panic(errorString("std.FieldOf: " + reflect.TypeFor[T]().String() + " has no field " + name))
+}
+
+// Get returns a copy of the field.
+func (f Field[T]) Get() T {
+	var val T
+	f.field.get(unsafe.Pointer(&val), f.memory.unsafePointer())
+	return val
+}
+
+// Set the field to a copy of val.
+func (f Field[T]) Set(val T) {
+	f.field.set(f.memory.unsafePointer(), unsafe.Pointer(&val), f.memory, 0)
+}
@@ -0,0 +1,151 @@
+package std_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"runtime.link/std"
+)
+
+// allocator of Go memory that pretends to be C memory, so that the
+// memory can be checked without a C library.
+type allocator struct {
+	live map[unsafe.Pointer][]byte
+}
+
+func newAllocator() (*allocator, *std.Allocator) {
+	a := &allocator{live: make(map[unsafe.Pointer][]byte)}
+	return a, &std.Allocator{
+		Allocate: func(n, size uintptr) unsafe.Pointer {
+			buf := make([]byte, n*size)
+			a.live[unsafe.Pointer(&buf[0])] = buf
+			return unsafe.Pointer(&buf[0])
+		},
+		Free: func(ptr unsafe.Pointer) {
+			if _, ok := a.live[ptr]; !ok {
+				panic("free of memory that is not allocated")
+			}
+			delete(a.live, ptr)
+		},
+	}
+}
+
+func expectPanic(t *testing.T, expected error, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		if err, _ := recover().(error); err != expected {
+			t.Fatal("expected a panic with", expected, "got", err)
+		}
+	}()
+	fn()
+}
+
+type point struct {
+	X, Y int32
+}
+
+func TestPointer(t *testing.T) {
+	a, alloc := newAllocator()
+	p := std.New[point](alloc)
+	if p.Get() != (point{}) {
+		t.Fatal("expected zeroed memory")
+	}
+	p.Set(point{1, 2})
+	if *(*point)(p.UnsafePointer()) != (point{1, 2}) {
+		t.Fatal("expected Set to write the memory")
+	}
+	copied := p
+	copied.Free()
+	if len(a.live) != 0 {
+		t.Fatal("expected Free to free the memory")
+	}
+	expectPanic(t, std.ErrUseAfterFree, func() { p.Get() })
+	expectPanic(t, std.ErrUseAfterFree, func() { p.Set(point{}) })
+	expectPanic(t, std.ErrDoubleFree, p.Free)
+	if err := p.Close(); err != std.ErrDoubleFree {
+		t.Fatal("expected Close to report a double free", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected New to reject a string, as it has a different layout in C")
+			}
+		}()
+		std.New[string](alloc)
+	}()
+	expectPanic(t, std.ErrNoAllocator, func() { std.New[point](new(std.Allocator)) })
+}
+
+type person struct {
+	Age    int8
+	Name   string
+	Tags   [2]string
+	Height float64
+}
+
+func TestStruct(t *testing.T) {
+	a, alloc := newAllocator()
+	s := std.NewStruct[person](alloc)
+	val := person{Age: 42, Name: "Alice", Tags: [2]string{"a", "b"}, Height: 1.5}
+	s.Set(val)
+	if s.Get() != val {
+		t.Fatal("unexpected struct", s.Get())
+	}
+	type cperson struct {
+		Age    int8
+		Name   *byte
+		Tags   [2]*byte
+		Height float64
+	}
+	c := (*cperson)(s.UnsafePointer())
+	if c.Age != 42 || *c.Name != 'A' || *c.Tags[1] != 'b' || c.Height != 1.5 {
+		t.Fatal("expected the struct to have a C layout")
+	}
+	if len(a.live) != 4 {
+		t.Fatal("expected the struct and its 3 strings to be allocated", len(a.live))
+	}
+	name := std.FieldOf[string](s, "Name")
+	name.Set("Bob")
+	if name.Get() != "Bob" || s.Get().Name != "Bob" || s.Get().Height != 1.5 {
+		t.Fatal("unexpected field", name.Get())
+	}
+	if len(a.live) != 4 {
+		t.Fatal("expected the old name to be freed", len(a.live))
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected FieldOf to check the type of the field")
+			}
+		}()
+		std.FieldOf[int32](s, "Name")
+	}()
+	s.Free()
+	if len(a.live) != 0 {
+		t.Fatal("expected the struct and its strings to be freed", len(a.live))
+	}
+	expectPanic(t, std.ErrUseAfterFree, func() { s.Get() })
+	expectPanic(t, std.ErrUseAfterFree, func() { name.Set("Carol") })
+	expectPanic(t, std.ErrDoubleFree, s.Free)
+}
+
+func TestMemoryOf(t *testing.T) {
+	var freed int
+	m := std.MemoryOf[point](0x1000, func() { freed++ })
+	if m.Pointer() != 0x1000 {
+		t.Fatal("unexpected pointer", m.Pointer())
+	}
+	if m.Release() != 0x1000 || freed != 0 {
+		t.Fatal("expected Release to return the pointer without freeing it")
+	}
+	expectPanic(t, std.ErrUseAfterFree, func() { m.Pointer() })
+	expectPanic(t, std.ErrDoubleFree, m.Free)
+	if freed != 0 {
+		t.Fatal("expected released memory to never be freed")
+	}
+	var zero std.Memory[point]
+	if zero.Pointer() != 0 || zero.Close() != nil {
+		t.Fatal("expected the zero Memory to be nil")
+	}
+}
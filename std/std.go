@@ -296,25 +296,6 @@ type (
 	SignedAtomic c_sig_atomic_t
 )
 
-// Error represents a C int error, where 0 is success
-// and any other value is an error.
-type Error c_int
-
-// Errors types.
-const (
-	ErrDomain              Error = c_EDOM
-	ErrIllegalByteSequence Error = c_EILSEQ
-	ErrResultTooLarge      Error = c_ERANGE
-)
-
-// Err returns nil if err is 0, otherwise it returns an error
-func (err Error) Err() error {
-	if err == 0 {
-		return nil
-	}
-	return errors.New(strconv.Itoa(int(err)))
-}
-
 
 // Structures.
 type (
@@ -479,60 +460,3 @@ func (p *handle) SetPointer(val unsafe.Pointer) {
 func (s String) UnsafePointer() unsafe.Pointer {
 	return unsafe.Pointer(s.ptr)
 }
-
-// Memory is like [Handle] except it can be freed.
-type Memory[T any] struct {
-	_ [0]*T
-	*memory
-}
-
-func (m memory) Pointer() uintptr {
-	return uintptr(m.ptr)
-}
-
-type memory struct {
-	ptr  uintptr
-	free func()
-}
-
-// Pointer is a typed pointer to C memory that can
-// be freed, dereferenced, and passed to C functions.
-type Pointer[T any] struct {
-	_ [0]*T
-	*pointer[T]
-}
-
-type pointer[T any] struct {
-	ptr   unsafe.Pointer
-	free  func()
-	local bool // Go memory?
-}
-
-func (p pointer[T]) Get() T {
-	return *(*T)(p.ptr)
-}
-
-func (p pointer[T]) Set(val T) {
-	*(*T)(p.ptr) = val
-}
-
-func (p pointer[T]) Free() {
-	p.free()
-}
-
-func (p pointer[T]) UnsafePointer() unsafe.Pointer {
-	return p.ptr
-}
-
-type Struct[T any] struct {
-	_      [0]*T
-	ptr    uintptr   // pointer to C
-	free   func()    // free function
-	local  bool      // Go memory?
-	layout []uintptr // C offsets.
-}
-
-type Field[T any] struct {
-	valid bool
-	kind  uint8
-}
@@ -0,0 +1,322 @@
+// Package stdtag defines an [analysis.Analyzer] that checks the std tags of
+// library structs, so that mistakes are reported by go vet, rather than
+// when the library is imported by dll.Import (or never).
+package stdtag
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"runtime.link/std"
+)
+
+const doc = `check std tags of library structs
+
+The stdtag analyzer reports std tags on func fields that are invalid, or
+that are incompatible with the Go signature of the field: parameter and
+result counts and kinds, along with %[n]v indices that are out of range
+and destructors of results that are not a std.Memory (or std.Pointer and
+std.Struct). Fields tagged with ffi or sym, instead of std, are also
+reported.`
+
+// Analyzer for std tags.
+var Analyzer = &analysis.Analyzer{
+	Name:     "stdtag",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	inspect.Preorder([]ast.Node{(*ast.StructType)(nil)}, func(node ast.Node) {
+		for _, field := range node.(*ast.StructType).Fields.List {
+			if field.Tag != nil {
+				check(pass, field)
+			}
+		}
+	})
+	return nil, nil
+}
+
+// accessor reports whether the std tag names a struct field, as those of
+// std.Getters and std.Setters do, rather than a C function.
+func accessor(tag string) bool {
+	sym, _, _ := strings.Cut(strings.TrimSpace(tag), " ")
+	return strings.Contains(sym, ".")
+}
+
+func check(pass *analysis.Pass, field *ast.Field) {
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return
+	}
+	// pos returns the position of the given offset within the tag, which
+	// is only known when the tag is a raw string literal.
+	pos := func(offset int) token.Pos {
+		if field.Tag.Value[0] != '`' {
+			return field.Tag.Pos()
+		}
+		return field.Tag.Pos() + 1 + token.Pos(offset)
+	}
+	for _, key := range []string{"ffi", "sym"} {
+		if _, offset, ok := lookup(tag, key); ok {
+			pass.Reportf(pos(offset-len(key)-2), "use a std tag instead of %s", key)
+		}
+	}
+	value, offset, ok := lookup(tag, "std")
+	if !ok {
+		return
+	}
+	signature, ok := pass.TypesInfo.TypeOf(field.Type).Underlying().(*types.Signature)
+	if !ok || accessor(value) {
+		return
+	}
+	_, ctype, err := std.Tag(value).Parse()
+	if err != nil {
+		var syntax std.SyntaxError
+		if errors.As(err, &syntax) {
+			pass.Reportf(pos(offset+max(syntax.Pos-1, 0)), "invalid std tag: %v", syntax.Err)
+			return
+		}
+		pass.Reportf(pos(offset), "invalid std tag: %v", err)
+		return
+	}
+	if ctype.Name != "func" || ctype.Func == nil {
+		pass.Reportf(pos(offset), "std tag of a func field must have a func type")
+		return
+	}
+	report := func(format string, args ...any) {
+		pass.Reportf(pos(offset), "std tag %s: %s", value, fmt.Sprintf(format, args...))
+	}
+	var (
+		params = signature.Params()
+		used   = make([]bool, params.Len())
+		next   = 1
+	)
+	for i, arg := range ctype.Args {
+		explicit := arg.Maps != next
+		next = arg.Maps + 1
+		if arg.Maps > params.Len() {
+			if explicit {
+				report("%%[%d]v is out of range, the func has %d parameters", arg.Maps, params.Len())
+			} else {
+				report("argument %d has no Go parameter, the func has %d parameters", i+1, params.Len())
+			}
+			continue
+		}
+		for _, drop := range arg.Drop.Args {
+			if drop.Maps > params.Len() {
+				report("%%[%d]v is out of range, the func has %d parameters", drop.Maps, params.Len())
+			}
+		}
+		used[arg.Maps-1] = true
+		param := params.At(arg.Maps - 1).Type()
+		variadic := signature.Variadic() && arg.Maps == params.Len()
+		if arg.More != variadic || arg.More && i != len(ctype.Args)-1 {
+			report("variadic argument %d must map to the Go variadic parameter", i+1)
+			continue
+		}
+		if arg.More {
+			if _, ok := param.(*types.Slice).Elem().Underlying().(*types.Interface); !ok {
+				report("Go variadic parameter must be ...any, not %s", param)
+			}
+			continue
+		}
+		if msg := compatible(pass, param, arg); msg != "" {
+			report("argument %d: %s", i+1, msg)
+		}
+	}
+	for i, ok := range used {
+		if !ok {
+			report("Go parameter %d is not mapped to an argument", i+1)
+		}
+	}
+	results := signature.Results()
+	length := results.Len()
+	if length > 0 && types.Identical(results.At(length-1).Type(), types.Universe.Lookup("error").Type()) {
+		length--
+	}
+	if length == 1 {
+		if ctype.Func.Name == "void" && ctype.Func.Free == 0 {
+			report("the func has a result, but the C function returns void")
+			return
+		}
+		if msg := compatible(pass, results.At(0).Type(), *ctype.Func); msg != "" {
+			report("result: %s", msg)
+		}
+		if drop := ctype.Func.Drop.Name; drop != "" && !isMemory(results.At(0).Type()) {
+			report("result: Go type %s is not a std.Memory, so it is never freed by %s", results.At(0).Type(), drop)
+		}
+	}
+}
+
+// lookup returns the value of the key within the struct tag, along with
+// the offset of the value, following the conventions of
+// reflect.StructTag.Lookup
+func lookup(tag, key string) (string, int, bool) {
+	offset := 0
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag, offset = tag[i:], offset+i
+		if tag == "" {
+			break
+		}
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		name := tag[:i]
+		tag, offset = tag[i+1:], offset+i+1
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		quoted := tag[:i+1]
+		tag, offset = tag[i+1:], offset+i+1
+		if name == key {
+			value, err := strconv.Unquote(quoted)
+			if err != nil {
+				break
+			}
+			return value, offset - len(quoted) + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// class of a value, as passed between Go and C.
+type class byte
+
+const (
+	unknown   class = iota
+	integer         // including bool
+	float32s        // float
+	float64s        // double
+	pointer         // including strings, slices, funcs and handles.
+	aggregate       // struct passed by value.
+	void
+)
+
+// classes of the standard C types.
+var classes = map[string]class{
+	"bool": integer, "char": integer, "schar": integer, "uchar": integer,
+	"short": integer, "ushort": integer, "int": integer, "uint": integer,
+	"long": integer, "ulong": integer,
+	"int8_t": integer, "int16_t": integer, "int32_t": integer, "int64_t": integer,
+	"uint8_t": integer, "uint16_t": integer, "uint32_t": integer, "uint64_t": integer,
+	"size_t": integer, "ptrdiff": integer, "ptrdiff_t": integer,
+	"intptr_t": integer, "uintptr_t": integer,
+	"float":  float32s,
+	"double": float64s,
+	"void":   void,
+}
+
+// compatible returns a description of why the Go type cannot be passed
+// as the C type, or an empty string if it can be.
+func compatible(pass *analysis.Pass, rtype types.Type, ctype std.Type) string {
+	var have class
+	switch under := rtype.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case under.Kind() == types.Float32:
+			have = float32s
+		case under.Kind() == types.Float64:
+			have = float64s
+		case under.Info()&(types.IsBoolean|types.IsInteger) != 0:
+			have = integer
+		case under.Kind() == types.String, under.Kind() == types.UnsafePointer:
+			have = pointer
+		}
+	case *types.Pointer, *types.Slice, *types.Signature:
+		have = pointer
+	case *types.Struct:
+		have = aggregate
+		if isHandle(rtype) {
+			have = pointer
+		}
+	}
+	if have == unknown {
+		return "unsupported Go type " + rtype.String()
+	}
+	want, ok := classes[ctype.Name]
+	if ctype.Free != 0 || ctype.Name == "func" {
+		want, ok = pointer, true
+	}
+	if !ok || want == void {
+		return "" // defined by the library.
+	}
+	switch {
+	case have == want:
+		return ""
+	case have == integer && want == pointer:
+		// pointers may be passed as integers that are wide enough.
+		if pass.TypesSizes.Sizeof(rtype) >= pass.TypesSizes.Sizeof(types.Typ[types.UnsafePointer]) {
+			return ""
+		}
+	}
+	return "Go type " + rtype.String() + " is not compatible with " + ctype.String()
+}
+
+// isMemory reports whether the type is a std.Memory, std.Pointer or
+// std.Struct, or is defined by one. These embed a pointer to the
+// std.memory that is freed by the destructor, or to a struct that
+// embeds it.
+func isMemory(rtype types.Type) bool {
+	under, ok := rtype.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	isStd := func(rtype types.Type, name string) bool {
+		named, ok := rtype.(*types.Named)
+		return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "runtime.link/std" &&
+			(name == "" || named.Obj().Name() == name)
+	}
+	for i := 0; i < under.NumFields(); i++ {
+		ptr, ok := under.Field(i).Type().(*types.Pointer)
+		if !ok || !isStd(ptr.Elem(), "") {
+			continue
+		}
+		if isStd(ptr.Elem(), "memory") {
+			return true
+		}
+		elem, ok := ptr.Elem().Underlying().(*types.Struct)
+		if ok && elem.NumFields() == 1 && elem.Field(0).Embedded() && isStd(elem.Field(0).Type(), "memory") {
+			return true
+		}
+	}
+	return false
+}
+
+// isHandle reports whether the type implements std.IsPointer.
+func isHandle(rtype types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(rtype, false, nil, "Pointer")
+	method, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	signature := method.Type().(*types.Signature)
+	return signature.Params().Len() == 0 && signature.Results().Len() == 1 &&
+		types.Identical(signature.Results().At(0).Type(), types.Typ[types.Uintptr])
+}
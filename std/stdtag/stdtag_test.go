@@ -0,0 +1,13 @@
+package stdtag_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"runtime.link/std/stdtag"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), stdtag.Analyzer, "a")
+}
@@ -0,0 +1,65 @@
+package a
+
+import (
+	"unsafe"
+
+	"runtime.link/std"
+)
+
+type File struct{ _ uintptr }
+
+type Window struct{ ptr uintptr }
+
+func (w Window) Pointer() uintptr { return w.ptr }
+
+type Stream std.Memory[Stream]
+
+type Point struct{ X, Y int32 }
+
+type Library struct {
+	location struct{} `std:"libc.so.6 libm.so.6"`
+
+	Puts     func(string) int32                                       `std:"puts func(&#char)int"`
+	Sqrt     func(float64) (float64, error)                           `std:"sqrt func(double)double; errno"`
+	Abs      func(int32) int32                                        `std:"abs func(int)int"`
+	Printf   func(string, ...any) int32                               `std:"printf func(&#char,void...?@1)int"`
+	Memcpy   func(unsafe.Pointer, []byte, uintptr)                    `std:"memcpy func(&void[>=@3],&#void~@1,size_t)&void"`
+	Setvbuf  func(stream *File, mode int32, size uintptr, buf []byte) `std:"setvbuf func(&FILE,&char%[4]v,int%[2]v,size_t)int"`
+	Title    func(Window) string                                      `std:"SDL_GetWindowTitle func(&SDL_Window)&char"`
+	Move     func(Point, uintptr)                                     `std:"move func(point,&void)void"`
+	Untagged func()
+
+	Syntax   func()                `std:"foo func(int"`             // want `invalid std tag: unexpected token, expected .,. or .\).`
+	Missing  func()                `std:"foo"`                      // want `invalid std tag: missing type information`
+	NotFunc  func()                `std:"foo int"`                  // want `std tag of a func field must have a func type`
+	Float    func(float32) float64 `std:"f func(double)double"`     // want `argument 1: Go type float32 is not compatible with double`
+	Narrow   func(int32)           `std:"f func(&void)void"`        // want `argument 1: Go type int32 is not compatible with &void`
+	Ptr      func(*File)           `std:"f func(int)void"`          // want `argument 1: Go type \*a.File is not compatible with int`
+	Result   func() int32          `std:"f func()void"`             // want `the func has a result, but the C function returns void`
+	Returns  func() string         `std:"f func()int"`              // want `result: Go type string is not compatible with int`
+	Fewer    func(int32, int32)    `std:"f func(int)void"`          // want `Go parameter 2 is not mapped to an argument`
+	More     func(int32)           `std:"f func(int,int)void"`      // want `argument 2 has no Go parameter, the func has 1 parameters`
+	Index    func(int32)           `std:"f func(int,int%[3]v)void"` // want `%\[3\]v is out of range, the func has 1 parameters`
+	Variadic func(string, ...any)  `std:"f func(&char,&char)void"`  // want `variadic argument 2 must map to the Go variadic parameter`
+	Varargs  func(...int32)        `std:"f func(void...)void"`      // want `Go variadic parameter must be ...any, not \[\]int32`
+	Channel  func(chan int)        `std:"f func(&void)void"`        // want `argument 1: unsupported Go type chan int`
+
+	Log   func(string, ...any) `ffi:"SDL_Log"`       // want `use a std tag instead of ffi`
+	Error func(string)         `sym:"perror(&char)"` // want `use a std tag instead of sym`
+}
+
+type Files struct {
+	SetBuffer func(*File, *byte) int32       `std:"int setbuf(&FILE,free@fclose{%v})"`
+	Dropped   func(*File, *byte) int32       `std:"int setbuf(&FILE,free@fclose{%[3]v})"` // want `%\[3\]v is out of range, the func has 2 parameters`
+	Isalnum   func(rune) rune                `std:"int isalnum(int)"`
+	Declared  func(float32) float64          `std:"double sqrt(double)"` // want `argument 1: Go type float32 is not compatible with double`
+	Open      func(string, string) Stream    `std:"$FILE@fclose fopen(&char,&char)"`
+	Strdup    func(string) std.Pointer[byte] `std:"$char@free strdup(&char)"`
+	Leaked    func(string, string) *File     `std:"$FILE@fclose fopen(&char,&char)"` // want `result: Go type \*a.File is not a std.Memory, so it is never freed by fclose`
+}
+
+type Surfaces struct {
+	Width    func(Stream) int32             `std:"SDL_Surface.w+16 int"`
+	SetWidth func(Stream, int32)            `std:"SDL_Surface.w+16 int"`
+	Name     func(std.Pointer[byte]) string `std:"my_struct.name"`
+}
@@ -0,0 +1,19 @@
+package std
+
+type Memory[T any] struct {
+	_ [0]*T
+	*memory
+}
+
+type Pointer[T any] struct {
+	_ [0]*T
+	*pointer[T]
+}
+
+type pointer[T any] struct {
+	memory
+}
+
+type memory struct{ ptr uintptr }
+
+func (m *memory) Pointer() uintptr { return m.ptr }
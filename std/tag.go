@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"strings"
 	"text/scanner"
+	"unicode"
 )
 
 // Tag includes a symbol name along with the type of the symbol.
@@ -59,6 +60,7 @@ type Type struct {
 	Free rune       // ownership assertion, one of '$', '&', '*', '+' or '-'
 	Test Assertions // memory safety assertions
 	Call Call       // symbol to lookup on failure (if function)
+	Drop Call       // destructor of a '$' value, else the pointer is borrowed until it is called.
 	More bool       // varaidic
 
 	Maps int // index of the Go argument that is mapped to this value.
@@ -66,7 +68,9 @@ type Type struct {
 
 // Call represents a function to call on failure
 // within a [Tag], to return information about why
-// the assertion failed.
+// the assertion failed. The name 'errno' (without
+// any arguments) refers to the C errno value that
+// is set by the call, rather than to a function.
 type Call struct {
 	Name string     // symbol name
 	Args []Argument // arguments to pass to the function
@@ -92,15 +96,20 @@ type Argument struct {
 	Index uint8  // of the argument being referred to. if greater than zero ignore const and value.
 	Const string // C standard constant (or supported macro) name.
 	Value int64  // integer value
+	Maps  int    // index of the Go argument being referred to, by %v or %[n]v
 }
 
 // Parse returns a structured representation of
 // the symbols and type defined in the tag.
 func (tag Tag) Parse() ([]string, Type, error) {
+	if result, symbols, params, ok := tag.declaration(); ok {
+		return tag.parseDeclaration(result, symbols, params)
+	}
 	symbols, stype, ok := strings.Cut(string(tag), " ")
 	if !ok {
 		return nil, Type{}, ErrTagMissingType
 	}
+	symbols = strings.TrimSuffix(symbols, "?")
 	var scan scanner.Scanner
 	scan.Init(strings.NewReader(stype))
 	ctype, err := tag.parseType(&scan, strings.Index(string(tag), " "))
@@ -110,10 +119,265 @@ func (tag Tag) Parse() ([]string, Type, error) {
 	return strings.Split(symbols, ","), ctype, nil
 }
 
-func (tag Tag) argument(scan *scanner.Scanner, pos int) (Argument, error) {
+// declaration splits a tag written in C declaration order, such as
+// 'int isalnum(int)', into the result type, the symbols and the offset
+// of the parameter list. ok is false if the tag is not in this order.
+func (tag Tag) declaration() (result, symbols string, params int, ok bool) {
+	params = strings.IndexByte(string(tag), '(')
+	if params < 0 {
+		return "", "", 0, false
+	}
+	start := strings.LastIndexByte(string(tag[:params]), ' ') + 1
+	symbols = string(tag[start:params])
+	if symbols == "" || strings.TrimSuffix(symbols, "?") == "func" {
+		return "", "", 0, false
+	}
+	for _, r := range symbols {
+		if r != '_' && r != ',' && r != '@' && r != '.' && r != '?' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return "", "", 0, false
+		}
+	}
+	if start > 0 {
+		result = strings.TrimSpace(string(tag[:start]))
+	}
+	return result, symbols, params, true
+}
+
+// parseDeclaration parses a tag in C declaration order, such that it
+// results in the same [Type] as the equivalent 'sym func(args)ret' tag.
+// A missing result type is void, as is a parameter list of (void).
+func (tag Tag) parseDeclaration(result, symbols string, params int) ([]string, Type, error) {
+	var (
+		scan  scanner.Scanner
+		ctype = Type{Name: "func", Func: &Type{Name: "void"}}
+	)
+	if result != "" {
+		scan.Init(strings.NewReader(result))
+		ret, err := tag.parseType(&scan, 0)
+		if err != nil {
+			return nil, Type{}, err
+		}
+		if scan.Peek() != scanner.EOF {
+			return nil, Type{}, SyntaxError{
+				Tag: tag,
+				Pos: scan.Pos().Column + 1,
+				Err: errorString("unexpected token, expected symbol name"),
+			}
+		}
+		ctype.Func = &ret
+	}
+	scan.Init(strings.NewReader(string(tag[params:])))
+	args, err := tag.parseParams(&scan, params)
+	if err != nil {
+		return nil, Type{}, err
+	}
+	if len(args) == 1 && args[0].Name == "void" && args[0].Free == 0 && !args[0].Hash && !args[0].More && args[0].Test == (Assertions{}) {
+		args = nil
+	}
+	ctype.Args = args
+	if scan.Peek() == ';' {
+		if ctype.Call, err = tag.parseCall(&scan, params); err != nil {
+			return nil, Type{}, err
+		}
+	}
+	return strings.Split(strings.TrimSuffix(symbols, "?"), ","), ctype, nil
+}
+
+// Optional reports whether the symbols of the tag are marked as
+// optional with a trailing '?', such that it is not an error for
+// none of them to be available.
+func (tag Tag) Optional() bool {
+	if _, symbols, _, ok := tag.declaration(); ok {
+		return strings.HasSuffix(symbols, "?")
+	}
+	symbols, _, _ := strings.Cut(string(tag), " ")
+	return strings.HasSuffix(symbols, "?")
+}
+
+// Canonical returns the tag in its canonical form, as printed by
+// [Type.String], with any optional marker preserved. Tags written in
+// C declaration order remain so, with an explicit result type and a
+// parameter list of (void) for functions without any parameters.
+func (tag Tag) Canonical() (Tag, error) {
+	symbols, ctype, err := tag.Parse()
+	if err != nil {
+		return tag, err
+	}
+	list := strings.Join(symbols, ",")
+	if tag.Optional() {
+		list += "?"
+	}
+	if _, _, _, ok := tag.declaration(); ok {
+		var b strings.Builder
+		ctype.Func.write(&b, ctype.Func.Maps)
+		b.WriteString(" " + list)
+		if len(ctype.Args) == 0 {
+			b.WriteString("(void)")
+		} else {
+			ctype.writeArgs(&b)
+		}
+		ctype.Call.write(&b)
+		return Tag(b.String()), nil
+	}
+	return Tag(list + " " + ctype.String()), nil
+}
+
+// String returns the canonical tag syntax for the type, such that
+// parsing it results in the same [Type].
+func (t Type) String() string {
+	var b strings.Builder
+	t.write(&b, t.Maps)
+	return b.String()
+}
+
+// write the type, with an explicit %[n]v index when it is not
+// mapped to the next Go argument.
+func (t Type) write(b *strings.Builder, next int) {
+	switch {
+	case t.Drop.Name != "" && t.Free == '$':
+		b.WriteRune(t.Free)
+	case t.Drop.Name != "":
+		b.WriteString("free@" + t.Drop.Name + "{")
+		for i, arg := range t.Drop.Args {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(arg.String())
+		}
+		b.WriteByte('}')
+	case t.Free != 0:
+		b.WriteRune(t.Free)
+	}
+	if t.Hash {
+		b.WriteByte('#')
+	}
+	b.WriteString(t.Name)
+	if t.Drop.Name != "" && t.Free == '$' {
+		b.WriteString("@" + t.Drop.Name)
+	}
+	if t.Name == "func" && t.Func != nil {
+		t.writeArgs(b)
+		t.Func.write(b, t.Func.Maps)
+		t.Call.write(b)
+	}
+	if t.Maps != next {
+		b.WriteString("%[" + strconv.Itoa(t.Maps) + "]v")
+	}
+	test := t.Test
+	switch {
+	case t.More:
+		b.WriteString("...")
+	case test.Capacity:
+		b.WriteByte('[')
+	default:
+		for i := 0; i < test.Indirect; i++ {
+			b.WriteByte('*')
+		}
+	}
+	var (
+		op  string
+		arg Argument
+	)
+	switch {
+	case test.Overlaps.Check:
+		// the parser inverts '~', so that it means 'must not overlap'.
+		op, arg = "~", test.Overlaps
+		test.Inverted = !test.Inverted
+	case test.Lifetime.Check:
+		op, arg = "^", test.Lifetime
+	case test.SameType.Check:
+		op, arg = ":", test.SameType
+	case test.OfFormat.Check:
+		op, arg = "?", test.OfFormat
+	case test.MoreThan.Check, test.LessThan.Check, test.Equality.Check:
+		switch {
+		case test.MoreThan.Check:
+			op, arg = ">", test.MoreThan
+		case test.LessThan.Check:
+			op, arg = "<", test.LessThan
+		}
+		if test.Equality.Check {
+			op, arg = op+"=", test.Equality
+		}
+	}
+	if op != "" {
+		if test.Inverted {
+			b.WriteByte('!')
+		}
+		b.WriteString(op)
+		b.WriteString(arg.String())
+	}
+	if test.Capacity {
+		b.WriteByte(']')
+	}
+}
+
+// writeArgs writes the parenthesized parameter list of a func type.
+func (t Type) writeArgs(b *strings.Builder) {
+	b.WriteByte('(')
+	next := 1
+	for i, arg := range t.Args {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		arg.write(b, next)
+		next = arg.Maps + 1
+	}
+	b.WriteByte(')')
+}
+
+// write the call that follows the type of a func, if any.
+func (c Call) write(b *strings.Builder) {
+	if c.Name == "" {
+		return
+	}
+	b.WriteString("; ")
+	b.WriteString(c.Name)
+	if c.Name != "errno" || len(c.Args) > 0 {
+		b.WriteByte('(')
+		for i, arg := range c.Args {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(arg.String())
+		}
+		b.WriteByte(')')
+	}
+}
+
+// String returns the tag syntax for the argument.
+func (arg Argument) String() string {
+	switch {
+	case arg.Maps > 0:
+		return "%[" + strconv.Itoa(arg.Maps) + "]v"
+	case arg.Index > 0:
+		return "@" + strconv.Itoa(int(arg.Index))
+	case arg.Const != "":
+		return arg.Const
+	default:
+		return strconv.FormatInt(arg.Value, 10)
+	}
+}
+
+// argument parses an argument, next is the index of the Go argument
+// that %v refers to, if nil, %v and %[n]v are not permitted.
+func (tag Tag) argument(scan *scanner.Scanner, pos int, next *int) (Argument, error) {
 	var arg Argument
 	arg.Check = true
 	tok := scan.Scan()
+	switch {
+	case tok == '%' && next != nil:
+		maps, err := tag.mapping(scan, pos)
+		if err != nil {
+			return arg, err
+		}
+		if maps == 0 {
+			maps = *next
+		}
+		*next = maps + 1
+		arg.Maps = maps
+		return arg, nil
+	}
 	switch tok {
 	case '@':
 		tok = scan.Scan()
@@ -135,8 +399,19 @@ func (tag Tag) argument(scan *scanner.Scanner, pos int) (Argument, error) {
 		arg.Index = uint8(value)
 	case scanner.Ident:
 		arg.Const = scan.TokenText()
-	case scanner.Int:
-		value, err := strconv.ParseInt(scan.TokenText(), 10, 64)
+	case scanner.Int, '-':
+		text := scan.TokenText()
+		if tok == '-' {
+			if scan.Scan() != scanner.Int {
+				return arg, SyntaxError{
+					Tag: Tag(tag),
+					Pos: pos + scan.Pos().Column,
+					Err: errorString("expected integer literal"),
+				}
+			}
+			text += scan.TokenText()
+		}
+		value, err := strconv.ParseInt(text, 10, 64)
 		if err != nil {
 			return arg, SyntaxError{
 				Tag: Tag(tag),
@@ -171,6 +446,38 @@ func (tag Tag) parseType(scan *scanner.Scanner, pos int) (Type, error) {
 		stype.Hash = true
 	case scanner.Ident:
 		stype.Name = scan.TokenText()
+		if stype.Name == "free" && scan.Peek() == '@' {
+			scan.Scan()
+			if scan.Scan() != scanner.Ident {
+				return stype, SyntaxError{
+					Tag: Tag(tag),
+					Pos: pos + scan.Pos().Column,
+					Err: errorString("expected destructor name"),
+				}
+			}
+			stype.Drop.Name = scan.TokenText()
+			if scan.Scan() != '{' {
+				return stype, SyntaxError{
+					Tag: Tag(tag),
+					Pos: pos + scan.Pos().Column,
+					Err: errorString("expected '{'"),
+				}
+			}
+			args, err := tag.arguments(scan, pos, '}', true)
+			if err != nil {
+				return stype, err
+			}
+			stype.Drop.Args = args
+			// the pointed to type is optional.
+			stype.Free, stype.Name = '&', ""
+			if scan.Peek() == '#' {
+				stype.Hash = true
+				scan.Scan()
+			}
+			if r := scan.Peek(); r != '_' && !unicode.IsLetter(r) {
+				stype.Name = "void"
+			}
+		}
 	case scanner.EOF:
 		return stype, SyntaxError{
 			Tag: Tag(tag),
@@ -199,103 +506,51 @@ func (tag Tag) parseType(scan *scanner.Scanner, pos int) (Type, error) {
 		}
 		stype.Name = scan.TokenText()
 	}
-	if stype.Name == "func" {
-		tok = scan.Scan()
-		if tok != '(' {
+	if scan.Peek() == '@' && stype.Drop.Name == "" {
+		scan.Scan()
+		if stype.Free != '$' {
 			return stype, SyntaxError{
 				Tag: Tag(tag),
 				Pos: pos + scan.Pos().Column,
-				Err: errorString("expected '('"),
+				Err: errorString("destructor of a type that is not owned (expecting '$')"),
 			}
 		}
-		for {
-			if scan.Peek() == ')' {
-				scan.Scan()
-				break
-			}
-			if scan.Peek() == scanner.EOF {
-				return stype, SyntaxError{
-					Tag: Tag(tag),
-					Pos: pos + scan.Pos().Column,
-					Err: errorString("unexpected end of tag, expected ')'"),
-				}
-			}
-			var arg Type
-			arg, err := tag.parseType(scan, pos)
-			if err != nil {
-				return stype, err
-			}
-			arg.Maps = len(stype.Args) + 1
-			stype.Args = append(stype.Args, arg)
-
-			if scan.Peek() != ',' && scan.Peek() != ')' {
-				return stype, SyntaxError{
-					Tag: Tag(tag),
-					Pos: pos + scan.Pos().Column + 1,
-					Err: errorString("unexpected token, expected ',' or ')'"),
-				}
-			}
-			if scan.Peek() == ',' {
-				scan.Scan()
+		if scan.Scan() != scanner.Ident {
+			return stype, SyntaxError{
+				Tag: Tag(tag),
+				Pos: pos + scan.Pos().Column,
+				Err: errorString("expected destructor name"),
 			}
 		}
+		stype.Drop.Name = scan.TokenText()
+	}
+	if stype.Name == "func" && scan.Peek() == '(' {
+		args, err := tag.parseParams(scan, pos)
+		if err != nil {
+			return stype, err
+		}
+		stype.Args = args
 		ret, err := tag.parseType(scan, pos)
 		if err != nil {
 			return stype, err
 		}
 		stype.Func = &ret
-
 		if scan.Peek() == ';' {
-			scan.Scan()
-			if scan.Scan() != scanner.Ident {
-				return stype, SyntaxError{
-					Tag: Tag(tag),
-					Pos: pos + scan.Pos().Column,
-					Err: errorString("expected symbol name"),
-				}
-			}
-			stype.Call.Name = scan.TokenText()
-			tok = scan.Scan()
-			if tok != '(' {
-				return stype, SyntaxError{
-					Tag: Tag(tag),
-					Pos: pos + scan.Pos().Column,
-					Err: errorString("expected '('"),
-				}
-			}
-			for {
-				if scan.Peek() == ')' {
-					scan.Scan()
-					break
-				}
-				if scan.Peek() == scanner.EOF {
-					return stype, SyntaxError{
-						Tag: Tag(tag),
-						Pos: pos + scan.Pos().Column,
-						Err: errorString("unexpected end of tag, expected ')'"),
-					}
-				}
-				var arg Argument
-				arg, err := tag.argument(scan, pos)
-				if err != nil {
-					return stype, err
-				}
-				stype.Call.Args = append(stype.Call.Args, arg)
-				if scan.Peek() != ',' && scan.Peek() != ')' {
-					return stype, SyntaxError{
-						Tag: Tag(tag),
-						Pos: pos + scan.Pos().Column + 1,
-						Err: errorString("unexpected token, expected ',' or ')"),
-					}
-				}
-				if scan.Peek() == ',' {
-					scan.Scan()
-				}
+			if stype.Call, err = tag.parseCall(scan, pos); err != nil {
+				return stype, err
 			}
 		}
 	}
+	if scan.Peek() == '%' {
+		scan.Scan()
+		maps, err := tag.mapping(scan, pos)
+		if err != nil {
+			return stype, err
+		}
+		stype.Maps = maps
+	}
 	switch scan.Peek() {
-	case scanner.EOF, ',', ')':
+	case scanner.EOF, ',', ')', ';':
 		return stype, nil
 	case '[':
 		stype.Test.Capacity = true
@@ -316,6 +571,10 @@ func (tag Tag) parseType(scan *scanner.Scanner, pos int) (Type, error) {
 				}
 			}
 		}
+		switch scan.Peek() {
+		case scanner.EOF, ',', ')', ';':
+			return stype, nil
+		}
 	}
 	if scan.Peek() == '!' {
 		stype.Test.Inverted = true
@@ -323,15 +582,19 @@ func (tag Tag) parseType(scan *scanner.Scanner, pos int) (Type, error) {
 	}
 	tok = scan.Scan()
 	//tokPos := pos + scan.Pos().Column
-	arg, err := tag.argument(scan, pos)
+	var orEqual bool
+	if (tok == '>' || tok == '<') && scan.Peek() == '=' {
+		orEqual = true
+		scan.Scan()
+	}
+	arg, err := tag.argument(scan, pos, nil)
 	if err != nil {
 		return stype, err
 	}
 	switch tok {
 	case '>', '<':
-		if scan.Peek() == '=' {
+		if orEqual {
 			stype.Test.Equality = arg
-			scan.Scan()
 		}
 		if tok == '>' {
 			stype.Test.MoreThan = arg
@@ -365,3 +628,157 @@ func (tag Tag) parseType(scan *scanner.Scanner, pos int) (Type, error) {
 	}
 	return stype, nil
 }
+
+// parseParams parses a parenthesized parameter list.
+func (tag Tag) parseParams(scan *scanner.Scanner, pos int) ([]Type, error) {
+	if scan.Scan() != '(' {
+		return nil, SyntaxError{
+			Tag: Tag(tag),
+			Pos: pos + scan.Pos().Column,
+			Err: errorString("expected '('"),
+		}
+	}
+	var (
+		args []Type
+		next = 1
+	)
+	for {
+		if scan.Peek() == ')' {
+			scan.Scan()
+			return args, nil
+		}
+		if scan.Peek() == scanner.EOF {
+			return nil, SyntaxError{
+				Tag: Tag(tag),
+				Pos: pos + scan.Pos().Column,
+				Err: errorString("unexpected end of tag, expected ')'"),
+			}
+		}
+		arg, err := tag.parseType(scan, pos)
+		if err != nil {
+			return nil, err
+		}
+		// like fmt, arguments are mapped in order, following
+		// on from any explicit %[n]v index.
+		if arg.Maps == 0 {
+			arg.Maps = next
+		}
+		next = arg.Maps + 1
+		args = append(args, arg)
+
+		if scan.Peek() != ',' && scan.Peek() != ')' {
+			return nil, SyntaxError{
+				Tag: Tag(tag),
+				Pos: pos + scan.Pos().Column + 1,
+				Err: errorString("unexpected token, expected ',' or ')'"),
+			}
+		}
+		if scan.Peek() == ',' {
+			scan.Scan()
+		}
+	}
+}
+
+// parseCall parses the symbol to call on failure, after a ';'
+func (tag Tag) parseCall(scan *scanner.Scanner, pos int) (Call, error) {
+	var call Call
+	scan.Scan()
+	if scan.Scan() != scanner.Ident {
+		return call, SyntaxError{
+			Tag: Tag(tag),
+			Pos: pos + scan.Pos().Column,
+			Err: errorString("expected symbol name"),
+		}
+	}
+	call.Name = scan.TokenText()
+	// errno is read after the call, rather than called.
+	if call.Name == "errno" && scan.Peek() != '(' {
+		return call, nil
+	}
+	if scan.Scan() != '(' {
+		return call, SyntaxError{
+			Tag: Tag(tag),
+			Pos: pos + scan.Pos().Column,
+			Err: errorString("expected '('"),
+		}
+	}
+	var err error
+	call.Args, err = tag.arguments(scan, pos, ')', false)
+	return call, err
+}
+
+// arguments parses a list of arguments, up to and including the
+// closing delimiter. If verbs is true, the arguments may refer to
+// Go arguments with %v and %[n]v.
+func (tag Tag) arguments(scan *scanner.Scanner, pos int, closing rune, verbs bool) ([]Argument, error) {
+	var (
+		args []Argument
+		next *int
+	)
+	if verbs {
+		next = new(int)
+		*next = 1
+	}
+	for {
+		if scan.Peek() == closing {
+			scan.Scan()
+			return args, nil
+		}
+		if scan.Peek() == scanner.EOF {
+			return nil, SyntaxError{
+				Tag: Tag(tag),
+				Pos: pos + scan.Pos().Column,
+				Err: errorString("unexpected end of tag, expected '" + string(closing) + "'"),
+			}
+		}
+		arg, err := tag.argument(scan, pos, next)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if scan.Peek() != ',' && scan.Peek() != closing {
+			return nil, SyntaxError{
+				Tag: Tag(tag),
+				Pos: pos + scan.Pos().Column + 1,
+				Err: errorString("unexpected token, expected ',' or '" + string(closing) + "'"),
+			}
+		}
+		if scan.Peek() == ',' {
+			scan.Scan()
+		}
+	}
+}
+
+// mapping parses the remainder of a %v or %[n]v verb, after the '%', and
+// returns n, or zero for %v.
+func (tag Tag) mapping(scan *scanner.Scanner, pos int) (int, error) {
+	var maps int
+	if scan.Peek() == '[' {
+		scan.Scan()
+		tok := scan.Scan()
+		value, err := strconv.ParseInt(scan.TokenText(), 10, 8)
+		if tok != scanner.Int || err != nil || value < 1 {
+			return 0, SyntaxError{
+				Tag: Tag(tag),
+				Pos: pos + scan.Pos().Column,
+				Err: errorString("expected argument index"),
+			}
+		}
+		if scan.Scan() != ']' {
+			return 0, SyntaxError{
+				Tag: Tag(tag),
+				Pos: pos + scan.Pos().Column,
+				Err: errorString("expected ']'"),
+			}
+		}
+		maps = int(value)
+	}
+	if scan.Scan() != scanner.Ident || scan.TokenText() != "v" {
+		return 0, SyntaxError{
+			Tag: Tag(tag),
+			Pos: pos + scan.Pos().Column,
+			Err: errorString("expected %v or %[n]v"),
+		}
+	}
+	return maps, nil
+}
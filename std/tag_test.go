@@ -1,6 +1,7 @@
 package std_test
 
 import (
+	"reflect"
 	"testing"
 
 	"runtime.link/std"
@@ -50,3 +51,274 @@ func TestTagParsing(t *testing.T) {
 		t.Fatal("expected function to call 'ferror' with argument 4")
 	}
 }
+
+func TestTagComparisons(t *testing.T) {
+	const tag std.Tag = `snprintf func(&char[>=@2],size_t<=INT_MAX,&char,void...?@3)int!=0`
+
+	_, ctype, err := tag.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if test := ctype.Args[0].Test; !test.Capacity || test.MoreThan.Index != 2 || test.Equality.Index != 2 {
+		t.Fatal("expected 1st argument to have a capacity of at least the 2nd argument")
+	}
+	if test := ctype.Args[1].Test; test.LessThan.Const != "INT_MAX" || test.Equality.Const != "INT_MAX" {
+		t.Fatal("expected 2nd argument to be at most INT_MAX")
+	}
+	if test := ctype.Args[3].Test; !ctype.Args[3].More || test.OfFormat.Index != 3 {
+		t.Fatal("expected 4th argument to be formatted by the 3rd argument")
+	}
+	if test := ctype.Func.Test; !test.Inverted || !test.Equality.Check || test.Equality.Value != 0 {
+		t.Fatal("expected return value to have assertion to not equal 0")
+	}
+}
+
+func TestTagErrno(t *testing.T) {
+	const tag std.Tag = `sqrt func(double)double; errno`
+
+	_, ctype, err := tag.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctype.Call.Name != "errno" || len(ctype.Call.Args) != 0 {
+		t.Fatal("expected function to report errors through errno")
+	}
+}
+
+func TestTagOptional(t *testing.T) {
+	const tag std.Tag = `reallocarray,realloc? func(&void,size_t,size_t)$void`
+
+	symbols, _, err := tag.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tag.Optional() || std.Tag(`realloc func(&void,size_t)$void`).Optional() {
+		t.Fatal("expected only the tag with a trailing '?' to be optional")
+	}
+	if len(symbols) != 2 || symbols[1] != "realloc" {
+		t.Fatal("expected the optional marker to be removed from the symbols", symbols)
+	}
+}
+
+func TestTagVersion(t *testing.T) {
+	const tag std.Tag = `memcpy@GLIBC_2.14,memcpy func(&void,&void,size_t)void`
+
+	symbols, _, err := tag.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(symbols) != 2 || symbols[0] != "memcpy@GLIBC_2.14" || symbols[1] != "memcpy" {
+		t.Fatal("expected the versioned symbol to be preferred", symbols)
+	}
+}
+
+func TestTagVariadic(t *testing.T) {
+	const tag std.Tag = `printf func(&char,void...)int`
+
+	_, ctype, err := tag.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ctype.Args) != 2 || !ctype.Args[1].More || ctype.Args[1].Test.OfFormat.Check {
+		t.Fatal("expected an unchecked variadic argument")
+	}
+}
+
+func TestTagNegative(t *testing.T) {
+	_, ctype, err := std.Tag(`fileno func(&FILE)int>=-1; errno`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if test := ctype.Func.Test; !test.MoreThan.Check || !test.Equality.Check || test.MoreThan.Value != -1 {
+		t.Fatal("expected return value to be at least -1", test)
+	}
+	_, ctype, err = std.Tag(`int=-1 close(int!=-2)`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctype.Func.Test.Equality.Value != -1 || !ctype.Args[0].Test.Inverted || ctype.Args[0].Test.Equality.Value != -2 {
+		t.Fatal("expected negative assertions on the result and argument", ctype)
+	}
+	if _, _, err := std.Tag(`close func(int)int=-`).Parse(); err == nil {
+		t.Fatal("expected an error for a '-' without an integer")
+	}
+}
+
+func TestTagCanonical(t *testing.T) {
+	for _, tag := range []std.Tag{
+		`abs func(int)int`,
+		`fread func(&void[=@3],size_t*=@1,size_t,&FILE)size_t=@3; ferror(@4)`,
+		`snprintf func(&char[>=@2],size_t<=INT_MAX,&#char,void...?@3)int!=0`,
+		`sqrt func(double)double; errno`,
+		`fopen func(&#char,&#char)$FILE=NULL; strerror(errno)`,
+		`reallocarray,realloc? func(&void,size_t,size_t)$void`,
+		`memcpy@GLIBC_2.14 func(&void~@2,&#void!~@1,size_t)&void^@1`,
+		`qsort func(&void,size_t,size_t,&func(&#void,&#void)int)void`,
+		`atexit func($func)int`,
+		`strtol func(&#char,+char:@1,int>=0)long`,
+		`memset func(&void[>@3],int<256,size_t**>1)&void`,
+		`f func(-int,*char)void`,
+		`setvbuf func(&FILE,&char%[3]v,int%[2]v,size_t)int`,
+		`strchr func(&#char,int)ptrdiff`,
+		`fileno func(&FILE)int>=-1; errno`,
+		`close func(int!=-1)int=-1; errno`,
+	} {
+		canonical, err := tag.Canonical()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canonical != tag {
+			t.Errorf("expected %s, got %s", tag, canonical)
+		}
+	}
+	canonical, err := std.Tag(`fread func(&void[=@3], size_t*=@1, size_t, &FILE)size_t=@3;ferror(@4)`).Canonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canonical != `fread func(&void[=@3],size_t*=@1,size_t,&FILE)size_t=@3; ferror(@4)` {
+		t.Fatal("unexpected canonical form", canonical)
+	}
+	canonical, err = std.Tag(`f func(int%v,int%[1]v,int%v)void`).Canonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canonical != `f func(int,int%[1]v,int)void` {
+		t.Fatal("unexpected canonical form", canonical)
+	}
+}
+
+func TestTagMapping(t *testing.T) {
+	const tag std.Tag = `f func(int%[2]v,int,double%[1]v,&char%v)void`
+
+	_, ctype, err := tag.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var maps []int
+	for _, arg := range ctype.Args {
+		maps = append(maps, arg.Maps)
+	}
+	if !reflect.DeepEqual(maps, []int{2, 3, 1, 2}) {
+		t.Fatal("expected arguments to be mapped like fmt verbs, got", maps)
+	}
+	for _, tag := range []std.Tag{`f func(int%[0]v)void`, `f func(int%[x]v)void`, `f func(int%d)void`, `f func(int%[1)void`} {
+		if _, _, err := tag.Parse(); err == nil {
+			t.Error("expected a syntax error for", tag)
+		}
+	}
+}
+
+func TestTypeString(t *testing.T) {
+	_, ctype, err := std.Tag(`memcpy func(&void[>=@3],&#void~@1,size_t)&void^@1; errno`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, again, err := std.Tag("memcpy " + ctype.String()).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ctype, again) {
+		t.Fatalf("expected %#v, got %#v", ctype, again)
+	}
+}
+
+func TestTagDeclaration(t *testing.T) {
+	for decl, tag := range map[std.Tag]std.Tag{
+		`int isalnum(int)`:                       `isalnum func(int)int`,
+		`$char strerror(int)`:                    `strerror func(int)$char`,
+		`int<0 printf(&#char,void...?@1)`:        `printf func(&#char,void...?@1)int<0`,
+		`free(#void)`:                            `free func(#void)void`,
+		`int rand(void)`:                         `rand func()int`,
+		`int rand()`:                             `rand func()int`,
+		`$FILE=NULL fopen(&#char,&#char); errno`: `fopen func(&#char,&#char)$FILE=NULL; errno`,
+		`void qsort(&void,size_t,size_t,&func(&#void,&#void)int)`: `qsort func(&void,size_t,size_t,&func(&#void,&#void)int)void`,
+		`$void reallocarray,realloc?(&void,size_t)`:               `reallocarray,realloc? func(&void,size_t)$void`,
+		`size_t fread(&void[=@3],size_t*=@1,size_t,&FILE)`:        `fread func(&void[=@3],size_t*=@1,size_t,&FILE)size_t`,
+		`$FILE@fclose=NULL fopen(&#char,&#char); errno`:           `fopen func(&#char,&#char)$FILE@fclose=NULL; errno`,
+	} {
+		symbols, ctype, err := decl.Parse()
+		if err != nil {
+			t.Fatal(decl, err)
+		}
+		expected, etype, err := tag.Parse()
+		if err != nil {
+			t.Fatal(tag, err)
+		}
+		if !reflect.DeepEqual(symbols, expected) || !reflect.DeepEqual(ctype, etype) {
+			t.Errorf("expected %s to parse like %s, got %s", decl, tag, ctype)
+		}
+		if decl.Optional() != tag.Optional() {
+			t.Errorf("expected %s to be optional like %s", decl, tag)
+		}
+		canonical, err := decl.Canonical()
+		if err != nil {
+			t.Fatal(decl, err)
+		}
+		if again, _ := canonical.Canonical(); again != canonical {
+			t.Errorf("expected %s to stay %s, got %s", decl, canonical, again)
+		}
+		if _, ctype, _ := canonical.Parse(); !reflect.DeepEqual(ctype, etype) {
+			t.Errorf("expected %s to parse like %s, got %s", canonical, tag, ctype)
+		}
+	}
+	for decl, canonical := range map[std.Tag]std.Tag{
+		`free(#void)`:                            `void free(#void)`,
+		`int rand()`:                             `int rand(void)`,
+		`int rand(void)`:                         `int rand(void)`,
+		`int  isalnum(int)`:                      `int isalnum(int)`,
+		`$FILE=NULL fopen(&#char,&#char); errno`: `$FILE=NULL fopen(&#char,&#char); errno`,
+	} {
+		if got, _ := decl.Canonical(); got != canonical {
+			t.Errorf("expected %s to be canonically %s, got %s", decl, canonical, got)
+		}
+	}
+	for _, tag := range []std.Tag{`quot int`, `cb &func(int)void`, `abs func(int)int`} {
+		if _, _, err := tag.Parse(); err != nil {
+			t.Error(tag, err)
+		}
+	}
+}
+
+func TestTagDestructor(t *testing.T) {
+	const tag std.Tag = `int setvbuf(&FILE,free@fclose{%v},int,size_t%[2]v)`
+
+	_, ctype, err := tag.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := ctype.Args[1]
+	if buf.Free != '&' || buf.Name != "void" || buf.Drop.Name != "fclose" || len(buf.Drop.Args) != 1 || buf.Drop.Args[0].Maps != 1 {
+		t.Fatalf("expected the buffer to be borrowed until fclose(%%[1]v), got %#v", buf)
+	}
+	canonical, err := tag.Canonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canonical != `int setvbuf(&FILE,free@fclose{%[1]v}void,int,size_t%[2]v)` {
+		t.Fatal("unexpected canonical form", canonical)
+	}
+	if _, _, err := std.Tag(`f func(int=%v)void`).Parse(); err == nil {
+		t.Fatal("expected verbs to be limited to destructor arguments")
+	}
+}
+
+func TestTagOwnedDestructor(t *testing.T) {
+	const tag std.Tag = `SDL_CreateWindow func(&#char,int,int,int,int,uint32_t)$SDL_Window@SDL_DestroyWindow=NULL; SDL_GetError()`
+
+	_, ctype, err := tag.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := ctype.Func
+	if ret.Free != '$' || ret.Name != "SDL_Window" || ret.Drop.Name != "SDL_DestroyWindow" || len(ret.Drop.Args) != 0 || !ret.Test.Equality.Check {
+		t.Fatalf("expected the window to be owned until SDL_DestroyWindow is called, got %#v", ret)
+	}
+	if canonical, _ := tag.Canonical(); canonical != tag {
+		t.Fatal("unexpected canonical form", canonical)
+	}
+	for _, tag := range []std.Tag{`malloc func(size_t)&void@free`, `malloc func(size_t)$void@`, `malloc func(size_t)$void@free@free`} {
+		if _, _, err := tag.Parse(); err == nil {
+			t.Error("expected a syntax error for", tag)
+		}
+	}
+}